@@ -2,18 +2,30 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 
 	"example.com/blog/blogpb"
+	"example.com/internal/dial"
 	"google.golang.org/grpc"
 )
 
+var (
+	addr          = flag.String("addr", "localhost:50051", "server address, or a file:///path or dnssrv:///name target listing several replicas")
+	serviceConfig = flag.String("service-config", "", "JSON file with the retry, hedging and load balancing policies of the connection (default built-in policies)")
+)
+
 func main() {
+	flag.Parse()
 
 	fmt.Println("welcome to blog client")
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	config, err := dial.Load(*serviceConfig)
+	if err != nil {
+		log.Fatalf("failed to load service config: %v", err)
+	}
+	conn, err := dial.Dial(*addr, config, grpc.WithInsecure())
 
 	if err != nil {
 		log.Fatalf("could not connect : %v", err)
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/textproto"
+
+	"example.com/blog/blogpb"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// newGateway returns a handler serving the REST/JSON API declared by the
+// google.api.http annotations in blog.proto. Every request is forwarded to
+// the gRPC server at grpcAddr, and gRPC status codes are translated to the
+// matching HTTP status (NotFound -> 404, InvalidArgument -> 400, ...).
+// ListBlog is written as newline-delimited JSON, one blog per line.
+// ReadBlog honors the If-None-Match header, answering 304 Not Modified
+// with no body when the blog still has the given ETag. The calls carry key
+// in the gatewayKeyMetadata, so that the server can trust the address of
+// the client the gateway adds to them.
+func newGateway(ctx context.Context, grpcAddr, key string) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithErrorHandler(runtime.DefaultHTTPErrorHandler),
+		runtime.WithStreamErrorHandler(runtime.DefaultStreamErrorHandler),
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithForwardResponseOption(setETag),
+	)
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(metadata.AppendToOutgoingContext(ctx, gatewayKeyMetadata, key), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(metadata.AppendToOutgoingContext(ctx, gatewayKeyMetadata, key), desc, cc, method, opts...)
+		}),
+	}
+	conn, err := grpc.DialContext(ctx, dialAddr(grpcAddr), opts...)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	if err := blogpb.RegisterBlogServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	// registered last, it takes over ReadBlog from the generated handler
+	client := blogpb.NewBlogServiceClient(conn)
+	if err := mux.HandlePath(http.MethodGet, "/v1/blogs/{blog_id}", readBlog(mux, client)); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// gatewayKeyMetadata is the metadata by which the gateway proves that it
+// relays a call, with a key drawn when the server starts.
+const gatewayKeyMetadata = "x-gateway-key"
+
+// newGatewayKey returns a key no caller can guess.
+func newGatewayKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// viaGateway returns a ratelimit.Options.Proxy recognizing the calls
+// relayed by the gateway holding key.
+func viaGateway(key string) func(ctx context.Context) bool {
+	return func(ctx context.Context) bool {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, v := range md.Get(gatewayKeyMetadata) {
+			if subtle.ConstantTimeCompare([]byte(v), []byte(key)) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// incomingHeader forwards If-None-Match as the "if-none-match" metadata read
+// by ReadBlog, and other headers as the gateway does by default.
+func incomingHeader(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == "If-None-Match" {
+		return "if-none-match", true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// readBlog serves ReadBlog like the generated handler, but answers 304 Not
+// Modified, with no body, when the blog still has the ETag the client gave.
+func readBlog(mux *runtime.ServeMux, client blogpb.BlogServiceClient) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, r)
+		rctx, err := runtime.AnnotateContext(ctx, mux, r, "/blog.BlogService/ReadBlog", runtime.WithHTTPPathPattern("/v1/blogs/{blog_id}"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, r, err)
+			return
+		}
+		req := &blogpb.ReadBlogRequest{BlogId: pathParams["blog_id"]}
+		if err := r.ParseForm(); err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, r, status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+		if err := runtime.PopulateQueryParameters(req, r.Form, utilities.NewDoubleArray([][]string{{"blog_id"}})); err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, r, status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+		var md runtime.ServerMetadata
+		res, err := client.ReadBlog(rctx, req, grpc.Header(&md.HeaderMD), grpc.Trailer(&md.TrailerMD))
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, r, err)
+			return
+		}
+		if res.GetNotModified() {
+			w.Header().Set("ETag", res.GetEtag())
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outboundMarshaler, w, r, res, mux.GetForwardResponseOptions()...)
+	}
+}
+
+// setETag sets the ETag header of ReadBlog responses.
+func setETag(ctx context.Context, w http.ResponseWriter, resp proto.Message) error {
+	if res, ok := resp.(*blogpb.ReadBlogResponse); ok && res.GetEtag() != "" {
+		w.Header().Set("ETag", res.GetEtag())
+	}
+	return nil
+}
+
+// dialAddr turns a listen address such as "0.0.0.0:50051" into one the
+// gateway can dial.
+func dialAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"strings"
+	"testing"
+
+	"example.com/blog/blogpb"
+	"example.com/blog/blogservice"
+	"example.com/blog/blogstore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// testGatewayKey is the key of the gateways of the tests.
+const testGatewayKey = "test-key"
+
+// startGateway serves a BlogService backed by an in-memory store, and the
+// gateway in front of it.
+func startGateway(t *testing.T, opts ...grpc.ServerOption) (*blogservice.Server, *httptest.Server) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := blogservice.New(blogstore.NewMemory())
+	s := grpc.NewServer(opts...)
+	blogpb.RegisterBlogServiceServer(s, svc)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	gw, err := newGateway(ctx, lis.Addr().String(), testGatewayKey)
+	if err != nil {
+		t.Fatalf("newGateway: %v", err)
+	}
+	srv := httptest.NewServer(gw)
+	t.Cleanup(srv.Close)
+	return svc, srv
+}
+
+func get(t *testing.T, url, ifNoneMatch string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("reading the body of GET %s: %v", url, err)
+	}
+	return res, string(body)
+}
+
+func TestGatewayConditionalRead(t *testing.T) {
+	svc, srv := startGateway(t)
+	created, err := svc.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{
+		Blog: &blogpb.Blog{AuthorId: "rahul", Title: "first", Content: "content of first"},
+	})
+	if err != nil {
+		t.Fatalf("CreateBlog: %v", err)
+	}
+	url := srv.URL + "/v1/blogs/" + created.GetBlog().GetId()
+
+	res, body := get(t, url, "")
+	tag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || tag == "" || !strings.Contains(body, `"first"`) {
+		t.Fatalf("GET = %d with ETag %q and body %q, want 200 with an ETag and the blog", res.StatusCode, tag, body)
+	}
+
+	res, body = get(t, url, tag)
+	if res.StatusCode != http.StatusNotModified {
+		t.Errorf("GET with If-None-Match status = %d, want 304", res.StatusCode)
+	}
+	if got := res.Header.Get("ETag"); got != tag {
+		t.Errorf("GET with If-None-Match ETag = %q, want %q", got, tag)
+	}
+	if body != "" {
+		t.Errorf("GET with If-None-Match body = %q, want none", body)
+	}
+
+	res, body = get(t, url+"?if_none_match="+neturl.QueryEscape(tag), "")
+	if res.StatusCode != http.StatusNotModified || body != "" {
+		t.Errorf("GET with an if_none_match parameter = %d with body %q, want 304 without a body", res.StatusCode, body)
+	}
+
+	res, _ = get(t, url, `"stale"`)
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("GET with a stale If-None-Match status = %d, want 200", res.StatusCode)
+	}
+	res, _ = get(t, srv.URL+"/v1/blogs/618798d8d334a39f69d0ef24", tag)
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of an unknown blog status = %d, want 404", res.StatusCode)
+	}
+}
+
+func TestGatewayProxy(t *testing.T) {
+	proxy := viaGateway(testGatewayKey)
+	relayed := make(chan bool, 1)
+	svc, srv := startGateway(t, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		relayed <- proxy(ctx)
+		return handler(ctx, req)
+	}))
+	created, err := svc.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{Blog: &blogpb.Blog{Title: "first"}})
+	if err != nil {
+		t.Fatalf("CreateBlog: %v", err)
+	}
+	// REST clients cannot pass for the gateway by sending a key of their own
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/blogs/"+created.GetBlog().GetId(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Grpc-Metadata-X-Gateway-Key", "guess")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	res.Body.Close()
+	if !<-relayed {
+		t.Error("call relayed by the gateway was not recognized")
+	}
+
+	for _, md := range []metadata.MD{nil, metadata.Pairs(gatewayKeyMetadata, "guess")} {
+		if proxy(metadata.NewIncomingContext(context.Background(), md)) {
+			t.Errorf("call with metadata %v recognized as relayed by the gateway", md)
+		}
+	}
+}
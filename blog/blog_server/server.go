@@ -2,165 +2,52 @@ package main
 
 import (
 	"context"
+	"expvar"
+	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"time"
 
 	"example.com/blog/blogpb"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"example.com/blog/blogservice"
+	"example.com/blog/blogstore"
+	"example.com/internal/admission"
+	"example.com/internal/auth"
+	"example.com/internal/deadline"
+	"example.com/internal/httpserve"
+	"example.com/internal/ratelimit"
+	"example.com/internal/recovery"
+	"example.com/internal/serverconfig"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
-	"google.golang.org/grpc/status"
 )
 
-var collection *mongo.Collection
+var (
+	addr           = flag.String("addr", "0.0.0.0:50051", "address the gRPC server listens on")
+	gatewayAddr    = flag.String("gateway-addr", "0.0.0.0:8080", "address the REST/JSON gateway listens on")
+	corsOrigins    = flag.String("cors-origins", "", "comma separated origins allowed to call the server from a browser, \"*\" for any; none when empty")
+	configFile     = flag.String("config", "", "JSON server config file, reloaded on SIGHUP; see internal/serverconfig")
+	maxConcurrency = flag.Int("max-concurrency", 1000, "upper bound of the adaptive limit on concurrent calls; calls over the limit fail with Unavailable")
+	cacheSize      = flag.Int("cache-size", 1000, "blogs kept in memory in front of mongo; 0 disables the cache")
+	cacheTTL       = flag.Duration("cache-ttl", 30*time.Second, "how long a cached blog or listing is served before mongo is read again")
 
-type server struct {
-}
-
-type blogItem struct {
-	Id       primitive.ObjectID `bson:"_id,omitempty"`
-	AuthorId string             `bson:"author_id,omitempty"`
-	Content  string             `bson:"content,omitempty"`
-	Title    string             `bson:"title,omitempty"`
-}
-
-func (*server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest) (*blogpb.CreateBlogResponse, error) {
-	fmt.Println("Inside CreateBlog method")
-	blog := req.GetBlog()
-
-	data := blogItem{
-		AuthorId: blog.GetAuthorId(),
-		Content:  blog.GetContent(),
-		Title:    blog.GetTitle(),
-	}
-	res, err := collection.InsertOne(context.Background(), data)
-	if err != nil {
-		fmt.Println("Failed to insert blog")
-		return nil, status.Error(codes.Internal, fmt.Sprintf("Failed to insert blog due to error:%v", err))
-	}
-	Id := res.InsertedID.(primitive.ObjectID)
-
-	return &blogpb.CreateBlogResponse{
-		Blog: &blogpb.Blog{
-			Id:       Id.Hex(),
-			AuthorId: blog.GetAuthorId(),
-			Content:  blog.GetContent(),
-			Title:    blog.GetTitle(),
-		},
-	}, nil
-}
-
-func dataToBlog(data *blogItem) *blogpb.Blog {
-	return &blogpb.Blog{
-		Id:       data.Id.Hex(),
-		AuthorId: data.AuthorId,
-		Title:    data.Title,
-		Content:  data.Content,
-	}
-}
-
-func (*server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blogpb.ReadBlogResponse, error) {
-	fmt.Println("Inside ReadBlog method")
-	blogID := req.GetBlogId()
-	oid, err := primitive.ObjectIDFromHex(blogID)
-	if err != nil {
-		return nil, status.Error(
-			codes.InvalidArgument, fmt.Sprintf("Cannot parse ID"),
-		)
-	}
-	data := &blogItem{}
-	err = collection.FindOne(context.Background(), bson.M{"_id": oid}).Decode(data)
-	if err != nil {
-		return nil, status.Error(codes.NotFound, fmt.Sprintf("Cannot find the blog with given id: %v\n", err))
-	}
-	return &blogpb.ReadBlogResponse{
-		Blog: dataToBlog(data),
-	}, nil
-}
-
-func (*server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest) (*blogpb.UpdateBlogResponse, error) {
-	log.Println("Inside UpdateBlog method")
-	data := req.GetBlog()
-	oid, err := primitive.ObjectIDFromHex(data.Id)
-	if err != nil {
-		return nil, status.Error(
-			codes.InvalidArgument, "Cannot parse ID",
-		)
-	}
-	blog := &blogItem{}
-	blog.AuthorId = data.GetAuthorId()
-	blog.Content = data.GetContent()
-	blog.Title = data.GetTitle()
-	updateErr := collection.FindOneAndUpdate(context.Background(), bson.M{"_id": oid}, bson.M{"$set": blog}).Decode(blog)
-	if updateErr != nil {
-		if updateErr == mongo.ErrNoDocuments {
-			return nil, status.Error(codes.NotFound, fmt.Sprintf("Cannot find the blog with given id: %v\n", err))
-		} else {
-			return nil, status.Error(codes.Internal, fmt.Sprintf("Cannot update object in mongodb: %v", updateErr))
-		}
-	}
-	return &blogpb.UpdateBlogResponse{
-		Blog: dataToBlog(blog),
-	}, nil
-}
-
-func (*server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest) (*blogpb.DeleteBlogResponse, error) {
-	log.Println("Inside DeleteBlog method")
-	blogID := req.GetBlogId()
-	oid, err := primitive.ObjectIDFromHex(blogID)
-	if err != nil {
-		return nil, status.Error(
-			codes.InvalidArgument, "Cannot parse ID",
-		)
-	}
-	fmt.Println(oid)
-	res, delErr := collection.DeleteOne(context.Background(), bson.M{"_id": oid})
-	if delErr != nil {
-		return nil, status.Error(
-			codes.Internal, fmt.Sprintf("Cannnot Delete blog due to Error :%v", delErr),
-		)
-	}
-	if res.DeletedCount == 0 {
-		return nil, status.Error(codes.NotFound, fmt.Sprintf("Cannot find the blog with given id: %v\n", err))
-	}
-	return &blogpb.DeleteBlogResponse{
-		BlogId: oid.Hex(),
-	}, nil
-}
+	budgets deadline.Budgets
+)
 
-func (*server) ListBlog(req *blogpb.ListBlogRequest, stream blogpb.BlogService_ListBlogServer) error {
-	fmt.Println("Inside ReadBlog method")
-	cur, err := collection.Find(context.Background(), bson.M{})
-	if err != nil {
-		return status.Error(
-			codes.Internal, fmt.Sprintf("Cannnot Read blog due to Error :%v", err),
-		)
-	}
-	defer cur.Close(context.Background())
-	for cur.Next(context.Background()) {
-		data := &blogItem{}
-		err := cur.Decode(data)
-		if err != nil {
-			return status.Error(
-				codes.Internal, fmt.Sprintf("Cannnot Read blog due to Error :%v", err),
-			)
-		}
-		stream.Send(&blogpb.ListBlogResponse{
-			Blog: dataToBlog(data),
-		})
-	}
-	return nil
-}
 func main() {
 	// if we crash code, we will get exact file and line number
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	flag.Var(&budgets, "min-budget", "comma separated /package.Service/Method=duration minimum deadlines; shorter calls are rejected")
+	flag.Parse()
 	fmt.Println("Blog Service started ")
 
 	//connect to database
@@ -177,29 +64,106 @@ func main() {
 	defer client.Disconnect(context.TODO())
 
 	//create or connect a collection
-	collection = client.Database("BLOG").Collection("blog")
+	collection := client.Database("BLOG").Collection("blog")
 	fmt.Println(collection)
 
-	lis, err := net.Listen("tcp", "0.0.0.0:50051")
+	lis, err := net.Listen("tcp", *addr)
 	if err != nil {
 		log.Fatalf("failed to listen %v", err)
 	}
-	s := grpc.NewServer()
-	blogpb.RegisterBlogServiceServer(s, &server{})
+	config, err := serverconfig.Load(*configFile)
+	if err != nil {
+		log.Fatalf("failed to load server config: %v", err)
+	}
+	callers := auth.New(config.Callers)
+	// the gateway relays the calls of REST clients with their address
+	gatewayKey, err := newGatewayKey()
+	if err != nil {
+		log.Fatalf("failed to create the gateway key: %v", err)
+	}
+	limiter := ratelimit.New(&config.RateLimits, ratelimit.Options{
+		Authenticator: callers.Identity,
+		Proxy:         viaGateway(gatewayKey),
+	})
+	serverconfig.ReloadOnHangup(*configFile, func(c *serverconfig.Config) {
+		callers.SetCallers(c.Callers)
+		limiter.SetConfig(&c.RateLimits)
+	})
+	shedder := admission.New(admission.Options{MaxLimit: *maxConcurrency, Admin: callers.Admin})
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recovery.UnaryServerInterceptor(),
+			deadline.UnaryServerInterceptor(budgets),
+			limiter.UnaryServerInterceptor(),
+			shedder.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(),
+			deadline.StreamServerInterceptor(budgets),
+			limiter.StreamServerInterceptor(),
+			shedder.StreamServerInterceptor(),
+		),
+	)
+	store := blogstore.NewMongo(collection)
+	if *cacheSize > 0 {
+		cache := blogstore.NewCache(store, blogstore.CacheOptions{Size: *cacheSize, TTL: *cacheTTL})
+		cache.Publish("blogcache")
+		store = cache
+	}
+	blogpb.RegisterBlogServiceServer(s, blogservice.New(store))
 	reflection.Register(s)
+
+	// client load balancers stop sending calls to servers that are not
+	// serving, see healthCheckConfig in internal/dial
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	for name := range s.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	// gRPC, gRPC-Web and Connect share the listener
+	h, err := httpserve.Handler(s, strings.Split(*corsOrigins, ",")...)
+	if err != nil {
+		log.Fatalf("failed to create http handler : %v", err)
+	}
+	grpcServer := &http.Server{Handler: h}
 	go func() {
 		fmt.Println("Starting server")
-		if err := s.Serve(lis); err != nil {
+		if err := grpcServer.Serve(lis); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("failed to serve : %v", err)
 		}
 	}()
+
+	// REST/JSON gateway for clients that cannot speak gRPC
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gw, err := newGateway(ctx, *addr, gatewayKey)
+	if err != nil {
+		log.Fatalf("failed to create gateway: %v", err)
+	}
+	// cache stats and other expvars are served next to the API
+	mux := http.NewServeMux()
+	mux.Handle("/", gw)
+	mux.Handle("/debug/vars", expvar.Handler())
+	httpServer := &http.Server{Addr: *gatewayAddr, Handler: mux}
+	go func() {
+		fmt.Println("Starting gateway on", *gatewayAddr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("failed to serve gateway : %v", err)
+		}
+	}()
+
 	//wait for control c to exit
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt)
 
 	// block until a signal is received
 	<-ch
+	healthServer.Shutdown()
+	fmt.Println("Stopping the gateway")
+	httpServer.Shutdown(context.Background())
 	fmt.Println("Stopping the server")
+	grpcServer.Close()
 	s.Stop()
 	fmt.Println("Closing the listener")
 	lis.Close()
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"example.com/blog/blogpb"
+)
+
+// env is what a command needs to talk to the server and the user.
+type env struct {
+	client blogpb.BlogServiceClient
+	stdin  io.Reader
+	stderr io.Writer
+	out    *printer
+}
+
+type command struct {
+	name    string
+	summary string
+	args    string
+	run     func(ctx context.Context, e *env, args []string) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{name: "create", summary: "create a blog", args: "", run: runCreate},
+		{name: "get", summary: "print a blog", args: "<id>", run: runGet},
+		{name: "update", summary: "update the given fields of a blog", args: "<id>", run: runUpdate},
+		{name: "delete", summary: "delete a blog", args: "<id>", run: runDelete},
+		{name: "list", summary: "list every blog", args: "", run: runList},
+		{name: "search", summary: "list the blogs matching all the given filters", args: "[text]", run: runSearch},
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func (e *env) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: blogctl %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args and checks the number of positional arguments.
+func parse(fs *flag.FlagSet, args []string, nargs int) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if nargs >= 0 && fs.NArg() != nargs {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// contentFlags registers the flags used to pass the content of a blog.
+type contentFlags struct {
+	content string
+	file    string
+}
+
+func (c *contentFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&c.content, "content", "", "content of the blog")
+	fs.StringVar(&c.file, "file", "", `read the content from a file, "-" for stdin`)
+}
+
+// read returns the content given on the command line, from a file or from
+// stdin.
+func (c *contentFlags) read(stdin io.Reader) (string, error) {
+	switch {
+	case c.content != "" && c.file != "":
+		return "", errors.New("-content and -file are mutually exclusive")
+	case c.file == "-":
+		b, err := io.ReadAll(stdin)
+		return string(b), err
+	case c.file != "":
+		b, err := os.ReadFile(c.file)
+		return string(b), err
+	}
+	return c.content, nil
+}
+
+func runCreate(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("create", "")
+	author := fs.String("author", "", "author id (required)")
+	title := fs.String("title", "", "title (required)")
+	var content contentFlags
+	content.register(fs)
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if *author == "" || *title == "" {
+		fmt.Fprintln(e.stderr, "-author and -title are required")
+		fs.Usage()
+		return errUsage
+	}
+	text, err := content.read(e.stdin)
+	if err != nil {
+		return err
+	}
+	res, err := e.client.CreateBlog(ctx, &blogpb.CreateBlogRequest{
+		Blog: &blogpb.Blog{AuthorId: *author, Title: *title, Content: text},
+	})
+	if err != nil {
+		return err
+	}
+	return e.out.blog(res.GetBlog())
+}
+
+func runGet(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("get", "<id>")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	res, err := e.client.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return e.out.blog(res.GetBlog())
+}
+
+func runUpdate(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("update", "<id>")
+	author := fs.String("author", "", "new author id")
+	title := fs.String("title", "", "new title")
+	var content contentFlags
+	content.register(fs)
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	text, err := content.read(e.stdin)
+	if err != nil {
+		return err
+	}
+	if *author == "" && *title == "" && text == "" {
+		fmt.Fprintln(e.stderr, "nothing to update")
+		fs.Usage()
+		return errUsage
+	}
+	res, err := e.client.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{
+		Blog: &blogpb.Blog{Id: fs.Arg(0), AuthorId: *author, Title: *title, Content: text},
+	})
+	if err != nil {
+		return err
+	}
+	return e.out.blog(res.GetBlog())
+}
+
+func runDelete(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("delete", "<id>")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	res, err := e.client.DeleteBlog(ctx, &blogpb.DeleteBlogRequest{BlogId: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return e.out.deleted(res.GetBlogId())
+}
+
+func runList(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("list", "")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	return e.list(ctx, func(*blogpb.Blog) bool { return true })
+}
+
+func runSearch(ctx context.Context, e *env, args []string) error {
+	fs := e.flags("search", "[text]")
+	author := fs.String("author", "", "only blogs by this author id")
+	title := fs.String("title", "", "only blogs whose title contains this text")
+	if err := parse(fs, args, -1); err != nil {
+		return err
+	}
+	text := strings.ToLower(strings.Join(fs.Args(), " "))
+	titleText := strings.ToLower(*title)
+	return e.list(ctx, func(blog *blogpb.Blog) bool {
+		if *author != "" && blog.GetAuthorId() != *author {
+			return false
+		}
+		if titleText != "" && !strings.Contains(strings.ToLower(blog.GetTitle()), titleText) {
+			return false
+		}
+		if text != "" &&
+			!strings.Contains(strings.ToLower(blog.GetTitle()), text) &&
+			!strings.Contains(strings.ToLower(blog.GetContent()), text) {
+			return false
+		}
+		return true
+	})
+}
+
+// list prints the blogs streamed by ListBlog for which keep returns true.
+func (e *env) list(ctx context.Context, keep func(*blogpb.Blog) bool) error {
+	stream, err := e.client.ListBlog(ctx, &blogpb.ListBlogRequest{})
+	if err != nil {
+		return err
+	}
+	var blogs []*blogpb.Blog
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if keep(res.GetBlog()) {
+			blogs = append(blogs, res.GetBlog())
+		}
+	}
+	return e.out.blogs(blogs)
+}
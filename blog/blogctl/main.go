@@ -0,0 +1,135 @@
+// Command blogctl creates, reads, updates, deletes, lists and searches blogs
+// on a BlogService server.
+//
+// Usage:
+//
+//	blogctl [global flags] <command> [command flags] [args]
+//
+// Run "blogctl -h" for the list of commands and global flags and
+// "blogctl <command> -h" for the flags of a command.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"example.com/blog/blogpb"
+	"example.com/internal/dial"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// errUsage is returned by commands called with bad arguments; their usage
+// has already been printed.
+var errUsage = errors.New("usage")
+
+type options struct {
+	addr       string
+	useTLS     bool
+	caFile     string
+	serverName string
+	config     string
+	token      string
+	output     string
+	timeout    time.Duration
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var opts options
+	fs := flag.NewFlagSet("blogctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.addr, "addr", "localhost:50051", "address of the blog server, or a file:///path or dnssrv:///name target listing several replicas")
+	fs.BoolVar(&opts.useTLS, "tls", false, "connect using TLS")
+	fs.StringVar(&opts.caFile, "ca-file", "", "CA certificate used to verify the server (implies -tls)")
+	fs.StringVar(&opts.serverName, "server-name", "", "override the server name used to verify the TLS certificate")
+	fs.StringVar(&opts.config, "service-config", "", "JSON file with the retry, hedging and load balancing policies of the connection (default built-in policies)")
+	fs.StringVar(&opts.token, "token", os.Getenv("BLOGCTL_TOKEN"), "bearer token sent with every call (default $BLOGCTL_TOKEN)")
+	fs.StringVar(&opts.output, "o", "table", "output format: table, json or yaml")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "timeout of the whole command")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: blogctl [global flags] <command> [command flags] [args]\n\ncommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %-8s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintf(stderr, "\nglobal flags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	cmd := findCommand(fs.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(stderr, "blogctl: unknown command %q\n", fs.Arg(0))
+		fs.Usage()
+		return 2
+	}
+	p, err := newPrinter(opts.output, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "blogctl: %v\n", err)
+		return 2
+	}
+
+	conn, err := connect(opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "blogctl: could not connect: %v\n", err)
+		return 1
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+	if opts.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+opts.token)
+	}
+
+	env := &env{
+		client: blogpb.NewBlogServiceClient(conn),
+		stdin:  stdin,
+		stderr: stderr,
+		out:    p,
+	}
+	if err := cmd.run(ctx, env, fs.Args()[1:]); err != nil {
+		if err == errUsage {
+			return 2
+		}
+		fmt.Fprintf(stderr, "blogctl %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+func connect(opts options) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if opts.useTLS || opts.caFile != "" {
+		if opts.caFile != "" {
+			var err error
+			creds, err = credentials.NewClientTLSFromFile(opts.caFile, opts.serverName)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			creds = credentials.NewTLS(&tls.Config{ServerName: opts.serverName})
+		}
+	}
+	config, err := dial.Load(opts.config)
+	if err != nil {
+		return nil, err
+	}
+	return dial.Dial(opts.addr, config, grpc.WithTransportCredentials(creds))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"example.com/blog/blogpb"
+	"example.com/internal/harness"
+)
+
+// exec runs the named command against client and returns what it printed.
+func exec(t *testing.T, client blogpb.BlogServiceClient, format, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	p, err := newPrinter(format, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &env{client: client, stdin: strings.NewReader(stdin), stderr: &errOut, out: p}
+	err = findCommand(args[0]).run(context.Background(), e, args[1:])
+	return out.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	c := harness.Blog(t, nil)
+	out, err := exec(t, c, "json", "from stdin", "create", "-author", "rahul", "-title", "first", "-file", "-")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !strings.Contains(out, `"content": "from stdin"`) {
+		t.Fatalf("create output %q does not contain the content read from stdin", out)
+	}
+	res, err := c.ListBlog(context.Background(), &blogpb.ListBlogRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := res.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := first.GetBlog().GetId()
+	if _, err := exec(t, c, "json", "", "create", "-author", "mike", "-title", "second", "-content", "other text"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		format   string
+		args     []string
+		contains []string
+		excludes []string
+		wantErr  bool
+	}{
+		{name: "get table", format: "table", args: []string{"get", id}, contains: []string{"ID", id, "rahul", "first"}},
+		{name: "get yaml", format: "yaml", args: []string{"get", id}, contains: []string{"id: " + id, "authorId: rahul"}},
+		{name: "update", format: "json", args: []string{"update", "-title", "renamed", id}, contains: []string{`"title": "renamed"`, `"content": "from stdin"`}},
+		{name: "list", format: "table", args: []string{"list"}, contains: []string{"renamed", "second"}},
+		{name: "search author", format: "table", args: []string{"search", "-author", "mike"}, contains: []string{"second"}, excludes: []string{"renamed"}},
+		{name: "search text", format: "json", args: []string{"search", "STDIN"}, contains: []string{"renamed"}, excludes: []string{"second"}},
+		{name: "search nothing", format: "json", args: []string{"search", "-title", "missing"}, contains: []string{"[]"}},
+		{name: "delete", format: "table", args: []string{"delete", id}, contains: []string{"deleted " + id}},
+		{name: "get deleted", format: "table", args: []string{"get", id}, wantErr: true},
+		{name: "get without id", format: "table", args: []string{"get"}, wantErr: true},
+		{name: "create without title", format: "table", args: []string{"create", "-author", "rahul"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := exec(t, c, tt.format, "", tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%v: err = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(out, s) {
+					t.Errorf("%v: output %q does not contain %q", tt.args, out, s)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(out, s) {
+					t.Errorf("%v: output %q contains %q", tt.args, out, s)
+				}
+			}
+		})
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"no command", nil},
+		{"unknown command", []string{"publish"}},
+		{"unknown format", []string{"-o", "xml", "list"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := run(tt.args, strings.NewReader(""), &out, &errOut); code != 2 {
+				t.Errorf("run(%v) = %d, want 2", tt.args, code)
+			}
+		})
+	}
+}
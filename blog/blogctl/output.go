@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"example.com/blog/blogpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"gopkg.in/yaml.v3"
+)
+
+// maxTableContent is the number of content characters shown in a table.
+const maxTableContent = 40
+
+// printer writes blogs in one of the supported output formats.
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func newPrinter(format string, w io.Writer) (*printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return &printer{format: format, w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, want table, json or yaml", format)
+}
+
+func (p *printer) blog(blog *blogpb.Blog) error {
+	if p.format == "table" {
+		return p.table([]*blogpb.Blog{blog})
+	}
+	return p.encode(blogValue(blog))
+}
+
+func (p *printer) blogs(blogs []*blogpb.Blog) error {
+	if p.format == "table" {
+		return p.table(blogs)
+	}
+	values := make([]interface{}, 0, len(blogs))
+	for _, blog := range blogs {
+		values = append(values, blogValue(blog))
+	}
+	return p.encode(values)
+}
+
+func (p *printer) deleted(id string) error {
+	if p.format == "table" {
+		_, err := fmt.Fprintf(p.w, "deleted %s\n", id)
+		return err
+	}
+	return p.encode(map[string]string{"blogId": id})
+}
+
+func (p *printer) table(blogs []*blogpb.Blog) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tAUTHOR\tTITLE\tCONTENT")
+	for _, blog := range blogs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", blog.GetId(), blog.GetAuthorId(), blog.GetTitle(), summary(blog.GetContent()))
+	}
+	return tw.Flush()
+}
+
+func (p *printer) encode(v interface{}) error {
+	if p.format == "yaml" {
+		enc := yaml.NewEncoder(p.w)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// blogValue converts blog to a generic value using the protobuf JSON field
+// names, so the JSON and YAML outputs share the same keys.
+func blogValue(blog *blogpb.Blog) interface{} {
+	b, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(blog)
+	if err != nil {
+		return nil
+	}
+	var v map[string]interface{}
+	json.Unmarshal(b, &v)
+	return v
+}
+
+// summary shortens content to a single table cell.
+func summary(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if r := []rune(content); len(r) > maxTableContent {
+		return string(r[:maxTableContent-3]) + "..."
+	}
+	return content
+}
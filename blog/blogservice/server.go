@@ -0,0 +1,138 @@
+// Package blogservice implements the BlogService defined in blogpb.
+package blogservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	"example.com/blog/blogpb"
+	"example.com/blog/blogstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Server implements blogpb.BlogServiceServer on top of a blogstore.Store.
+type Server struct {
+	store blogstore.Store
+}
+
+// New returns a Server keeping its blogs in store.
+func New(store blogstore.Store) *Server {
+	return &Server{store: store}
+}
+
+// storeError converts an error returned by the store into a status error.
+func storeError(err error, action string) error {
+	switch err {
+	case blogstore.ErrInvalidID:
+		return status.Error(codes.InvalidArgument, "Cannot parse ID")
+	case blogstore.ErrNotFound:
+		return status.Error(codes.NotFound, "Cannot find the blog with given id")
+	}
+	return status.Error(codes.Internal, fmt.Sprintf("Cannot %v blog due to error: %v", action, err))
+}
+
+func (s *Server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest) (*blogpb.CreateBlogResponse, error) {
+	fmt.Println("Inside CreateBlog method")
+	blog, err := s.store.Create(ctx, req.GetBlog())
+	if err != nil {
+		fmt.Println("Failed to insert blog")
+		return nil, storeError(err, "insert")
+	}
+	return &blogpb.CreateBlogResponse{
+		Blog: blog,
+	}, nil
+}
+
+func (s *Server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (*blogpb.ReadBlogResponse, error) {
+	fmt.Println("Inside ReadBlog method")
+	blog, err := s.store.Read(ctx, req.GetBlogId())
+	if err != nil {
+		return nil, storeError(err, "read")
+	}
+	tag := etag(blog)
+	if etagMatches(ifNoneMatch(ctx, req), tag) {
+		return &blogpb.ReadBlogResponse{
+			Etag:        tag,
+			NotModified: true,
+		}, nil
+	}
+	return &blogpb.ReadBlogResponse{
+		Blog: blog,
+		Etag: tag,
+	}, nil
+}
+
+// etag returns an HTTP entity tag that changes whenever blog does.
+func etag(blog *blogpb.Blog) string {
+	data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(blog)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ifNoneMatch returns the etags the client of a ReadBlog call holds: the
+// field of req, or else the "if-none-match" metadata sent by the gateway
+// for the If-None-Match HTTP header.
+func ifNoneMatch(ctx context.Context, req *blogpb.ReadBlogRequest) string {
+	if tags := req.GetIfNoneMatch(); tags != "" {
+		return tags
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return strings.Join(md.Get("if-none-match"), ",")
+}
+
+// etagMatches reports whether tag is one of the comma separated etags, or
+// they are "*", comparing weak etags as strong ones.
+func etagMatches(tags, tag string) bool {
+	for _, t := range strings.Split(tags, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest) (*blogpb.UpdateBlogResponse, error) {
+	log.Println("Inside UpdateBlog method")
+	blog, err := s.store.Update(ctx, req.GetBlog())
+	if err != nil {
+		return nil, storeError(err, "update")
+	}
+	return &blogpb.UpdateBlogResponse{
+		Blog: blog,
+	}, nil
+}
+
+func (s *Server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest) (*blogpb.DeleteBlogResponse, error) {
+	log.Println("Inside DeleteBlog method")
+	blogID := req.GetBlogId()
+	if err := s.store.Delete(ctx, blogID); err != nil {
+		return nil, storeError(err, "delete")
+	}
+	return &blogpb.DeleteBlogResponse{
+		BlogId: blogID,
+	}, nil
+}
+
+func (s *Server) ListBlog(req *blogpb.ListBlogRequest, stream blogpb.BlogService_ListBlogServer) error {
+	fmt.Println("Inside ListBlog method")
+	err := s.store.List(stream.Context(), func(blog *blogpb.Blog) error {
+		return stream.Send(&blogpb.ListBlogResponse{
+			Blog: blog,
+		})
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return storeError(err, "read")
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package blogservice_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"example.com/blog/blogpb"
+	"example.com/internal/harness"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// unknownID is a well formed id that is never generated by the store.
+const unknownID = "618798d8d334a39f69d0ef24"
+
+func createBlog(t *testing.T, c blogpb.BlogServiceClient, title string) *blogpb.Blog {
+	t.Helper()
+	res, err := c.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{
+		Blog: &blogpb.Blog{AuthorId: "rahul", Title: title, Content: "content of " + title},
+	})
+	if err != nil {
+		t.Fatalf("CreateBlog: %v", err)
+	}
+	return res.GetBlog()
+}
+
+func TestCreateBlog(t *testing.T) {
+	c := harness.Blog(t, nil)
+	blog := createBlog(t, c, "first")
+	if blog.GetId() == "" {
+		t.Fatal("CreateBlog returned an empty id")
+	}
+	want := &blogpb.Blog{Id: blog.GetId(), AuthorId: "rahul", Title: "first", Content: "content of first"}
+	if !proto.Equal(blog, want) {
+		t.Errorf("CreateBlog = %v, want %v", blog, want)
+	}
+}
+
+func TestReadBlog(t *testing.T) {
+	c := harness.Blog(t, nil)
+	blog := createBlog(t, c, "first")
+	tests := []struct {
+		name string
+		id   string
+		want *blogpb.Blog
+		code codes.Code
+	}{
+		{"existing", blog.GetId(), blog, codes.OK},
+		{"unknown id", unknownID, nil, codes.NotFound},
+		{"malformed id", "not-an-id", nil, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.ReadBlog(context.Background(), &blogpb.ReadBlogRequest{BlogId: tt.id})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("ReadBlog(%q) code = %v, want %v", tt.id, code, tt.code)
+			}
+			if !proto.Equal(res.GetBlog(), tt.want) {
+				t.Errorf("ReadBlog(%q) = %v, want %v", tt.id, res.GetBlog(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBlogConditional(t *testing.T) {
+	c := harness.Blog(t, nil)
+	blog := createBlog(t, c, "first")
+	ctx := context.Background()
+	first, err := c.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blog.GetId()})
+	if err != nil {
+		t.Fatalf("ReadBlog: %v", err)
+	}
+	tag := first.GetEtag()
+	if tag == "" {
+		t.Fatal("ReadBlog returned no etag")
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		ifNoneMatch string
+	}{
+		{"field", ctx, tag},
+		{"weak etag in a list", ctx, `"stale", W/` + tag},
+		{"any", ctx, "*"},
+		{"metadata", metadata.AppendToOutgoingContext(ctx, "if-none-match", tag), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.ReadBlog(tt.ctx, &blogpb.ReadBlogRequest{BlogId: blog.GetId(), IfNoneMatch: tt.ifNoneMatch})
+			if err != nil {
+				t.Fatalf("ReadBlog: %v", err)
+			}
+			if !res.GetNotModified() || res.GetBlog() != nil || res.GetEtag() != tag {
+				t.Errorf("ReadBlog = %v, want not modified with etag %s", res, tag)
+			}
+		})
+	}
+
+	if _, err := c.UpdateBlog(ctx, &blogpb.UpdateBlogRequest{Blog: &blogpb.Blog{Id: blog.GetId(), Title: "second"}}); err != nil {
+		t.Fatalf("UpdateBlog: %v", err)
+	}
+	res, err := c.ReadBlog(ctx, &blogpb.ReadBlogRequest{BlogId: blog.GetId(), IfNoneMatch: tag})
+	if err != nil {
+		t.Fatalf("ReadBlog: %v", err)
+	}
+	if res.GetNotModified() || res.GetBlog().GetTitle() != "second" || res.GetEtag() == tag {
+		t.Errorf("ReadBlog after UpdateBlog = %v, want the updated blog with a new etag", res)
+	}
+}
+
+func TestUpdateBlog(t *testing.T) {
+	c := harness.Blog(t, nil)
+	blog := createBlog(t, c, "first")
+	tests := []struct {
+		name   string
+		update *blogpb.Blog
+		want   *blogpb.Blog
+		code   codes.Code
+	}{
+		{
+			name:   "partial update",
+			update: &blogpb.Blog{Id: blog.GetId(), Title: "updated"},
+			want:   &blogpb.Blog{Id: blog.GetId(), AuthorId: "rahul", Title: "updated", Content: "content of first"},
+		},
+		{
+			name:   "unknown id",
+			update: &blogpb.Blog{Id: unknownID, Title: "updated"},
+			code:   codes.NotFound,
+		},
+		{
+			name:   "malformed id",
+			update: &blogpb.Blog{Id: "not-an-id", Title: "updated"},
+			code:   codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.UpdateBlog(context.Background(), &blogpb.UpdateBlogRequest{Blog: tt.update})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("UpdateBlog code = %v, want %v", code, tt.code)
+			}
+			if !proto.Equal(res.GetBlog(), tt.want) {
+				t.Errorf("UpdateBlog = %v, want %v", res.GetBlog(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteBlog(t *testing.T) {
+	c := harness.Blog(t, nil)
+	blog := createBlog(t, c, "first")
+	tests := []struct {
+		name string
+		id   string
+		code codes.Code
+	}{
+		{"existing", blog.GetId(), codes.OK},
+		{"already deleted", blog.GetId(), codes.NotFound},
+		{"unknown id", unknownID, codes.NotFound},
+		{"malformed id", "not-an-id", codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.DeleteBlog(context.Background(), &blogpb.DeleteBlogRequest{BlogId: tt.id})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("DeleteBlog(%q) code = %v, want %v", tt.id, code, tt.code)
+			}
+			if err == nil && res.GetBlogId() != tt.id {
+				t.Errorf("DeleteBlog(%q) = %q", tt.id, res.GetBlogId())
+			}
+		})
+	}
+	if _, err := c.ReadBlog(context.Background(), &blogpb.ReadBlogRequest{BlogId: blog.GetId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("ReadBlog after delete = %v, want NotFound", err)
+	}
+}
+
+func TestListBlog(t *testing.T) {
+	c := harness.Blog(t, nil)
+	tests := []struct {
+		name   string
+		titles []string
+	}{
+		{"empty", nil},
+		{"several", []string{"first", "second", "third"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []*blogpb.Blog
+			for _, title := range tt.titles {
+				want = append(want, createBlog(t, c, title))
+			}
+			stream, err := c.ListBlog(context.Background(), &blogpb.ListBlogRequest{})
+			if err != nil {
+				t.Fatalf("ListBlog: %v", err)
+			}
+			var got []*blogpb.Blog
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got = append(got, res.GetBlog())
+			}
+			if len(got) != len(want) {
+				t.Fatalf("ListBlog returned %d blogs, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !proto.Equal(got[i], want[i]) {
+					t.Errorf("ListBlog[%d] = %v, want %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package blogstore
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"example.com/blog/blogpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// CacheOptions configure a Cache.
+type CacheOptions struct {
+	// Size is the most blogs kept. A listing is only kept when it holds
+	// at most Size blogs.
+	Size int
+	// TTL is how long a blog or listing is served from memory before the
+	// store is read again. It bounds how stale a read may be after a write
+	// made through another server.
+	TTL time.Duration
+}
+
+// CacheStats count how a Cache served reads.
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	ListHits      uint64 `json:"listHits"`
+	ListMisses    uint64 `json:"listMisses"`
+	Evictions     uint64 `json:"evictions"`
+	Invalidations uint64 `json:"invalidations"`
+	Entries       int    `json:"entries"`
+}
+
+// Cache is a Store keeping recently read blogs, and the listing of all
+// blogs, in memory in front of another Store. The least recently read
+// blogs are evicted first. Writes made through the cache invalidate what
+// they change at once.
+type Cache struct {
+	store Store
+	opts  CacheOptions
+	now   func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List // of *cacheEntry, most recently read first
+	entries map[string]*list.Element
+	listing []*blogpb.Blog
+	// listingExpires is zero when no listing is kept.
+	listingExpires time.Time
+	// gen counts the writes. A read that started before a write does not
+	// fill the cache with what it read, which may predate the write.
+	gen   uint64
+	stats CacheStats
+}
+
+type cacheEntry struct {
+	blog    *blogpb.Blog
+	expires time.Time
+}
+
+// NewCache returns a Cache in front of store.
+func NewCache(store Store, opts CacheOptions) *Cache {
+	return &Cache{
+		store:   store,
+		opts:    opts,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Stats returns the counters of c.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// Publish exports the stats of c as the expvar name, served as JSON by
+// expvar.Handler. It panics if name is already in use.
+func (c *Cache) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return c.Stats() }))
+}
+
+func (c *Cache) Create(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	created, err := c.store.Create(ctx, blog)
+	c.invalidate("")
+	return created, err
+}
+
+func (c *Cache) Read(ctx context.Context, id string) (*blogpb.Blog, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[id]; ok {
+		entry := e.Value.(*cacheEntry)
+		if c.now().Before(entry.expires) {
+			c.lru.MoveToFront(e)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return proto.Clone(entry.blog).(*blogpb.Blog), nil
+		}
+		c.remove(e)
+	}
+	c.stats.Misses++
+	gen := c.gen
+	c.mu.Unlock()
+
+	blog, err := c.store.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		c.add(blog)
+	}
+	return blog, nil
+}
+
+func (c *Cache) Update(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	updated, err := c.store.Update(ctx, blog)
+	c.invalidate(blog.GetId())
+	return updated, err
+}
+
+func (c *Cache) Delete(ctx context.Context, id string) error {
+	err := c.store.Delete(ctx, id)
+	c.invalidate(id)
+	return err
+}
+
+func (c *Cache) List(ctx context.Context, fn func(*blogpb.Blog) error) error {
+	c.mu.Lock()
+	if c.now().Before(c.listingExpires) {
+		listing := c.listing
+		c.stats.ListHits++
+		c.mu.Unlock()
+		for _, blog := range listing {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(proto.Clone(blog).(*blogpb.Blog)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	c.stats.ListMisses++
+	gen := c.gen
+	c.mu.Unlock()
+
+	var listing []*blogpb.Blog
+	tooLong := c.opts.Size <= 0
+	err := c.store.List(ctx, func(blog *blogpb.Blog) error {
+		if !tooLong {
+			if tooLong = len(listing) == c.opts.Size; tooLong {
+				listing = nil
+			} else {
+				listing = append(listing, proto.Clone(blog).(*blogpb.Blog))
+			}
+		}
+		return fn(blog)
+	})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen && !tooLong {
+		c.listing = listing
+		c.listingExpires = c.now().Add(c.opts.TTL)
+	}
+	return nil
+}
+
+// invalidate drops the blog with the given id, if any, and the listing.
+func (c *Cache) invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.listing, c.listingExpires = nil, time.Time{}
+	if e, ok := c.entries[id]; ok {
+		c.remove(e)
+		c.stats.Invalidations++
+	}
+}
+
+func (c *Cache) add(blog *blogpb.Blog) {
+	if c.opts.Size <= 0 {
+		return
+	}
+	entry := &cacheEntry{blog: proto.Clone(blog).(*blogpb.Blog), expires: c.now().Add(c.opts.TTL)}
+	if e, ok := c.entries[blog.GetId()]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[blog.GetId()] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.opts.Size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(e *list.Element) {
+	delete(c.entries, e.Value.(*cacheEntry).blog.GetId())
+	c.lru.Remove(e)
+}
@@ -0,0 +1,188 @@
+package blogstore_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/blog/blogpb"
+	"example.com/blog/blogstore"
+)
+
+func TestCacheStore(t *testing.T) {
+	testStore(t, func(*testing.T) blogstore.Store {
+		return blogstore.NewCache(blogstore.NewMemory(), blogstore.CacheOptions{Size: 100, TTL: time.Minute})
+	})
+}
+
+// countingStore counts the reads that reach a store.
+type countingStore struct {
+	blogstore.Store
+	reads, lists int32
+}
+
+func (s *countingStore) Read(ctx context.Context, id string) (*blogpb.Blog, error) {
+	atomic.AddInt32(&s.reads, 1)
+	return s.Store.Read(ctx, id)
+}
+
+func (s *countingStore) List(ctx context.Context, fn func(*blogpb.Blog) error) error {
+	atomic.AddInt32(&s.lists, 1)
+	return s.Store.List(ctx, fn)
+}
+
+func newCache(t *testing.T, size int) (*blogstore.Cache, *countingStore, func(time.Duration)) {
+	t.Helper()
+	store := &countingStore{Store: blogstore.NewMemory()}
+	c := blogstore.NewCache(store, blogstore.CacheOptions{Size: size, TTL: time.Minute})
+	now := time.Unix(1000, 0)
+	blogstore.SetCacheClock(c, func() time.Time { return now })
+	return c, store, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCacheRead(t *testing.T) {
+	ctx := context.Background()
+	c, store, advance := newCache(t, 2)
+	var ids []string
+	for _, title := range []string{"a", "b", "c"} {
+		blog, err := c.Create(ctx, &blogpb.Blog{Title: title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, blog.GetId())
+	}
+	read := func(id string) *blogpb.Blog {
+		t.Helper()
+		blog, err := c.Read(ctx, id)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		return blog
+	}
+
+	read(ids[0])
+	read(ids[0]).Title = "changed by the caller"
+	if got := read(ids[0]).GetTitle(); got != "a" {
+		t.Errorf("cached title = %q, want a", got)
+	}
+	if store.reads != 1 {
+		t.Errorf("store read %d times, want once", store.reads)
+	}
+
+	// the least recently read blog is evicted
+	read(ids[1])
+	read(ids[0])
+	read(ids[2])
+	read(ids[0])
+	read(ids[1])
+	if store.reads != 4 {
+		t.Errorf("store read %d times, want 4", store.reads)
+	}
+
+	// entries expire
+	advance(time.Minute)
+	read(ids[1])
+	if store.reads != 5 {
+		t.Errorf("store read %d times after the TTL, want 5", store.reads)
+	}
+
+	// writes invalidate at once
+	if _, err := c.Update(ctx, &blogpb.Blog{Id: ids[1], Title: "b2"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(ids[1]).GetTitle(); got != "b2" {
+		t.Errorf("title after Update = %q, want b2", got)
+	}
+	if err := c.Delete(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Read(ctx, ids[1]); err != blogstore.ErrNotFound {
+		t.Errorf("Read after Delete = %v, want ErrNotFound", err)
+	}
+
+	want := blogstore.CacheStats{Hits: 4, Misses: 7, Evictions: 2, Invalidations: 2, Entries: 1}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestCacheList(t *testing.T) {
+	ctx := context.Background()
+	c, store, advance := newCache(t, 2)
+	list := func() []string {
+		t.Helper()
+		var titles []string
+		if err := c.List(ctx, func(blog *blogpb.Blog) error {
+			titles = append(titles, blog.GetTitle())
+			return nil
+		}); err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		return titles
+	}
+	create := func(title string) {
+		t.Helper()
+		if _, err := c.Create(ctx, &blogpb.Blog{Title: title}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	create("a")
+	list()
+	if got := list(); len(got) != 1 || store.lists != 1 {
+		t.Errorf("second List = %v after %d store lists, want [a] from the cache", got, store.lists)
+	}
+	create("b")
+	if got := list(); len(got) != 2 || store.lists != 2 {
+		t.Errorf("List after Create = %v after %d store lists, want [a b] from the store", got, store.lists)
+	}
+	advance(time.Minute)
+	list()
+	if store.lists != 3 {
+		t.Errorf("store listed %d times after the TTL, want 3", store.lists)
+	}
+
+	// listings longer than the cache are not kept
+	create("c")
+	list()
+	if got := list(); len(got) != 3 || store.lists != 5 {
+		t.Errorf("List of 3 blogs = %v after %d store lists, want all from the store", got, store.lists)
+	}
+}
+
+// racingStore updates a blog while a read of it is in flight.
+type racingStore struct {
+	blogstore.Store
+	during func()
+}
+
+func (s *racingStore) Read(ctx context.Context, id string) (*blogpb.Blog, error) {
+	blog, err := s.Store.Read(ctx, id)
+	if s.during != nil {
+		s.during()
+		s.during = nil
+	}
+	return blog, err
+}
+
+func TestCacheReadRacingUpdate(t *testing.T) {
+	ctx := context.Background()
+	store := &racingStore{Store: blogstore.NewMemory()}
+	c := blogstore.NewCache(store, blogstore.CacheOptions{Size: 10, TTL: time.Minute})
+	blog, err := c.Create(ctx, &blogpb.Blog{Title: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.during = func() {
+		if _, err := c.Update(ctx, &blogpb.Blog{Id: blog.GetId(), Title: "new"}); err != nil {
+			t.Error(err)
+		}
+	}
+	if got, _ := c.Read(ctx, blog.GetId()); got.GetTitle() != "old" {
+		t.Fatalf("racing Read = %v, want the old title", got)
+	}
+	if got, _ := c.Read(ctx, blog.GetId()); got.GetTitle() != "new" {
+		t.Errorf("Read after the racing Update = %v, want the new title", got)
+	}
+}
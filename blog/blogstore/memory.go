@@ -0,0 +1,109 @@
+package blogstore
+
+import (
+	"context"
+	"sync"
+
+	"example.com/blog/blogpb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/proto"
+)
+
+type memoryStore struct {
+	mu    sync.RWMutex
+	ids   []string
+	blogs map[string]*blogpb.Blog
+}
+
+// NewMemory returns a Store that keeps blogs in memory. Ids have the same
+// format as the ones generated by the Mongo store.
+func NewMemory() Store {
+	return &memoryStore{blogs: make(map[string]*blogpb.Blog)}
+}
+
+func (m *memoryStore) Create(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	stored := &blogpb.Blog{
+		Id:       primitive.NewObjectID().Hex(),
+		AuthorId: blog.GetAuthorId(),
+		Title:    blog.GetTitle(),
+		Content:  blog.GetContent(),
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ids = append(m.ids, stored.Id)
+	m.blogs[stored.Id] = stored
+	return proto.Clone(stored).(*blogpb.Blog), nil
+}
+
+func (m *memoryStore) Read(ctx context.Context, id string) (*blogpb.Blog, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, ErrInvalidID
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stored, ok := m.blogs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return proto.Clone(stored).(*blogpb.Blog), nil
+}
+
+func (m *memoryStore) Update(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	if _, err := primitive.ObjectIDFromHex(blog.GetId()); err != nil {
+		return nil, ErrInvalidID
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.blogs[blog.GetId()]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if blog.GetAuthorId() != "" {
+		stored.AuthorId = blog.GetAuthorId()
+	}
+	if blog.GetTitle() != "" {
+		stored.Title = blog.GetTitle()
+	}
+	if blog.GetContent() != "" {
+		stored.Content = blog.GetContent()
+	}
+	return proto.Clone(stored).(*blogpb.Blog), nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, id string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return ErrInvalidID
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.blogs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.blogs, id)
+	for i, storedID := range m.ids {
+		if storedID == id {
+			m.ids = append(m.ids[:i], m.ids[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *memoryStore) List(ctx context.Context, fn func(*blogpb.Blog) error) error {
+	// snapshot so fn may call back into the store
+	m.mu.RLock()
+	blogs := make([]*blogpb.Blog, 0, len(m.ids))
+	for _, id := range m.ids {
+		blogs = append(blogs, proto.Clone(m.blogs[id]).(*blogpb.Blog))
+	}
+	m.mu.RUnlock()
+	for _, blog := range blogs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(blog); err != nil {
+			return err
+		}
+	}
+	return nil
+}
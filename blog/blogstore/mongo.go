@@ -0,0 +1,121 @@
+package blogstore
+
+import (
+	"context"
+
+	"example.com/blog/blogpb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type blogItem struct {
+	Id       primitive.ObjectID `bson:"_id,omitempty"`
+	AuthorId string             `bson:"author_id,omitempty"`
+	Content  string             `bson:"content,omitempty"`
+	Title    string             `bson:"title,omitempty"`
+}
+
+func dataToBlog(data *blogItem) *blogpb.Blog {
+	return &blogpb.Blog{
+		Id:       data.Id.Hex(),
+		AuthorId: data.AuthorId,
+		Title:    data.Title,
+		Content:  data.Content,
+	}
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongo returns a Store keeping blogs in the given collection.
+func NewMongo(collection *mongo.Collection) Store {
+	return &mongoStore{collection: collection}
+}
+
+func (m *mongoStore) Create(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	data := blogItem{
+		AuthorId: blog.GetAuthorId(),
+		Content:  blog.GetContent(),
+		Title:    blog.GetTitle(),
+	}
+	res, err := m.collection.InsertOne(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	data.Id = res.InsertedID.(primitive.ObjectID)
+	return dataToBlog(&data), nil
+}
+
+func (m *mongoStore) Read(ctx context.Context, id string) (*blogpb.Blog, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	data := &blogItem{}
+	err = m.collection.FindOne(ctx, bson.M{"_id": oid}).Decode(data)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dataToBlog(data), nil
+}
+
+func (m *mongoStore) Update(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error) {
+	oid, err := primitive.ObjectIDFromHex(blog.GetId())
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	data := &blogItem{
+		AuthorId: blog.GetAuthorId(),
+		Content:  blog.GetContent(),
+		Title:    blog.GetTitle(),
+	}
+	// return the document after the update so PATCH /v1/blogs/{id} echoes the new state
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = m.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.M{"$set": data}, opts).Decode(data)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dataToBlog(data), nil
+}
+
+func (m *mongoStore) Delete(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+	res, err := m.collection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoStore) List(ctx context.Context, fn func(*blogpb.Blog) error) error {
+	cur, err := m.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		data := &blogItem{}
+		if err := cur.Decode(data); err != nil {
+			return err
+		}
+		if err := fn(dataToBlog(data)); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
@@ -0,0 +1,91 @@
+package blogstore_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"example.com/blog/blogstore"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestMongoStore runs the store suite against a throwaway mongod listening on
+// a random port with a temporary data directory. It is skipped when no mongod
+// binary is on PATH.
+func TestMongoStore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping mongod integration test in short mode")
+	}
+	client := startMongod(t)
+	collections := 0
+	testStore(t, func(t *testing.T) blogstore.Store {
+		collections++
+		collection := client.Database("BLOG_TEST").Collection(fmt.Sprintf("blog_%d", collections))
+		t.Cleanup(func() { collection.Drop(context.Background()) })
+		return blogstore.NewMongo(collection)
+	})
+}
+
+// startMongod starts a mongod for the duration of the test and returns a
+// client connected to it.
+func startMongod(t *testing.T) *mongo.Client {
+	t.Helper()
+	bin, err := exec.LookPath("mongod")
+	if err != nil {
+		t.Skip("mongod not found on PATH")
+	}
+	port, err := freePort()
+	if err != nil {
+		t.Fatalf("failed to pick a port: %v", err)
+	}
+	cmd := exec.Command(bin,
+		"--port", strconv.Itoa(port),
+		"--bind_ip", "127.0.0.1",
+		"--dbpath", t.TempDir(),
+		"--nounixsocket",
+		"--quiet",
+	)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start mongod: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	uri := fmt.Sprintf("mongodb://127.0.0.1:%d", port)
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to create mongo client: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	// mongod takes a moment before it accepts connections
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = client.Ping(ctx, nil)
+		cancel()
+		if err == nil {
+			return client
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("mongod at %s did not become ready: %v", uri, err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func freePort() (int, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,31 @@
+// Package blogstore persists blogs for the BlogService.
+package blogstore
+
+import (
+	"context"
+	"errors"
+
+	"example.com/blog/blogpb"
+)
+
+var (
+	// ErrNotFound is returned when no blog has the requested id.
+	ErrNotFound = errors.New("blog not found")
+	// ErrInvalidID is returned when an id is not a valid blog id.
+	ErrInvalidID = errors.New("invalid blog id")
+)
+
+// Store is the storage used by the BlogService.
+type Store interface {
+	// Create stores a new blog and returns it with its generated id.
+	Create(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error)
+	// Read returns the blog with the given id.
+	Read(ctx context.Context, id string) (*blogpb.Blog, error)
+	// Update overwrites the non-empty fields of the blog with blog.Id and
+	// returns the updated blog.
+	Update(ctx context.Context, blog *blogpb.Blog) (*blogpb.Blog, error)
+	// Delete removes the blog with the given id.
+	Delete(ctx context.Context, id string) error
+	// List calls fn for every stored blog, stopping at the first error.
+	List(ctx context.Context, fn func(*blogpb.Blog) error) error
+}
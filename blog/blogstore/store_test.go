@@ -0,0 +1,174 @@
+package blogstore_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"example.com/blog/blogpb"
+	"example.com/blog/blogstore"
+	"google.golang.org/protobuf/proto"
+)
+
+// unknownID is a well formed id that is never generated by a store.
+const unknownID = "618798d8d334a39f69d0ef24"
+
+// testStore runs the behaviour every Store implementation must share.
+// newStore must return an empty store on each call.
+func testStore(t *testing.T, newStore func(t *testing.T) blogstore.Store) {
+	t.Run("CRUD", func(t *testing.T) { testCRUD(t, newStore(t)) })
+	t.Run("MissingIDs", func(t *testing.T) { testMissingIDs(t, newStore(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, newStore(t)) })
+	t.Run("ConcurrentUpdates", func(t *testing.T) { testConcurrentUpdates(t, newStore(t)) })
+}
+
+func testCRUD(t *testing.T, s blogstore.Store) {
+	ctx := context.Background()
+	created, err := s.Create(ctx, &blogpb.Blog{AuthorId: "rahul", Title: "first", Content: "hello"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.GetId() == "" {
+		t.Fatal("Create returned an empty id")
+	}
+
+	read, err := s.Read(ctx, created.GetId())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !proto.Equal(read, created) {
+		t.Errorf("Read = %v, want %v", read, created)
+	}
+
+	updated, err := s.Update(ctx, &blogpb.Blog{Id: created.GetId(), Content: "updated"})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	want := &blogpb.Blog{Id: created.GetId(), AuthorId: "rahul", Title: "first", Content: "updated"}
+	if !proto.Equal(updated, want) {
+		t.Errorf("Update = %v, want %v", updated, want)
+	}
+	if read, _ := s.Read(ctx, created.GetId()); !proto.Equal(read, want) {
+		t.Errorf("Read after Update = %v, want %v", read, want)
+	}
+
+	if err := s.Delete(ctx, created.GetId()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Read(ctx, created.GetId()); err != blogstore.ErrNotFound {
+		t.Errorf("Read after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func testMissingIDs(t *testing.T, s blogstore.Store) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		id   string
+		want error
+	}{
+		{"unknown", unknownID, blogstore.ErrNotFound},
+		{"malformed", "not-an-id", blogstore.ErrInvalidID},
+		{"empty", "", blogstore.ErrInvalidID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Read(ctx, tt.id); err != tt.want {
+				t.Errorf("Read(%q) = %v, want %v", tt.id, err, tt.want)
+			}
+			if _, err := s.Update(ctx, &blogpb.Blog{Id: tt.id, Title: "x"}); err != tt.want {
+				t.Errorf("Update(%q) = %v, want %v", tt.id, err, tt.want)
+			}
+			if err := s.Delete(ctx, tt.id); err != tt.want {
+				t.Errorf("Delete(%q) = %v, want %v", tt.id, err, tt.want)
+			}
+		})
+	}
+}
+
+func testList(t *testing.T, s blogstore.Store) {
+	ctx := context.Background()
+	want := map[string]*blogpb.Blog{}
+	for i := 0; i < 5; i++ {
+		blog, err := s.Create(ctx, &blogpb.Blog{AuthorId: "rahul", Title: fmt.Sprintf("blog %d", i)})
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		want[blog.GetId()] = blog
+	}
+	got := map[string]*blogpb.Blog{}
+	err := s.List(ctx, func(blog *blogpb.Blog) error {
+		got[blog.GetId()] = blog
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("List returned %d blogs, want %d", len(got), len(want))
+	}
+	for id, blog := range want {
+		if !proto.Equal(got[id], blog) {
+			t.Errorf("List[%s] = %v, want %v", id, got[id], blog)
+		}
+	}
+
+	stop := fmt.Errorf("stop")
+	calls := 0
+	err = s.List(ctx, func(*blogpb.Blog) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("List with failing callback = %v after %d calls, want %v after 1", err, calls, stop)
+	}
+}
+
+func testConcurrentUpdates(t *testing.T, s blogstore.Store) {
+	ctx := context.Background()
+	blog, err := s.Create(ctx, &blogpb.Blog{AuthorId: "rahul", Title: "title", Content: "content"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	const writers = 20
+	titles := map[string]bool{}
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*writers)
+	for i := 0; i < writers; i++ {
+		title := fmt.Sprintf("title %d", i)
+		titles[title] = true
+		wg.Add(2)
+		go func(title string) {
+			defer wg.Done()
+			_, err := s.Update(ctx, &blogpb.Blog{Id: blog.GetId(), Title: title})
+			errs <- err
+		}(title)
+		go func() {
+			defer wg.Done()
+			_, err := s.Read(ctx, blog.GetId())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent access: %v", err)
+		}
+	}
+	final, err := s.Read(ctx, blog.GetId())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if !titles[final.GetTitle()] {
+		t.Errorf("final title %q was not written by any update", final.GetTitle())
+	}
+	if final.GetAuthorId() != "rahul" || final.GetContent() != "content" {
+		t.Errorf("fields untouched by the updates changed: %v", final)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(*testing.T) blogstore.Store { return blogstore.NewMemory() })
+}
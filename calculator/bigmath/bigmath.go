@@ -0,0 +1,293 @@
+// Package bigmath does arbitrary-precision decimal arithmetic on numbers
+// given as decimal strings.
+//
+// Addition, subtraction, multiplication, division and integer powers are
+// computed exactly on rationals; only the final result is rounded, to a
+// number of significant digits and with a rounding mode chosen by the
+// caller. Square roots are computed with enough guard bits that the rounded
+// result is correct.
+package bigmath
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode selects how results are rounded to the requested precision.
+type RoundingMode int
+
+const (
+	HalfEven RoundingMode = iota // to nearest, ties to even
+	HalfUp                       // to nearest, ties away from zero
+	HalfDown                     // to nearest, ties toward zero
+	Up                           // away from zero
+	Down                         // toward zero (truncation)
+	Ceiling                      // toward +Inf
+	Floor                        // toward -Inf
+)
+
+const (
+	// DefaultPrecision is the number of significant digits used for results
+	// that have no finite decimal representation when the caller does not
+	// ask for a precision (decimal128's precision).
+	DefaultPrecision = 34
+	// MaxPrecision bounds the requested number of significant digits.
+	MaxPrecision = 10000
+	// maxExponent bounds the decimal exponent of inputs and of the
+	// exponent given to Pow.
+	maxExponent = 10000
+	// maxResultBits bounds the size of the intermediate results of Pow.
+	maxResultBits = 1 << 20
+)
+
+var (
+	// ErrDivisionByZero is returned when dividing by zero.
+	ErrDivisionByZero = errors.New("division by zero")
+	// ErrNegativeSqrt is returned for the square root of a negative number.
+	ErrNegativeSqrt = errors.New("square root of a negative number")
+	// ErrTooLarge is returned when a value or result exceeds the limits.
+	ErrTooLarge = errors.New("number too large")
+)
+
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE]([+-]?\d+))?$`)
+
+// Parse parses a decimal number such as "-12", "0.125" or "6.02e23".
+func Parse(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	m := decimalPattern.FindStringSubmatch(s)
+	if m == nil {
+		return nil, fmt.Errorf("invalid decimal number %q", s)
+	}
+	if m[3] != "" {
+		exp, err := strconv.Atoi(m[3])
+		if err != nil || exp > maxExponent || exp < -maxExponent {
+			return nil, fmt.Errorf("exponent of %q out of range [-%d, %d]", s, maxExponent, maxExponent)
+		}
+	}
+	if len(s) > maxExponent {
+		return nil, ErrTooLarge
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal number %q", s)
+	}
+	return r, nil
+}
+
+// Add returns x + y.
+func Add(x, y *big.Rat) *big.Rat { return new(big.Rat).Add(x, y) }
+
+// Sub returns x - y.
+func Sub(x, y *big.Rat) *big.Rat { return new(big.Rat).Sub(x, y) }
+
+// Mul returns x * y.
+func Mul(x, y *big.Rat) *big.Rat { return new(big.Rat).Mul(x, y) }
+
+// Quo returns x / y.
+func Quo(x, y *big.Rat) (*big.Rat, error) {
+	if y.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	return new(big.Rat).Quo(x, y), nil
+}
+
+// Pow returns x raised to the integer power y.
+func Pow(x, y *big.Rat) (*big.Rat, error) {
+	if !y.IsInt() {
+		return nil, errors.New("exponent must be an integer")
+	}
+	if !y.Num().IsInt64() || y.Num().Int64() > maxExponent || y.Num().Int64() < -maxExponent {
+		return nil, fmt.Errorf("exponent out of range [-%d, %d]", maxExponent, maxExponent)
+	}
+	n := y.Num().Int64()
+	if n < 0 && x.Sign() == 0 {
+		return nil, ErrDivisionByZero
+	}
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	bits := int64(x.Num().BitLen())
+	if b := int64(x.Denom().BitLen()); b > bits {
+		bits = b
+	}
+	if bits*abs > maxResultBits {
+		return nil, ErrTooLarge
+	}
+	e := big.NewInt(abs)
+	num := new(big.Int).Exp(x.Num(), e, nil)
+	den := new(big.Int).Exp(x.Denom(), e, nil)
+	if n < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// Sqrt returns the square root of x rounded like Format, and whether the
+// result is exact.
+func Sqrt(x *big.Rat, digits int, mode RoundingMode) (string, bool, error) {
+	if x.Sign() < 0 {
+		return "", false, ErrNegativeSqrt
+	}
+	if x.Sign() == 0 {
+		return "0", true, nil
+	}
+	// exact when numerator and denominator are perfect squares
+	num, den := new(big.Int).Sqrt(x.Num()), new(big.Int).Sqrt(x.Denom())
+	if new(big.Int).Mul(num, num).Cmp(x.Num()) == 0 && new(big.Int).Mul(den, den).Cmp(x.Denom()) == 0 {
+		return Format(new(big.Rat).SetFrac(num, den), digits, mode)
+	}
+	if digits == 0 {
+		digits = DefaultPrecision
+	}
+	if digits > MaxPrecision || digits < 0 {
+		return Format(x, digits, mode)
+	}
+	// an irrational root is never exactly halfway between two decimals, so
+	// rounding an approximation with enough guard bits gives the correctly
+	// rounded result
+	prec := uint(float64(digits)*3.33) + 64
+	f := new(big.Float).SetPrec(prec).SetRat(x)
+	f.Sqrt(f)
+	approx, _ := f.Rat(nil)
+	s, _, err := Format(approx, digits, mode)
+	return s, false, err
+}
+
+// Format rounds r to digits significant digits with mode and returns it in
+// plain decimal notation with trailing zeros removed, along with whether
+// the rounding was exact. When digits is 0 exact decimals are returned in
+// full and the others are rounded to DefaultPrecision digits.
+func Format(r *big.Rat, digits int, mode RoundingMode) (string, bool, error) {
+	if digits > MaxPrecision {
+		return "", false, fmt.Errorf("precision larger than %d digits", MaxPrecision)
+	}
+	if digits < 0 {
+		return "", false, errors.New("negative precision")
+	}
+	if r.Sign() == 0 {
+		return "0", true, nil
+	}
+	if digits == 0 {
+		if scale, ok := terminatingScale(r.Denom()); ok {
+			n := new(big.Int).Mul(r.Num(), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil))
+			n.Quo(n, r.Denom())
+			return decimalString(n, scale), true, nil
+		}
+		digits = DefaultPrecision
+	}
+	// scale so that the integer part of r*10^scale has digits digits
+	scale := digits - 1 - exponent10(r)
+	q := new(big.Rat).Mul(r, pow10Rat(scale))
+	n, exact := roundInt(q, mode)
+	return decimalString(n, scale), exact, nil
+}
+
+// terminatingScale reports whether 1/den has a finite decimal expansion and
+// how many fractional digits it needs.
+func terminatingScale(den *big.Int) (int, bool) {
+	d := new(big.Int).Set(den)
+	two, five := big.NewInt(2), big.NewInt(5)
+	var twos, fives int
+	m := new(big.Int)
+	for d.Cmp(big.NewInt(1)) != 0 {
+		switch {
+		case m.Mod(d, two).Sign() == 0:
+			d.Quo(d, two)
+			twos++
+		case m.Mod(d, five).Sign() == 0:
+			d.Quo(d, five)
+			fives++
+		default:
+			return 0, false
+		}
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
+
+// exponent10 returns floor(log10(|r|)) for r != 0.
+func exponent10(r *big.Rat) int {
+	num := new(big.Int).Abs(r.Num())
+	e := len(num.String()) - len(r.Denom().String())
+	// 10^e <= |r| < 10^(e+1) holds for e or e-1
+	abs := new(big.Rat).Abs(r)
+	if abs.Cmp(pow10Rat(e)) < 0 {
+		e--
+	}
+	return e
+}
+
+func pow10Rat(e int) *big.Rat {
+	if e >= 0 {
+		return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(e)), nil))
+	}
+	return new(big.Rat).SetFrac(big.NewInt(1), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-e)), nil))
+}
+
+// roundInt rounds q to an integer with mode and reports whether q already
+// was one.
+func roundInt(q *big.Rat, mode RoundingMode) (*big.Int, bool) {
+	n, rem := new(big.Int).QuoRem(q.Num(), q.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return n, true
+	}
+	sign := q.Sign()
+	// compare the discarded fraction with one half
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+	cmp := half.Cmp(q.Denom())
+	away := false
+	switch mode {
+	case HalfEven:
+		away = cmp > 0 || (cmp == 0 && n.Bit(0) == 1)
+	case HalfUp:
+		away = cmp >= 0
+	case HalfDown:
+		away = cmp > 0
+	case Up:
+		away = true
+	case Down:
+		away = false
+	case Ceiling:
+		away = sign > 0
+	case Floor:
+		away = sign < 0
+	}
+	if away {
+		n.Add(n, big.NewInt(int64(sign)))
+	}
+	return n, false
+}
+
+// decimalString formats n * 10^-scale without trailing fractional zeros.
+func decimalString(n *big.Int, scale int) string {
+	digits := new(big.Int).Abs(n).String()
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+	}
+	if scale <= 0 {
+		if digits == "0" {
+			return "0"
+		}
+		return sign + digits + strings.Repeat("0", -scale)
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	intPart, frac := digits[:len(digits)-scale], strings.TrimRight(digits[len(digits)-scale:], "0")
+	if frac == "" {
+		if intPart == "0" {
+			return "0"
+		}
+		return sign + intPart
+	}
+	return sign + intPart + "." + frac
+}
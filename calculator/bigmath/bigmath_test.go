@@ -0,0 +1,167 @@
+package bigmath
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // as a fraction
+		ok   bool
+	}{
+		{"2147483648", "2147483648/1", true},
+		{"-0.125", "-1/8", true},
+		{"+.5", "1/2", true},
+		{"6.02e23", "602000000000000000000000/1", true},
+		{"1E-3", "1/1000", true},
+		{"010", "10/1", true},
+		{"1/3", "", false},
+		{"0x10", "", false},
+		{"1e", "", false},
+		{"", "", false},
+		{"1e100000", "", false},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("Parse(%q) error = %v, want ok %v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && r.String() != tt.want {
+			t.Errorf("Parse(%q) = %v, want %v", tt.in, r, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		x      string
+		y      string // divisor
+		digits int
+		mode   RoundingMode
+		want   string
+		exact  bool
+	}{
+		{"1", "4", 0, HalfEven, "0.25", true},
+		{"1", "3", 0, HalfEven, "0.3333333333333333333333333333333333", false},
+		{"2", "3", 5, HalfEven, "0.66667", false},
+		{"2", "3", 5, Down, "0.66666", false},
+		{"-2", "3", 5, Floor, "-0.66667", false},
+		{"-2", "3", 5, Ceiling, "-0.66666", false},
+		{"25", "1", 1, HalfEven, "20", false},
+		{"35", "1", 1, HalfEven, "40", false},
+		{"25", "1", 1, HalfUp, "30", false},
+		{"25", "1", 1, HalfDown, "20", false},
+		{"21", "1", 1, Up, "30", false},
+		{"-21", "1", 1, Up, "-30", false},
+		{"999", "1", 2, HalfEven, "1000", false},
+		{"123456", "1", 3, Down, "123000", false},
+		{"1", "1000", 3, HalfEven, "0.001", true},
+		{"0", "1", 3, HalfEven, "0", true},
+		{"12.5", "1", 10, HalfEven, "12.5", true},
+	}
+	for _, tt := range tests {
+		x, _ := Parse(tt.x)
+		y, _ := Parse(tt.y)
+		r, _ := Quo(x, y)
+		got, exact, err := Format(r, tt.digits, tt.mode)
+		if err != nil {
+			t.Errorf("Format(%s/%s, %d): %v", tt.x, tt.y, tt.digits, err)
+			continue
+		}
+		if got != tt.want || exact != tt.exact {
+			t.Errorf("Format(%s/%s, %d, %v) = %s, %v, want %s, %v", tt.x, tt.y, tt.digits, tt.mode, got, exact, tt.want, tt.exact)
+		}
+	}
+}
+
+func TestOperations(t *testing.T) {
+	x, _ := Parse("2147483647")
+	one, _ := Parse("1")
+	if got, _, _ := Format(Add(x, one), 0, HalfEven); got != "2147483648" {
+		t.Errorf("2147483647 + 1 = %s", got)
+	}
+	a, _ := Parse("0.1")
+	b, _ := Parse("0.2")
+	if got, exact, _ := Format(Add(a, b), 0, HalfEven); got != "0.3" || !exact {
+		t.Errorf("0.1 + 0.2 = %s (exact %v), want 0.3", got, exact)
+	}
+	two, _ := Parse("2")
+	hundred, _ := Parse("100")
+	p, err := Pow(two, hundred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := Format(p, 0, HalfEven); got != "1267650600228229401496703205376" {
+		t.Errorf("2^100 = %s", got)
+	}
+	minusTwo, _ := Parse("-2")
+	p, _ = Pow(two, minusTwo)
+	if got, _, _ := Format(p, 0, HalfEven); got != "0.25" {
+		t.Errorf("2^-2 = %s", got)
+	}
+	half, _ := Parse("0.5")
+	if _, err := Pow(two, half); err == nil {
+		t.Error("2^0.5 did not fail")
+	}
+	zero, _ := Parse("0")
+	if _, err := Quo(one, zero); err != ErrDivisionByZero {
+		t.Errorf("1/0 error = %v", err)
+	}
+	huge, _ := Parse("1e1000")
+	thousand, _ := Parse("1000")
+	if _, err := Pow(huge, thousand); err != ErrTooLarge {
+		t.Errorf("1e1000^1000 error = %v", err)
+	}
+}
+
+func TestSqrt(t *testing.T) {
+	tests := []struct {
+		x      string
+		digits int
+		mode   RoundingMode
+		want   string
+		exact  bool
+	}{
+		{"16", 0, HalfEven, "4", true},
+		{"2.25", 0, HalfEven, "1.5", true},
+		{"2", 20, HalfEven, "1.4142135623730950488", false},
+		{"2", 20, Up, "1.4142135623730950489", false},
+		{"2", 0, HalfEven, "1.414213562373095048801688724209698", false},
+		{"1e-40", 0, HalfEven, "0.00000000000000000001", true},
+		{"0", 0, HalfEven, "0", true},
+	}
+	for _, tt := range tests {
+		x, _ := Parse(tt.x)
+		got, exact, err := Sqrt(x, tt.digits, tt.mode)
+		if err != nil {
+			t.Errorf("Sqrt(%s): %v", tt.x, err)
+			continue
+		}
+		if got != tt.want || exact != tt.exact {
+			t.Errorf("Sqrt(%s, %d) = %s, %v, want %s, %v", tt.x, tt.digits, got, exact, tt.want, tt.exact)
+		}
+	}
+	neg, _ := Parse("-1")
+	if _, _, err := Sqrt(neg, 0, HalfEven); err != ErrNegativeSqrt {
+		t.Errorf("Sqrt(-1) error = %v", err)
+	}
+}
+
+func TestFibonacci(t *testing.T) {
+	a, b := big.NewInt(0), big.NewInt(1)
+	for n := uint64(0); n < 300; n++ {
+		fn, fn1 := Fibonacci(n)
+		if fn.Cmp(a) != 0 || fn1.Cmp(b) != 0 {
+			t.Fatalf("Fibonacci(%d) = %v, %v, want %v, %v", n, fn, fn1, a, b)
+		}
+		a, b = b, new(big.Int).Add(a, b)
+	}
+	f, _ := Fibonacci(1000)
+	const want = "43466557686937456435688527675040625802564660517371780402481729089536555417949051890403879840079255169295922593080322634775209689623239873322471161642996440906533187938298969649928516003704476137795166849228875"
+	if f.String() != want {
+		t.Errorf("Fibonacci(1000) = %v", f)
+	}
+}
@@ -0,0 +1,22 @@
+package bigmath
+
+import "math/big"
+
+// Fibonacci returns the Fibonacci numbers F(n) and F(n+1), with F(0) = 0
+// and F(1) = 1, by fast doubling in O(log n) multiplications.
+func Fibonacci(n uint64) (*big.Int, *big.Int) {
+	a, b := big.NewInt(0), big.NewInt(1) // F(k), F(k+1) for k = 0
+	t := new(big.Int)
+	for bit := 63; bit >= 0; bit-- {
+		// F(2k) = F(k) * (2F(k+1) - F(k))
+		// F(2k+1) = F(k)^2 + F(k+1)^2
+		t.Lsh(b, 1).Sub(t, a).Mul(t, a)
+		a.Mul(a, a)
+		b.Mul(b, b).Add(b, a)
+		a, t = t, a
+		if n>>uint(bit)&1 == 1 {
+			a, b = b, a.Add(a, b)
+		}
+	}
+	return a, b
+}
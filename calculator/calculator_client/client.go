@@ -2,22 +2,34 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"time"
 
 	"example.com/calculator/calculatorpb"
+	"example.com/internal/dial"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+var (
+	addr          = flag.String("addr", "localhost:50051", "server address, or a file:///path or dnssrv:///name target listing several replicas")
+	serviceConfig = flag.String("service-config", "", "JSON file with the retry, hedging and load balancing policies of the connection (default built-in policies)")
+)
+
 func main() {
+	flag.Parse()
 
 	fmt.Println("welcome to greet client")
 
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	config, err := dial.Load(*serviceConfig)
+	if err != nil {
+		log.Fatalf("failed to load service config: %v", err)
+	}
+	conn, err := dial.Dial(*addr, config, grpc.WithInsecure())
 
 	if err != nil {
 		log.Fatalf("could not connect : %v", err)
@@ -53,7 +65,7 @@ func SquareRoot(c calculatorpb.CalculatorServiceClient, number int32) {
 		}
 	}
 
-	fmt.Println("Result of square root of %v :%v/n", number, res.GetNumberRoot())
+	fmt.Printf("Result of square root of %v :%v\n", number, res.GetNumberRoot())
 }
 
 func sum(c calculatorpb.CalculatorServiceClient) {
@@ -1,141 +1,90 @@
 package main
 
 import (
-	"context"
+	"flag"
 	"fmt"
-	"io"
 	"log"
-	"math"
 	"net"
+	"net/http"
+	"strings"
+	"time"
 
 	"example.com/calculator/calculatorpb"
+	"example.com/calculator/calculatorservice"
+	"example.com/internal/admission"
+	"example.com/internal/auth"
+	"example.com/internal/deadline"
+	"example.com/internal/httpserve"
+	"example.com/internal/ratelimit"
+	"example.com/internal/recovery"
+	"example.com/internal/serverconfig"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
-	"google.golang.org/grpc/status"
 )
 
-type server struct {
-}
-
-func (*server) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
-	fmt.Println("Sum function is invoked: \n", req)
-	firstName := req.GetFirstNumber()
-	lastNumber := req.GetLastNumber()
-	result := firstName + lastNumber
-	res := &calculatorpb.SumResponse{
-		Result: result,
-	}
-	return res, nil
-}
-
-func (*server) PrimDecom(req *calculatorpb.PrimeDecompositionRequest, stream calculatorpb.CalculatorService_PrimDecomServer) error {
+var (
+	addr            = flag.String("addr", "0.0.0.0:50051", "address the server listens on")
+	corsOrigins     = flag.String("cors-origins", "", "comma separated origins allowed to call the server from a browser, \"*\" for any; none when empty")
+	sessionTTL      = flag.Duration("session-ttl", 30*time.Minute, "how long an unused calculator session is kept")
+	factorizeBudget = flag.Duration("factorize-budget", 10*time.Second, "how long Factorize works on a call without a deadline before returning the factors found")
+	configFile      = flag.String("config", "", "JSON server config file, reloaded on SIGHUP; see internal/serverconfig")
+	maxConcurrency  = flag.Int("max-concurrency", 1000, "upper bound of the adaptive limit on concurrent calls; calls over the limit fail with Unavailable")
 
-	number := req.GetNumber()
-	var k int32 = 2
-	for number > 1 {
-		if number%k == 0 {
-			res := &calculatorpb.PrimeDecompositionResponse{
-				Result: k,
-			}
-			stream.Send(res)
-			number = number / k
-		} else {
-			k = k + 1
-		}
-	}
-	return nil
-}
+	budgets deadline.Budgets
+)
 
-func (*server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAverageServer) error {
-	fmt.Println("ComputeAverage function is invoked with a streaming request")
-	var computeAverage []float32
-	for {
-		req, err := stream.Recv()
-		number := float32(0)
-		if err == io.EOF {
-			//porcessing finish
-			for _, num := range computeAverage {
-				number += num
-			}
-			result := float32(number / float32(len(computeAverage)))
-			return stream.SendAndClose(
-				&calculatorpb.ComputeAverageResponse{
-					Result: result,
-				},
-			)
-		}
-		if err != nil {
-			log.Fatalf("Error while reading client stream %v", err)
-		}
-		reqnumber := req.GetNumber()
-		computeAverage = append(computeAverage, reqnumber)
+func main() {
+	flag.Var(&budgets, "min-budget", "comma separated /package.Service/Method=duration minimum deadlines; shorter calls are rejected")
+	flag.Parse()
+	fmt.Println("welcome to calculator server")
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("failed to listen %v", err)
 	}
-}
-
-func (*server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServer) error {
-	fmt.Println("greet function is invoked with a streaming request")
-	var numberArray []int32
-	maximum := int32(0)
-	currentMaxi := int32(0)
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			return nil
-		}
-		if err != nil {
-			log.Fatalf("Error while reading client stream %v", err)
-			return err
-		}
-		reqnumber := req.GetNumber()
-		numberArray = append(numberArray, reqnumber)
-		for index, number := range numberArray {
-			if index == 0 || number > maximum {
-				maximum = number
-			}
-		}
-		if currentMaxi != maximum {
-			fmt.Println("new maximun found :", currentMaxi)
-			currentMaxi = maximum
-			err = stream.Send(&calculatorpb.FindMaximumResponse{
-				Result: maximum,
-			})
-			if err != nil {
-				log.Fatalf("Error while sending data to  client: %v", err)
-				return err
-			}
-		} else {
-			fmt.Println("No new maximun found :", currentMaxi)
-		}
-
+	config, err := serverconfig.Load(*configFile)
+	if err != nil {
+		log.Fatalf("failed to load server config: %v", err)
 	}
+	callers := auth.New(config.Callers)
+	limiter := ratelimit.New(&config.RateLimits, ratelimit.Options{Authenticator: callers.Identity})
+	serverconfig.ReloadOnHangup(*configFile, func(c *serverconfig.Config) {
+		callers.SetCallers(c.Callers)
+		limiter.SetConfig(&c.RateLimits)
+	})
+	shedder := admission.New(admission.Options{MaxLimit: *maxConcurrency, Admin: callers.Admin})
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recovery.UnaryServerInterceptor(),
+			deadline.UnaryServerInterceptor(budgets),
+			limiter.UnaryServerInterceptor(),
+			shedder.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(),
+			deadline.StreamServerInterceptor(budgets),
+			limiter.StreamServerInterceptor(),
+			shedder.StreamServerInterceptor(),
+		),
+	)
+	calculatorpb.RegisterCalculatorServiceServer(s, &calculatorservice.Server{SessionTTL: *sessionTTL, FactorizeBudget: *factorizeBudget})
+	reflection.Register(s)
 
-}
-
-func (*server) SquareRoot(ctx context.Context, req *calculatorpb.SquareRootRequest) (*calculatorpb.SquareRootResponse, error) {
-	fmt.Println("Received SquareRoot rpc")
-	number := req.GetNumber()
-	if number < 0 {
-		return nil, status.Error(
-			codes.InvalidArgument, fmt.Sprintf("received a negative number %v", number),
-		)
+	// client load balancers stop sending calls to servers that are not
+	// serving, see healthCheckConfig in internal/dial
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	for name := range s.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
 	}
-	return &calculatorpb.SquareRootResponse{
-		NumberRoot: math.Sqrt(float64(number)),
-	}, nil
-}
 
-func main() {
-	fmt.Println("welcome to greet server")
-	lis, err := net.Listen("tcp", "0.0.0.0:50051")
+	// gRPC, gRPC-Web and Connect share the listener
+	h, err := httpserve.Handler(s, strings.Split(*corsOrigins, ",")...)
 	if err != nil {
-		log.Fatalf("failed to listen %v", err)
+		log.Fatalf("failed to create http handler : %v", err)
 	}
-	s := grpc.NewServer()
-	calculatorpb.RegisterCalculatorServiceServer(s, &server{})
-	reflection.Register(s)
-
-	if err := s.Serve(lis); err != nil {
+	if err := http.Serve(lis, h); err != nil {
 		log.Fatalf("failed to serve : %v", err)
 	}
 }
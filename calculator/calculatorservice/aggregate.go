@@ -0,0 +1,115 @@
+package calculatorservice
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/stats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Limits on RunningAggregate windows. Duration windows are also capped at
+// maxWindowCount numbers so that a fast client cannot grow them unbounded.
+const (
+	maxWindowCount    = 1 << 20
+	maxWindowDuration = 24 * time.Hour
+)
+
+var aggregates = map[calculatorpb.Aggregate]stats.Aggregate{
+	calculatorpb.Aggregate_AGGREGATE_MAX:  stats.Max,
+	calculatorpb.Aggregate_AGGREGATE_MIN:  stats.Min,
+	calculatorpb.Aggregate_AGGREGATE_SUM:  stats.Sum,
+	calculatorpb.Aggregate_AGGREGATE_MEAN: stats.Mean,
+}
+
+func (*Server) RunningAggregate(stream calculatorpb.CalculatorService_RunningAggregateServer) error {
+	fmt.Println("Received RunningAggregate rpc")
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	agg, ok := aggregates[first.GetAggregate()]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "unknown aggregate %v", first.GetAggregate())
+	}
+	window, err := aggregateWindow(first)
+	if err != nil {
+		return err
+	}
+
+	next := first
+	return runAggregate(
+		agg, window, first.GetEmit() == calculatorpb.EmitMode_EMIT_EVERY_MESSAGE,
+		func() (float64, error) {
+			if next == nil {
+				req, err := stream.Recv()
+				if err != nil {
+					return 0, err
+				}
+				next = req
+			}
+			x := next.GetNumber()
+			next = nil
+			return x, nil
+		},
+		func(result float64) error {
+			return stream.Send(&calculatorpb.RunningAggregateResponse{Result: result})
+		},
+	)
+}
+
+func aggregateWindow(req *calculatorpb.RunningAggregateRequest) (stats.Window, error) {
+	switch w := req.GetWindow().(type) {
+	case *calculatorpb.RunningAggregateRequest_LastCount:
+		if w.LastCount == 0 || w.LastCount > maxWindowCount {
+			return stats.Window{}, status.Errorf(codes.InvalidArgument, "window count must be between 1 and %d", maxWindowCount)
+		}
+		return stats.Window{Count: int(w.LastCount)}, nil
+	case *calculatorpb.RunningAggregateRequest_LastDuration:
+		if err := w.LastDuration.CheckValid(); err != nil {
+			return stats.Window{}, status.Errorf(codes.InvalidArgument, "invalid window duration: %v", err)
+		}
+		d := w.LastDuration.AsDuration()
+		if d <= 0 || d > maxWindowDuration {
+			return stats.Window{}, status.Errorf(codes.InvalidArgument, "window duration must be positive and at most %v", maxWindowDuration)
+		}
+		return stats.Window{Count: maxWindowCount, Duration: d}, nil
+	}
+	return stats.Window{}, nil
+}
+
+// runAggregate feeds the numbers returned by recv into a running aggregate
+// and sends the results. Unless every is set, a result is only sent when
+// it differs from the previous one. It returns nil once recv reports
+// io.EOF.
+func runAggregate(agg stats.Aggregate, window stats.Window, every bool, recv func() (float64, error), send func(float64) error) error {
+	r := stats.NewRunning(agg, window)
+	var last float64
+	for i := 0; ; i++ {
+		x, err := recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return status.Errorf(codes.InvalidArgument, "number %d is %v", i+1, x)
+		}
+		result := r.Add(x, time.Now())
+		if i > 0 && !every && result == last {
+			continue
+		}
+		last = result
+		if err := send(result); err != nil {
+			return err
+		}
+	}
+}
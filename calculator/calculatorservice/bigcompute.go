@@ -0,0 +1,79 @@
+package calculatorservice
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"example.com/calculator/bigmath"
+	"example.com/calculator/calculatorpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var roundingModes = map[calculatorpb.RoundingMode]bigmath.RoundingMode{
+	calculatorpb.RoundingMode_ROUND_HALF_EVEN: bigmath.HalfEven,
+	calculatorpb.RoundingMode_ROUND_HALF_UP:   bigmath.HalfUp,
+	calculatorpb.RoundingMode_ROUND_HALF_DOWN: bigmath.HalfDown,
+	calculatorpb.RoundingMode_ROUND_UP:        bigmath.Up,
+	calculatorpb.RoundingMode_ROUND_DOWN:      bigmath.Down,
+	calculatorpb.RoundingMode_ROUND_CEILING:   bigmath.Ceiling,
+	calculatorpb.RoundingMode_ROUND_FLOOR:     bigmath.Floor,
+}
+
+func (*Server) BigCompute(ctx context.Context, req *calculatorpb.BigComputeRequest) (*calculatorpb.BigComputeResponse, error) {
+	fmt.Println("Received BigCompute rpc")
+	mode, ok := roundingModes[req.GetRounding()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown rounding mode %v", req.GetRounding())
+	}
+	if req.GetPrecision() > bigmath.MaxPrecision {
+		return nil, status.Errorf(codes.InvalidArgument, "precision larger than %d digits", bigmath.MaxPrecision)
+	}
+	digits := int(req.GetPrecision())
+	x, err := bigmath.Parse(req.GetX())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "x: %v", err)
+	}
+
+	op := req.GetOperation()
+	if op == calculatorpb.BigOperation_BIG_SQRT {
+		result, exact, err := bigmath.Sqrt(x, digits, mode)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return &calculatorpb.BigComputeResponse{Result: result, Exact: exact}, nil
+	}
+
+	y, err := bigmath.Parse(req.GetY())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "y: %v", err)
+	}
+	var r *big.Rat
+	switch op {
+	case calculatorpb.BigOperation_BIG_ADD:
+		r = bigmath.Add(x, y)
+	case calculatorpb.BigOperation_BIG_SUBTRACT:
+		r = bigmath.Sub(x, y)
+	case calculatorpb.BigOperation_BIG_MULTIPLY:
+		r = bigmath.Mul(x, y)
+	case calculatorpb.BigOperation_BIG_DIVIDE:
+		r, err = bigmath.Quo(x, y)
+	case calculatorpb.BigOperation_BIG_POWER:
+		r, err = bigmath.Pow(x, y)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown operation %v", op)
+	}
+	if err != nil {
+		code := codes.InvalidArgument
+		if err == bigmath.ErrTooLarge {
+			code = codes.OutOfRange
+		}
+		return nil, status.Error(code, err.Error())
+	}
+	result, exact, err := bigmath.Format(r, digits, mode)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &calculatorpb.BigComputeResponse{Result: result, Exact: exact}, nil
+}
@@ -0,0 +1,45 @@
+package calculatorservice
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/expr"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxExpressionLength bounds the size of the expressions we parse.
+const maxExpressionLength = 4096
+
+func (*Server) Evaluate(ctx context.Context, req *calculatorpb.EvaluateRequest) (*calculatorpb.EvaluateResponse, error) {
+	fmt.Println("Received Evaluate rpc")
+	src := req.GetExpression()
+	if len(src) > maxExpressionLength {
+		return nil, status.Errorf(codes.InvalidArgument, "expression longer than %d characters", maxExpressionLength)
+	}
+	result, err := expr.Evaluate(src, nil)
+	if err != nil {
+		return nil, expressionError("expression", err)
+	}
+	return &calculatorpb.EvaluateResponse{
+		Result: result,
+	}, nil
+}
+
+// expressionError converts an error from the expr package into an
+// InvalidArgument status whose BadRequest detail points at field.
+func expressionError(field string, err error) error {
+	st := status.New(codes.InvalidArgument, err.Error())
+	detailed, detailErr := st.WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: err.Error()},
+		},
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
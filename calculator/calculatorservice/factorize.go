@@ -0,0 +1,75 @@
+package calculatorservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/primes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxFactorizeDigits bounds the size of the numbers given to Factorize.
+const maxFactorizeDigits = 1000
+
+// defaultFactorizeBudget is the default of Server.FactorizeBudget.
+const defaultFactorizeBudget = 10 * time.Second
+
+func (s *Server) Factorize(req *calculatorpb.FactorizeRequest, stream calculatorpb.CalculatorService_FactorizeServer) error {
+	fmt.Println("Received Factorize rpc")
+	n := new(big.Int)
+	switch number := req.GetNumber().(type) {
+	case *calculatorpb.FactorizeRequest_IntNumber:
+		n.SetInt64(number.IntNumber)
+	case *calculatorpb.FactorizeRequest_BigNumber:
+		if len(number.BigNumber) > maxFactorizeDigits {
+			return status.Errorf(codes.InvalidArgument, "number longer than %d digits", maxFactorizeDigits)
+		}
+		if _, ok := n.SetString(number.BigNumber, 10); !ok {
+			return status.Errorf(codes.InvalidArgument, "invalid integer %q", number.BigNumber)
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "missing number")
+	}
+	if n.Sign() <= 0 {
+		return status.Errorf(codes.InvalidArgument, "received a non-positive number %v", n)
+	}
+	// a product of two large primes would keep rho busy for ages: calls
+	// without a deadline of their own get a budget, and the factors found
+	// within it
+	ctx := stream.Context()
+	budget := time.Duration(0)
+	if _, ok := ctx.Deadline(); !ok {
+		budget = s.FactorizeBudget
+		if budget <= 0 {
+			budget = defaultFactorizeBudget
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	err := primes.Factorize(ctx, n, func(p *big.Int, k int) error {
+		return stream.Send(&calculatorpb.FactorizeResponse{
+			Factor:       p.String(),
+			Multiplicity: uint32(k),
+		})
+	})
+	var incomplete *primes.IncompleteError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &incomplete):
+		if budget > 0 && stream.Context().Err() == nil {
+			return status.Errorf(codes.ResourceExhausted, "%v left unfactored after %v, set a longer deadline to keep trying", incomplete.Rest, budget)
+		}
+		return status.Errorf(status.FromContextError(incomplete.Err).Code(), "%v left unfactored: %v", incomplete.Rest, incomplete.Err)
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.FromContextError(err).Err()
+}
@@ -0,0 +1,171 @@
+package calculatorservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/linalg"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxMatrixDim bounds each matrix dimension, which keeps products and
+// decompositions to about 10^8 floating point operations.
+const maxMatrixDim = 512
+
+// maxVectorLen bounds the vectors given to DotProduct.
+const maxVectorLen = maxMatrixDim * maxMatrixDim
+
+func (*Server) MatrixMultiply(ctx context.Context, req *calculatorpb.MatrixMultiplyRequest) (*calculatorpb.MatrixResponse, error) {
+	fmt.Println("Received MatrixMultiply rpc")
+	a, err := toMatrix("a", req.GetA())
+	if err != nil {
+		return nil, err
+	}
+	b, err := toMatrix("b", req.GetB())
+	if err != nil {
+		return nil, err
+	}
+	return matrixResponse(linalg.Mul(a, b))
+}
+
+func (*Server) MatrixTranspose(ctx context.Context, req *calculatorpb.MatrixRequest) (*calculatorpb.MatrixResponse, error) {
+	fmt.Println("Received MatrixTranspose rpc")
+	m, err := toMatrix("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, err
+	}
+	return matrixResponse(linalg.Transpose(m), nil)
+}
+
+func (*Server) MatrixDeterminant(ctx context.Context, req *calculatorpb.MatrixRequest) (*calculatorpb.DeterminantResponse, error) {
+	fmt.Println("Received MatrixDeterminant rpc")
+	m, err := toMatrix("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, err
+	}
+	d, err := linalg.Det(m)
+	if err != nil {
+		return nil, linalgError(err)
+	}
+	if math.IsInf(d, 0) {
+		return nil, status.Error(codes.OutOfRange, "determinant overflows a double")
+	}
+	return &calculatorpb.DeterminantResponse{Determinant: d}, nil
+}
+
+func (*Server) MatrixInverse(ctx context.Context, req *calculatorpb.MatrixRequest) (*calculatorpb.MatrixResponse, error) {
+	fmt.Println("Received MatrixInverse rpc")
+	m, err := toMatrix("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, err
+	}
+	return matrixResponse(linalg.Inverse(m))
+}
+
+func (*Server) SolveLinearSystem(ctx context.Context, req *calculatorpb.SolveLinearSystemRequest) (*calculatorpb.MatrixResponse, error) {
+	fmt.Println("Received SolveLinearSystem rpc")
+	a, err := toMatrix("a", req.GetA())
+	if err != nil {
+		return nil, err
+	}
+	b, err := toMatrix("b", req.GetB())
+	if err != nil {
+		return nil, err
+	}
+	return matrixResponse(linalg.Solve(a, b))
+}
+
+func (*Server) DotProduct(ctx context.Context, req *calculatorpb.VectorPairRequest) (*calculatorpb.DotProductResponse, error) {
+	fmt.Println("Received DotProduct rpc")
+	if err := checkVectors(req); err != nil {
+		return nil, err
+	}
+	d, err := linalg.Dot(req.GetA(), req.GetB())
+	if err != nil {
+		return nil, linalgError(err)
+	}
+	return &calculatorpb.DotProductResponse{Result: d}, nil
+}
+
+func (*Server) CrossProduct(ctx context.Context, req *calculatorpb.VectorPairRequest) (*calculatorpb.CrossProductResponse, error) {
+	fmt.Println("Received CrossProduct rpc")
+	if err := checkVectors(req); err != nil {
+		return nil, err
+	}
+	c, err := linalg.Cross(req.GetA(), req.GetB())
+	if err != nil {
+		return nil, linalgError(err)
+	}
+	return &calculatorpb.CrossProductResponse{Result: c}, nil
+}
+
+func toMatrix(name string, m *calculatorpb.Matrix) (*linalg.Matrix, error) {
+	if m == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "missing matrix %s", name)
+	}
+	rows, cols := m.GetRows(), m.GetCols()
+	if rows > maxMatrixDim || cols > maxMatrixDim {
+		return nil, status.Errorf(codes.InvalidArgument, "matrix %s is %d x %d, at most %d x %d is supported", name, rows, cols, maxMatrixDim, maxMatrixDim)
+	}
+	if err := checkFinite(name, m.GetValues()); err != nil {
+		return nil, err
+	}
+	values := m.GetValues()
+	if values == nil {
+		// linalg.New would allocate a zero matrix instead.
+		values = []float64{}
+	}
+	res, err := linalg.New(int(rows), int(cols), values)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "matrix %s: %v", name, err)
+	}
+	return res, nil
+}
+
+func checkVectors(req *calculatorpb.VectorPairRequest) error {
+	if len(req.GetA()) > maxVectorLen || len(req.GetB()) > maxVectorLen {
+		return status.Errorf(codes.InvalidArgument, "vectors longer than %d elements", maxVectorLen)
+	}
+	if err := checkFinite("a", req.GetA()); err != nil {
+		return err
+	}
+	return checkFinite("b", req.GetB())
+}
+
+func checkFinite(name string, values []float64) error {
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return status.Errorf(codes.InvalidArgument, "%s[%d] is %v", name, i, v)
+		}
+	}
+	return nil
+}
+
+func matrixResponse(m *linalg.Matrix, err error) (*calculatorpb.MatrixResponse, error) {
+	if err != nil {
+		return nil, linalgError(err)
+	}
+	for _, v := range m.Data {
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, status.Error(codes.OutOfRange, "result overflows a double")
+		}
+	}
+	return &calculatorpb.MatrixResponse{
+		Result: &calculatorpb.Matrix{
+			Rows:   uint32(m.Rows),
+			Cols:   uint32(m.Cols),
+			Values: m.Data,
+		},
+	}, nil
+}
+
+func linalgError(err error) error {
+	if errors.Is(err, linalg.ErrDimension) || errors.Is(err, linalg.ErrSingular) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
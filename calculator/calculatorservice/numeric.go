@@ -0,0 +1,125 @@
+package calculatorservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/expr"
+	"example.com/calculator/numeric"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults and limits for the iterations of the numeric RPCs. Integrate
+// counts function evaluations, which are much cheaper than iterations.
+const (
+	defaultTolerance   = 1e-10
+	defaultIterations  = 100
+	maxIterations      = 100000
+	defaultEvaluations = 100000
+	maxEvaluations     = 10000000
+)
+
+func (*Server) Integrate(ctx context.Context, req *calculatorpb.NumericRequest) (*calculatorpb.NumericResponse, error) {
+	fmt.Println("Received Integrate rpc")
+	f, tol, n, err := numericArgs(req, defaultEvaluations, maxEvaluations)
+	if err != nil {
+		return nil, err
+	}
+	return numericResponse(numeric.Integrate(ctx, f, req.GetLower(), req.GetUpper(), tol, n))
+}
+
+func (*Server) FindRoot(ctx context.Context, req *calculatorpb.NumericRequest) (*calculatorpb.NumericResponse, error) {
+	fmt.Println("Received FindRoot rpc")
+	f, tol, n, err := numericArgs(req, defaultIterations, maxIterations)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetLower() >= req.GetUpper() {
+		return nil, status.Error(codes.InvalidArgument, "lower must be less than upper")
+	}
+	return numericResponse(numeric.FindRoot(ctx, f, req.GetLower(), req.GetUpper(), tol, n))
+}
+
+func (*Server) Minimize(ctx context.Context, req *calculatorpb.NumericRequest) (*calculatorpb.NumericResponse, error) {
+	fmt.Println("Received Minimize rpc")
+	f, tol, n, err := numericArgs(req, defaultIterations, maxIterations)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetLower() >= req.GetUpper() {
+		return nil, status.Error(codes.InvalidArgument, "lower must be less than upper")
+	}
+	return numericResponse(numeric.Minimize(ctx, f, req.GetLower(), req.GetUpper(), tol, n))
+}
+
+// numericArgs validates req and returns the function it describes, the
+// tolerance and the iteration limit.
+func numericArgs(req *calculatorpb.NumericRequest, defaultN, maxN int) (numeric.Func, float64, int, error) {
+	src := req.GetExpression()
+	if len(src) > maxExpressionLength {
+		return nil, 0, 0, status.Errorf(codes.InvalidArgument, "expression longer than %d characters", maxExpressionLength)
+	}
+	n, err := expr.Parse(src)
+	if err != nil {
+		return nil, 0, 0, expressionError("expression", err)
+	}
+	for _, b := range []float64{req.GetLower(), req.GetUpper()} {
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return nil, 0, 0, status.Errorf(codes.InvalidArgument, "bound %v is not finite", b)
+		}
+	}
+	tol := req.GetTolerance()
+	switch {
+	case tol == 0:
+		tol = defaultTolerance
+	case !(tol > 0) || math.IsInf(tol, 0):
+		return nil, 0, 0, status.Errorf(codes.InvalidArgument, "tolerance %v is not positive", tol)
+	}
+	iters := int(req.GetMaxIterations())
+	switch {
+	case iters == 0:
+		iters = defaultN
+	case iters > maxN:
+		return nil, 0, 0, status.Errorf(codes.InvalidArgument, "max_iterations is at most %d", maxN)
+	}
+
+	name := req.GetVariable()
+	if name == "" {
+		name = "x"
+	}
+	env := &expr.Env{Vars: map[string]float64{name: 0}}
+	f := func(x float64) (float64, error) {
+		env.Vars[name] = x
+		return expr.Eval(n, env)
+	}
+	return f, tol, iters, nil
+}
+
+func numericResponse(res numeric.Result, err error) (*calculatorpb.NumericResponse, error) {
+	var exprErr *expr.Error
+	switch {
+	case err == nil:
+	case errors.As(err, &exprErr):
+		return nil, expressionError("expression", err)
+	case errors.Is(err, numeric.ErrNotBracketed):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return nil, status.FromContextError(err).Err()
+	default:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if math.IsInf(res.Value, 0) || math.IsNaN(res.Value) {
+		return nil, status.Error(codes.OutOfRange, "result is not a finite number")
+	}
+	return &calculatorpb.NumericResponse{
+		X:             res.X,
+		Value:         res.Value,
+		ErrorEstimate: res.ErrorEstimate,
+		Iterations:    uint32(res.Iterations),
+		Converged:     res.Converged,
+	}, nil
+}
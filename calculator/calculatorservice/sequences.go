@@ -0,0 +1,120 @@
+package calculatorservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"example.com/calculator/bigmath"
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/primes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The sequence RPCs send each element as soon as it is computed. Send
+// blocks while the client's flow control window is full, so a slow reader
+// slows down the generator instead of making the server buffer, and a
+// canceled call makes Send fail.
+
+// maxFibonacciIndex bounds the Fibonacci numbers served, whose size
+// grows linearly with the index: F(1000000) has 208988 digits.
+const maxFibonacciIndex = 1000000
+
+// errCountReached stops a generator once the requested count was sent.
+var errCountReached = errors.New("count reached")
+
+func (*Server) Primes(req *calculatorpb.PrimesRequest, stream calculatorpb.CalculatorService_PrimesServer) error {
+	fmt.Println("Received Primes rpc")
+	end := req.GetEnd()
+	if end == 0 {
+		end = math.MaxUint64
+	}
+	if end < req.GetStart() {
+		return status.Error(codes.InvalidArgument, "end is less than start")
+	}
+	var sent uint64
+	err := primes.Range(stream.Context(), req.GetStart(), end, func(p uint64) error {
+		if err := stream.Send(&calculatorpb.PrimesResponse{Prime: p}); err != nil {
+			return err
+		}
+		if sent++; sent == req.GetCount() {
+			return errCountReached
+		}
+		return nil
+	})
+	return sequenceError(err)
+}
+
+func (*Server) Fibonacci(req *calculatorpb.FibonacciRequest, stream calculatorpb.CalculatorService_FibonacciServer) error {
+	fmt.Println("Received Fibonacci rpc")
+	offset := req.GetOffset()
+	if offset > maxFibonacciIndex {
+		return status.Errorf(codes.InvalidArgument, "offset is at most %d", maxFibonacciIndex)
+	}
+	last := uint64(maxFibonacciIndex)
+	if n := req.GetCount(); n > 0 && n-1 < last-offset {
+		last = offset + n - 1
+	}
+	a, b := bigmath.Fibonacci(offset)
+	for i := offset; i <= last; i++ {
+		if err := stream.Context().Err(); err != nil {
+			return sequenceError(err)
+		}
+		res := &calculatorpb.FibonacciResponse{
+			Index: i,
+			Value: a.String(),
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+		a, b = b, a.Add(a, b)
+	}
+	return nil
+}
+
+func (*Server) Progression(req *calculatorpb.ProgressionRequest, stream calculatorpb.CalculatorService_ProgressionServer) error {
+	fmt.Println("Received Progression rpc")
+	first, step := req.GetFirst(), req.GetStep()
+	if math.IsNaN(first) || math.IsInf(first, 0) || math.IsNaN(step) || math.IsInf(step, 0) {
+		return status.Error(codes.InvalidArgument, "first and step must be finite")
+	}
+	term := func(i uint64) float64 { return first + float64(i)*step }
+	switch req.GetKind() {
+	case calculatorpb.ProgressionKind_ARITHMETIC:
+	case calculatorpb.ProgressionKind_GEOMETRIC:
+		term = func(i uint64) float64 { return first * math.Pow(step, float64(i)) }
+	default:
+		return status.Errorf(codes.InvalidArgument, "unknown progression kind %v", req.GetKind())
+	}
+
+	// Terms are computed from their index rather than from the previous
+	// term, so that rounding errors do not accumulate.
+	for i, n := req.GetOffset(), uint64(0); req.GetCount() == 0 || n < req.GetCount(); i, n = i+1, n+1 {
+		if err := stream.Context().Err(); err != nil {
+			return sequenceError(err)
+		}
+		v := term(i)
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return status.Errorf(codes.OutOfRange, "term %d overflows a double", i)
+		}
+		if err := stream.Send(&calculatorpb.ProgressionResponse{Index: i, Value: v}); err != nil {
+			return err
+		}
+		if i == math.MaxUint64 {
+			break
+		}
+	}
+	return nil
+}
+
+func sequenceError(err error) error {
+	switch {
+	case err == nil, err == errCountReached:
+		return nil
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	return err
+}
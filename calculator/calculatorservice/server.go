@@ -0,0 +1,138 @@
+// Package calculatorservice implements the CalculatorService defined in
+// calculatorpb.
+package calculatorservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/primes"
+	"example.com/calculator/session"
+	"example.com/calculator/stats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements calculatorpb.CalculatorServiceServer.
+type Server struct {
+	// SessionTTL is how long an unused session is kept. Zero means 30
+	// minutes.
+	SessionTTL time.Duration
+	// MaxSessions bounds the number of live sessions. Zero means 10000.
+	MaxSessions int
+	// FactorizeBudget is how long Factorize works on a call without a
+	// deadline. Zero means 10 seconds.
+	FactorizeBudget time.Duration
+
+	sessionsOnce sync.Once
+	sessions     *session.Manager
+}
+
+func (*Server) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
+	fmt.Println("Sum function is invoked: \n", req)
+	firstName := req.GetFirstNumber()
+	lastNumber := req.GetLastNumber()
+	result := int64(firstName) + int64(lastNumber)
+	if result > math.MaxInt32 || result < math.MinInt32 {
+		return nil, status.Errorf(
+			codes.OutOfRange, "%v + %v does not fit in an int32, use BigCompute", firstName, lastNumber,
+		)
+	}
+	res := &calculatorpb.SumResponse{
+		Result: int32(result),
+	}
+	return res, nil
+}
+
+func (*Server) PrimDecom(req *calculatorpb.PrimeDecompositionRequest, stream calculatorpb.CalculatorService_PrimDecomServer) error {
+	number := req.GetNumber()
+	if number <= 0 {
+		return status.Error(
+			codes.InvalidArgument, fmt.Sprintf("received a non-positive number %v", number),
+		)
+	}
+	var factors []factor
+	err := primes.Factorize(stream.Context(), big.NewInt(int64(number)), func(p *big.Int, k int) error {
+		factors = append(factors, factor{p: int32(p.Int64()), k: k})
+		return nil
+	})
+	if err != nil {
+		return status.FromContextError(err).Err()
+	}
+	sort.Slice(factors, func(i, j int) bool { return factors[i].p < factors[j].p })
+	for _, f := range factors {
+		for i := 0; i < f.k; i++ {
+			res := &calculatorpb.PrimeDecompositionResponse{
+				Result: f.p,
+			}
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type factor struct {
+	p int32
+	k int
+}
+
+func (*Server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAverageServer) error {
+	fmt.Println("ComputeAverage function is invoked with a streaming request")
+	var summary stats.Summary
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			//porcessing finish
+			if summary.Count() == 0 {
+				return status.Error(codes.InvalidArgument, "no numbers received")
+			}
+			return stream.SendAndClose(
+				&calculatorpb.ComputeAverageResponse{
+					Result: float32(summary.Mean()),
+				},
+			)
+		}
+		if err != nil {
+			return err
+		}
+		summary.Add(float64(req.GetNumber()))
+	}
+}
+
+func (*Server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServer) error {
+	fmt.Println("greet function is invoked with a streaming request")
+	return runAggregate(
+		stats.Max, stats.Window{}, false,
+		func() (float64, error) {
+			req, err := stream.Recv()
+			return float64(req.GetNumber()), err
+		},
+		func(maximum float64) error {
+			return stream.Send(&calculatorpb.FindMaximumResponse{
+				Result: int32(maximum),
+			})
+		},
+	)
+}
+
+func (*Server) SquareRoot(ctx context.Context, req *calculatorpb.SquareRootRequest) (*calculatorpb.SquareRootResponse, error) {
+	fmt.Println("Received SquareRoot rpc")
+	number := req.GetNumber()
+	if number < 0 {
+		return nil, status.Error(
+			codes.InvalidArgument, fmt.Sprintf("received a negative number %v", number),
+		)
+	}
+	return &calculatorpb.SquareRootResponse{
+		NumberRoot: math.Sqrt(float64(number)),
+	}, nil
+}
@@ -0,0 +1,1145 @@
+package calculatorservice_test
+
+import (
+	"context"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/calculatorservice"
+	"example.com/internal/harness"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestSum(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name        string
+		first, last int32
+		want        int32
+	}{
+		{"positive", 10, 3, 13},
+		{"negative", -10, 3, -7},
+		{"zero", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.Sum(context.Background(), &calculatorpb.SumRequest{FirstNumber: tt.first, LastNumber: tt.last})
+			if err != nil {
+				t.Fatalf("Sum: %v", err)
+			}
+			if res.GetResult() != tt.want {
+				t.Errorf("Sum(%v, %v) = %v, want %v", tt.first, tt.last, res.GetResult(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimDecom(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name   string
+		number int32
+		want   []int32
+	}{
+		{"composite", 120, []int32{2, 2, 2, 3, 5}},
+		{"prime", 13, []int32{13}},
+		{"one", 1, nil},
+		{"large prime", 2147483647, []int32{2147483647}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.PrimDecom(context.Background(), &calculatorpb.PrimeDecompositionRequest{Number: tt.number})
+			if err != nil {
+				t.Fatalf("PrimDecom: %v", err)
+			}
+			var got []int32
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got = append(got, res.GetResult())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PrimDecom(%v) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimDecomNonPositive(t *testing.T) {
+	c := harness.Calculator(t)
+	for _, n := range []int32{0, -5} {
+		stream, err := c.PrimDecom(context.Background(), &calculatorpb.PrimeDecompositionRequest{Number: n})
+		if err != nil {
+			t.Fatalf("PrimDecom: %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("PrimDecom(%v) error = %v, want InvalidArgument", n, err)
+		}
+	}
+}
+
+func TestFactorize(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name string
+		req  *calculatorpb.FactorizeRequest
+		want map[string]uint32
+	}{
+		{
+			"int64",
+			&calculatorpb.FactorizeRequest{Number: &calculatorpb.FactorizeRequest_IntNumber{IntNumber: 360}},
+			map[string]uint32{"2": 3, "3": 2, "5": 1},
+		},
+		{
+			"int64 semiprime",
+			&calculatorpb.FactorizeRequest{Number: &calculatorpb.FactorizeRequest_IntNumber{IntNumber: 999999000001 * 7}},
+			map[string]uint32{"7": 1, "999999000001": 1},
+		},
+		{
+			"big",
+			&calculatorpb.FactorizeRequest{Number: &calculatorpb.FactorizeRequest_BigNumber{BigNumber: "83076749774033942464542339036610963"}},
+			map[string]uint32{"68719476767": 1, "1208925819614629174706189": 1},
+		},
+		{
+			"one",
+			&calculatorpb.FactorizeRequest{Number: &calculatorpb.FactorizeRequest_BigNumber{BigNumber: "1"}},
+			map[string]uint32{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.Factorize(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Factorize: %v", err)
+			}
+			got := map[string]uint32{}
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got[res.GetFactor()] = res.GetMultiplicity()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Factorize = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFactorizeBudget(t *testing.T) {
+	conn := harness.Start(t, func(s *grpc.Server) {
+		calculatorpb.RegisterCalculatorServiceServer(s, &calculatorservice.Server{FactorizeBudget: 50 * time.Millisecond})
+	})
+	c := calculatorpb.NewCalculatorServiceClient(conn)
+	// 24 times a product of two ~100-bit primes, far too slow for Pollard's rho
+	const rest = "1606938044258990275541962093111894167460966469892788384261671"
+	stream, err := c.Factorize(context.Background(), &calculatorpb.FactorizeRequest{
+		Number: &calculatorpb.FactorizeRequest_BigNumber{BigNumber: "38566513062215766613007090234685460019063195277426921222280104"},
+	})
+	if err != nil {
+		t.Fatalf("Factorize: %v", err)
+	}
+	got := map[string]uint32{}
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			if status.Code(err) != codes.ResourceExhausted || !strings.Contains(status.Convert(err).Message(), rest) {
+				t.Errorf("Recv error = %v, want ResourceExhausted naming the unfactored %s", err, rest)
+			}
+			break
+		}
+		got[res.GetFactor()] = res.GetMultiplicity()
+	}
+	if want := map[string]uint32{"2": 3, "3": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("factors found within the budget = %v, want %v", got, want)
+	}
+}
+
+func TestFactorizeInvalid(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name string
+		req  *calculatorpb.FactorizeRequest
+	}{
+		{"missing", &calculatorpb.FactorizeRequest{}},
+		{"zero", &calculatorpb.FactorizeRequest{Number: &calculatorpb.FactorizeRequest_IntNumber{IntNumber: 0}}},
+		{"negative", &calculatorpb.FactorizeRequest{Number: &calculatorpb.FactorizeRequest_BigNumber{BigNumber: "-12"}}},
+		{"not a number", &calculatorpb.FactorizeRequest{Number: &calculatorpb.FactorizeRequest_BigNumber{BigNumber: "12a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.Factorize(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Factorize: %v", err)
+			}
+			if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Recv error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestComputeAverage(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name    string
+		numbers []float32
+		want    float32
+	}{
+		{"integers", []float32{1, 2, 3, 4}, 2.5},
+		{"single", []float32{7}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.ComputeAverage(context.Background())
+			if err != nil {
+				t.Fatalf("ComputeAverage: %v", err)
+			}
+			for _, n := range tt.numbers {
+				if err := stream.Send(&calculatorpb.ComputeAverageRequest{Number: n}); err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+			}
+			res, err := stream.CloseAndRecv()
+			if err != nil {
+				t.Fatalf("CloseAndRecv: %v", err)
+			}
+			if res.GetResult() != tt.want {
+				t.Errorf("ComputeAverage(%v) = %v, want %v", tt.numbers, res.GetResult(), tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeAverageEmpty(t *testing.T) {
+	c := harness.Calculator(t)
+	stream, err := c.ComputeAverage(context.Background())
+	if err != nil {
+		t.Fatalf("ComputeAverage: %v", err)
+	}
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CloseAndRecv error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestComputeStatistics(t *testing.T) {
+	c := harness.Calculator(t)
+	stream, err := c.ComputeStatistics(context.Background())
+	if err != nil {
+		t.Fatalf("ComputeStatistics: %v", err)
+	}
+	const n = 10001
+	for i := 0; i < n; i++ {
+		req := &calculatorpb.ComputeStatisticsRequest{Number: float64(i)}
+		if i == 0 {
+			req.Percentiles = []float64{25, 90}
+		}
+		if err := stream.Send(req); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv: %v", err)
+	}
+	if res.GetCount() != n || res.GetMean() != 5000 || res.GetMin() != 0 || res.GetMax() != n-1 {
+		t.Errorf("got count %v mean %v min %v max %v", res.GetCount(), res.GetMean(), res.GetMin(), res.GetMax())
+	}
+	// The variance of 0..n-1 is n(n+1)/12.
+	if want := float64(n) * (n + 1) / 12; math.Abs(res.GetVariance()-want) > 1e-6*want {
+		t.Errorf("variance = %v, want %v", res.GetVariance(), want)
+	}
+	if math.Abs(res.GetStandardDeviation()-math.Sqrt(res.GetVariance())) > 1e-9 {
+		t.Errorf("standard deviation = %v, variance %v", res.GetStandardDeviation(), res.GetVariance())
+	}
+	if math.Abs(res.GetMedian()-5000) > 100 {
+		t.Errorf("median = %v, want about 5000", res.GetMedian())
+	}
+	want := map[float64]float64{25: 2500, 90: 9000}
+	if len(res.GetPercentiles()) != len(want) {
+		t.Fatalf("percentiles = %v, want %v", res.GetPercentiles(), want)
+	}
+	for _, p := range res.GetPercentiles() {
+		if math.Abs(p.GetValue()-want[p.GetPercentile()]) > 100 {
+			t.Errorf("percentile %v = %v, want about %v", p.GetPercentile(), p.GetValue(), want[p.GetPercentile()])
+		}
+	}
+}
+
+func TestComputeStatisticsInvalid(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name string
+		reqs []*calculatorpb.ComputeStatisticsRequest
+	}{
+		{"empty", nil},
+		{"nan", []*calculatorpb.ComputeStatisticsRequest{{Number: 1}, {Number: math.NaN()}}},
+		{"infinite", []*calculatorpb.ComputeStatisticsRequest{{Number: math.Inf(-1)}}},
+		{"percentile 100", []*calculatorpb.ComputeStatisticsRequest{{Number: 1, Percentiles: []float64{100}}}},
+		{"negative percentile", []*calculatorpb.ComputeStatisticsRequest{{Number: 1, Percentiles: []float64{-5}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.ComputeStatistics(context.Background())
+			if err != nil {
+				t.Fatalf("ComputeStatistics: %v", err)
+			}
+			for _, req := range tt.reqs {
+				if err := stream.Send(req); err != nil {
+					break
+				}
+			}
+			if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("CloseAndRecv error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestFindMaximum(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name    string
+		numbers []int32
+		want    []int32
+	}{
+		{"increasing maxima", []int32{1, 5, 3, 6, 2, 20}, []int32{1, 5, 6, 20}},
+		{"repeated", []int32{4, 4, 4}, []int32{4}},
+		{"all negative", []int32{-7, -9, -3, -4}, []int32{-7, -3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.FindMaximum(context.Background())
+			if err != nil {
+				t.Fatalf("FindMaximum: %v", err)
+			}
+			for _, n := range tt.numbers {
+				if err := stream.Send(&calculatorpb.FindMaximumRequest{Number: n}); err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+			}
+			stream.CloseSend()
+			var got []int32
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got = append(got, res.GetResult())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindMaximum(%v) = %v, want %v", tt.numbers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunningAggregate(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name    string
+		first   *calculatorpb.RunningAggregateRequest
+		numbers []float64
+		want    []float64
+	}{
+		{
+			"max on change",
+			&calculatorpb.RunningAggregateRequest{},
+			[]float64{-2, -5, -1, -1},
+			[]float64{-2, -1},
+		},
+		{
+			"min last 2 every message",
+			&calculatorpb.RunningAggregateRequest{
+				Aggregate: calculatorpb.Aggregate_AGGREGATE_MIN,
+				Window:    &calculatorpb.RunningAggregateRequest_LastCount{LastCount: 2},
+				Emit:      calculatorpb.EmitMode_EMIT_EVERY_MESSAGE,
+			},
+			[]float64{1, 5, 4, 4, 7},
+			[]float64{1, 1, 4, 4, 4},
+		},
+		{
+			"sum last 3",
+			&calculatorpb.RunningAggregateRequest{
+				Aggregate: calculatorpb.Aggregate_AGGREGATE_SUM,
+				Window:    &calculatorpb.RunningAggregateRequest_LastCount{LastCount: 3},
+			},
+			[]float64{1, 2, 3, 1, 2, 3},
+			[]float64{1, 3, 6},
+		},
+		{
+			"mean over an hour",
+			&calculatorpb.RunningAggregateRequest{
+				Aggregate: calculatorpb.Aggregate_AGGREGATE_MEAN,
+				Window:    &calculatorpb.RunningAggregateRequest_LastDuration{LastDuration: durationpb.New(time.Hour)},
+			},
+			[]float64{2, 4, 6},
+			[]float64{2, 3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.RunningAggregate(context.Background())
+			if err != nil {
+				t.Fatalf("RunningAggregate: %v", err)
+			}
+			for i, n := range tt.numbers {
+				req := &calculatorpb.RunningAggregateRequest{Number: n}
+				if i == 0 {
+					req = tt.first
+					req.Number = n
+				}
+				if err := stream.Send(req); err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+			}
+			stream.CloseSend()
+			var got []float64
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got = append(got, res.GetResult())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RunningAggregate(%v) = %v, want %v", tt.numbers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunningAggregateInvalid(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name string
+		req  *calculatorpb.RunningAggregateRequest
+	}{
+		{"unknown aggregate", &calculatorpb.RunningAggregateRequest{Aggregate: 42}},
+		{"zero count", &calculatorpb.RunningAggregateRequest{Window: &calculatorpb.RunningAggregateRequest_LastCount{}}},
+		{"negative duration", &calculatorpb.RunningAggregateRequest{Window: &calculatorpb.RunningAggregateRequest_LastDuration{LastDuration: durationpb.New(-time.Second)}}},
+		{"nan", &calculatorpb.RunningAggregateRequest{Number: math.NaN()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.RunningAggregate(context.Background())
+			if err != nil {
+				t.Fatalf("RunningAggregate: %v", err)
+			}
+			if err := stream.Send(tt.req); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Recv error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestSquareRoot(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name   string
+		number int32
+		want   float64
+		code   codes.Code
+	}{
+		{"perfect square", 16, 4, codes.OK},
+		{"zero", 0, 0, codes.OK},
+		{"negative", -10, 0, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.SquareRoot(context.Background(), &calculatorpb.SquareRootRequest{Number: tt.number})
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("SquareRoot(%v) code = %v, want %v", tt.number, code, tt.code)
+			}
+			if res.GetNumberRoot() != tt.want {
+				t.Errorf("SquareRoot(%v) = %v, want %v", tt.number, res.GetNumberRoot(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name       string
+		expression string
+		want       float64
+		code       codes.Code
+		message    string
+	}{
+		{name: "precedence", expression: "(3 + 4.5) * sqrt(16) / 2 ^ 3", want: 3.75},
+		{name: "functions", expression: "max(abs(-2), min(5, 7)) + log(exp(1))", want: 6},
+		{name: "syntax error", expression: "2 * (3 + ", code: codes.InvalidArgument, message: "position 10: unexpected end of expression"},
+		{name: "division by zero", expression: "1 / 0", code: codes.InvalidArgument, message: "position 3: division by zero"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.Evaluate(context.Background(), &calculatorpb.EvaluateRequest{Expression: tt.expression})
+			st := status.Convert(err)
+			if st.Code() != tt.code {
+				t.Fatalf("Evaluate(%q) code = %v, want %v (%v)", tt.expression, st.Code(), tt.code, err)
+			}
+			if tt.code != codes.OK {
+				if st.Message() != tt.message {
+					t.Errorf("Evaluate(%q) message = %q, want %q", tt.expression, st.Message(), tt.message)
+				}
+				if len(st.Details()) != 1 {
+					t.Errorf("Evaluate(%q) details = %v, want one BadRequest", tt.expression, st.Details())
+				}
+				return
+			}
+			if res.GetResult() != tt.want {
+				t.Errorf("Evaluate(%q) = %v, want %v", tt.expression, res.GetResult(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSumOverflow(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name        string
+		first, last int32
+	}{
+		{"positive overflow", math.MaxInt32, 1},
+		{"negative overflow", math.MinInt32, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.Sum(context.Background(), &calculatorpb.SumRequest{FirstNumber: tt.first, LastNumber: tt.last})
+			if code := status.Code(err); code != codes.OutOfRange {
+				t.Errorf("Sum(%v, %v) code = %v, want OutOfRange", tt.first, tt.last, code)
+			}
+		})
+	}
+}
+
+func TestBigCompute(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name  string
+		req   *calculatorpb.BigComputeRequest
+		want  string
+		exact bool
+		code  codes.Code
+	}{
+		{
+			name:  "add beyond int32",
+			req:   &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_ADD, X: "2147483647", Y: "1"},
+			want:  "2147483648",
+			exact: true,
+		},
+		{
+			name:  "exact decimal subtraction",
+			req:   &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_SUBTRACT, X: "0.3", Y: "0.1"},
+			want:  "0.2",
+			exact: true,
+		},
+		{
+			name:  "multiply",
+			req:   &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_MULTIPLY, X: "123456789012345678901234567890", Y: "-1e-10"},
+			want:  "-12345678901234567890.123456789",
+			exact: true,
+		},
+		{
+			name: "divide with precision and rounding",
+			req: &calculatorpb.BigComputeRequest{
+				Operation: calculatorpb.BigOperation_BIG_DIVIDE, X: "2", Y: "3",
+				Precision: 4, Rounding: calculatorpb.RoundingMode_ROUND_DOWN,
+			},
+			want: "0.6666",
+		},
+		{
+			name:  "power",
+			req:   &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_POWER, X: "10", Y: "40"},
+			want:  "10000000000000000000000000000000000000000",
+			exact: true,
+		},
+		{
+			name: "sqrt",
+			req:  &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_SQRT, X: "2", Precision: 10},
+			want: "1.414213562",
+		},
+		{
+			name: "division by zero",
+			req:  &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_DIVIDE, X: "1", Y: "0"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "negative sqrt",
+			req:  &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_SQRT, X: "-4"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "malformed number",
+			req:  &calculatorpb.BigComputeRequest{Operation: calculatorpb.BigOperation_BIG_ADD, X: "12abc", Y: "1"},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "missing operation",
+			req:  &calculatorpb.BigComputeRequest{X: "1", Y: "1"},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.BigCompute(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("BigCompute code = %v, want %v (%v)", code, tt.code, err)
+			}
+			if res.GetResult() != tt.want || res.GetExact() != tt.exact {
+				t.Errorf("BigCompute = %q (exact %v), want %q (exact %v)", res.GetResult(), res.GetExact(), tt.want, tt.exact)
+			}
+		})
+	}
+}
+
+func matrix(rows, cols uint32, values ...float64) *calculatorpb.Matrix {
+	return &calculatorpb.Matrix{Rows: rows, Cols: cols, Values: values}
+}
+
+func TestMatrixOperations(t *testing.T) {
+	c := harness.Calculator(t)
+	ctx := context.Background()
+	a := matrix(2, 2, 4, 7, 2, 6)
+
+	res, err := c.MatrixMultiply(ctx, &calculatorpb.MatrixMultiplyRequest{A: matrix(2, 3, 1, 2, 3, 4, 5, 6), B: matrix(3, 1, 1, 0, -1)})
+	if err != nil {
+		t.Fatalf("MatrixMultiply: %v", err)
+	}
+	if got, want := res.GetResult(), matrix(2, 1, -2, -2); !proto.Equal(got, want) {
+		t.Errorf("MatrixMultiply = %v, want %v", got, want)
+	}
+
+	res, err = c.MatrixTranspose(ctx, &calculatorpb.MatrixRequest{Matrix: matrix(1, 3, 1, 2, 3)})
+	if err != nil {
+		t.Fatalf("MatrixTranspose: %v", err)
+	}
+	if got, want := res.GetResult(), matrix(3, 1, 1, 2, 3); !proto.Equal(got, want) {
+		t.Errorf("MatrixTranspose = %v, want %v", got, want)
+	}
+
+	det, err := c.MatrixDeterminant(ctx, &calculatorpb.MatrixRequest{Matrix: a})
+	if err != nil {
+		t.Fatalf("MatrixDeterminant: %v", err)
+	}
+	if math.Abs(det.GetDeterminant()-10) > 1e-12 {
+		t.Errorf("MatrixDeterminant = %v, want 10", det.GetDeterminant())
+	}
+
+	res, err = c.MatrixInverse(ctx, &calculatorpb.MatrixRequest{Matrix: a})
+	if err != nil {
+		t.Fatalf("MatrixInverse: %v", err)
+	}
+	want := []float64{0.6, -0.7, -0.2, 0.4}
+	for i, v := range res.GetResult().GetValues() {
+		if math.Abs(v-want[i]) > 1e-12 {
+			t.Errorf("MatrixInverse = %v, want %v", res.GetResult().GetValues(), want)
+			break
+		}
+	}
+
+	res, err = c.SolveLinearSystem(ctx, &calculatorpb.SolveLinearSystemRequest{A: a, B: matrix(2, 1, 18, 14)})
+	if err != nil {
+		t.Fatalf("SolveLinearSystem: %v", err)
+	}
+	if got := res.GetResult().GetValues(); len(got) != 2 || math.Abs(got[0]-1) > 1e-12 || math.Abs(got[1]-2) > 1e-12 {
+		t.Errorf("SolveLinearSystem = %v, want [1 2]", got)
+	}
+
+	dot, err := c.DotProduct(ctx, &calculatorpb.VectorPairRequest{A: []float64{1, 2, 3}, B: []float64{4, 5, 6}})
+	if err != nil {
+		t.Fatalf("DotProduct: %v", err)
+	}
+	if dot.GetResult() != 32 {
+		t.Errorf("DotProduct = %v, want 32", dot.GetResult())
+	}
+
+	cross, err := c.CrossProduct(ctx, &calculatorpb.VectorPairRequest{A: []float64{0, 1, 0}, B: []float64{0, 0, 1}})
+	if err != nil {
+		t.Fatalf("CrossProduct: %v", err)
+	}
+	if got := cross.GetResult(); !reflect.DeepEqual(got, []float64{1, 0, 0}) {
+		t.Errorf("CrossProduct = %v, want [1 0 0]", got)
+	}
+}
+
+func TestMatrixOperationsInvalid(t *testing.T) {
+	c := harness.Calculator(t)
+	ctx := context.Background()
+	singular := matrix(2, 2, 1, 2, 2, 4)
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"multiply mismatch", func() error {
+			_, err := c.MatrixMultiply(ctx, &calculatorpb.MatrixMultiplyRequest{A: matrix(1, 2, 1, 2), B: matrix(1, 2, 1, 2)})
+			return err
+		}},
+		{"missing matrix", func() error {
+			_, err := c.MatrixTranspose(ctx, &calculatorpb.MatrixRequest{})
+			return err
+		}},
+		{"wrong value count", func() error {
+			_, err := c.MatrixTranspose(ctx, &calculatorpb.MatrixRequest{Matrix: matrix(2, 2, 1, 2, 3)})
+			return err
+		}},
+		{"too large", func() error {
+			_, err := c.MatrixTranspose(ctx, &calculatorpb.MatrixRequest{Matrix: matrix(1, 1000)})
+			return err
+		}},
+		{"nan", func() error {
+			_, err := c.MatrixDeterminant(ctx, &calculatorpb.MatrixRequest{Matrix: matrix(1, 1, math.NaN())})
+			return err
+		}},
+		{"determinant not square", func() error {
+			_, err := c.MatrixDeterminant(ctx, &calculatorpb.MatrixRequest{Matrix: matrix(1, 2, 1, 2)})
+			return err
+		}},
+		{"inverse singular", func() error {
+			_, err := c.MatrixInverse(ctx, &calculatorpb.MatrixRequest{Matrix: singular})
+			return err
+		}},
+		{"solve singular", func() error {
+			_, err := c.SolveLinearSystem(ctx, &calculatorpb.SolveLinearSystemRequest{A: singular, B: matrix(2, 1, 1, 1)})
+			return err
+		}},
+		{"dot mismatch", func() error {
+			_, err := c.DotProduct(ctx, &calculatorpb.VectorPairRequest{A: []float64{1}, B: []float64{1, 2}})
+			return err
+		}},
+		{"cross not 3d", func() error {
+			_, err := c.CrossProduct(ctx, &calculatorpb.VectorPairRequest{A: []float64{1, 2}, B: []float64{1, 2}})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	c := harness.Calculator(t)
+	ctx := context.Background()
+	created, err := c.CreateSession(ctx, &calculatorpb.CreateSessionRequest{})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if created.GetIdleTimeout().AsDuration() <= 0 {
+		t.Errorf("idle timeout = %v, want positive", created.GetIdleTimeout())
+	}
+	id := created.GetSessionId()
+
+	stream, err := c.SessionEval(ctx)
+	if err != nil {
+		t.Fatalf("SessionEval: %v", err)
+	}
+	steps := []struct {
+		input string
+		want  *calculatorpb.SessionEvalResponse
+	}{
+		{"x = 3", &calculatorpb.SessionEvalResponse{Result: 3, Name: "x"}},
+		{"f(a, b) = a * b + x", &calculatorpb.SessionEvalResponse{Name: "f"}},
+		{"f(x, 2)", &calculatorpb.SessionEvalResponse{Result: 9}},
+		{"y", &calculatorpb.SessionEvalResponse{Error: `position 1: unknown identifier "y"`}},
+		{"x * 2", &calculatorpb.SessionEvalResponse{Result: 6}},
+	}
+	for i, step := range steps {
+		req := &calculatorpb.SessionEvalRequest{Input: step.input}
+		if i == 0 {
+			req.SessionId = id
+		}
+		if err := stream.Send(req); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if !proto.Equal(res, step.want) {
+			t.Errorf("SessionEval(%q) = %v, want %v", step.input, res, step.want)
+		}
+	}
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Recv after CloseSend = %v, want EOF", err)
+	}
+
+	if _, err := c.DeleteSession(ctx, &calculatorpb.DeleteSessionRequest{SessionId: id}); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := c.DeleteSession(ctx, &calculatorpb.DeleteSessionRequest{SessionId: id}); status.Code(err) != codes.NotFound {
+		t.Errorf("second DeleteSession error = %v, want NotFound", err)
+	}
+	stream, err = c.SessionEval(ctx)
+	if err != nil {
+		t.Fatalf("SessionEval: %v", err)
+	}
+	if err := stream.Send(&calculatorpb.SessionEvalRequest{SessionId: id, Input: "x"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Errorf("SessionEval on a deleted session error = %v, want NotFound", err)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	c := harness.Start(t, func(s *grpc.Server) {
+		calculatorpb.RegisterCalculatorServiceServer(s, &calculatorservice.Server{SessionTTL: 50 * time.Millisecond, MaxSessions: 1})
+	})
+	client := calculatorpb.NewCalculatorServiceClient(c)
+	ctx := context.Background()
+	created, err := client.CreateSession(ctx, &calculatorpb.CreateSessionRequest{})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if _, err := client.CreateSession(ctx, &calculatorpb.CreateSessionRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("CreateSession beyond MaxSessions error = %v, want ResourceExhausted", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, err := client.DeleteSession(ctx, &calculatorpb.DeleteSessionRequest{SessionId: created.GetSessionId()}); status.Code(err) != codes.NotFound {
+		t.Errorf("DeleteSession of an expired session error = %v, want NotFound", err)
+	}
+	if _, err := client.CreateSession(ctx, &calculatorpb.CreateSessionRequest{}); err != nil {
+		t.Errorf("CreateSession after expiry: %v", err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		req  *calculatorpb.ConvertRequest
+		want float64
+	}{
+		{&calculatorpb.ConvertRequest{Value: 1, FromUnit: "GiB/s", ToUnit: "TB/day"}, 92.771293593600},
+		{&calculatorpb.ConvertRequest{Value: 5, FromUnit: "km", ToUnit: "m"}, 5000},
+		{&calculatorpb.ConvertRequest{Value: 100, FromUnit: "degC", ToUnit: "degF"}, 212},
+	}
+	for _, tt := range tests {
+		res, err := c.Convert(context.Background(), tt.req)
+		if err != nil {
+			t.Errorf("Convert(%v): %v", tt.req, err)
+			continue
+		}
+		if math.Abs(res.GetValue()-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v) = %v, want %v", tt.req, res.GetValue(), tt.want)
+		}
+	}
+
+	for _, req := range []*calculatorpb.ConvertRequest{
+		{Value: 1, FromUnit: "km", ToUnit: "kg"},
+		{Value: 1, FromUnit: "smoot", ToUnit: "m"},
+		{Value: 1, FromUnit: "m", ToUnit: ""},
+		{Value: 1, FromUnit: "m", ToUnit: strings.Repeat("(", 1000) + "m" + strings.Repeat(")", 1000)},
+	} {
+		if _, err := c.Convert(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Convert(%v) error = %v, want InvalidArgument", req, err)
+		}
+	}
+}
+
+func TestConvertOversizedUnit(t *testing.T) {
+	// too large for a gRPC message, but not for a handler called in-process
+	nested := strings.Repeat("(", 3000000) + "m" + strings.Repeat(")", 3000000)
+	s := &calculatorservice.Server{}
+	if _, err := s.Convert(context.Background(), &calculatorpb.ConvertRequest{Value: 1, FromUnit: nested, ToUnit: "m"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Convert from an oversized unit error = %v, want InvalidArgument", err)
+	}
+	if _, err := s.EvaluateWithUnits(context.Background(), &calculatorpb.EvaluateWithUnitsRequest{Expression: "1 m", Unit: nested}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("EvaluateWithUnits in an oversized unit error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestEvaluateWithUnits(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		req      *calculatorpb.EvaluateWithUnitsRequest
+		want     float64
+		wantUnit string
+	}{
+		{&calculatorpb.EvaluateWithUnitsRequest{Expression: "2 GiB/s * 1 h", Unit: "TiB"}, 7200.0 / 1024, "TiB"},
+		{&calculatorpb.EvaluateWithUnitsRequest{Expression: "100 km / 2 h"}, 100000.0 / 7200, "m/s"},
+		{&calculatorpb.EvaluateWithUnitsRequest{Expression: "3 ft + 1 in", Unit: "in"}, 37, "in"},
+	}
+	for _, tt := range tests {
+		res, err := c.EvaluateWithUnits(context.Background(), tt.req)
+		if err != nil {
+			t.Errorf("EvaluateWithUnits(%v): %v", tt.req, err)
+			continue
+		}
+		if math.Abs(res.GetValue()-tt.want) > 1e-9 || res.GetUnit() != tt.wantUnit {
+			t.Errorf("EvaluateWithUnits(%v) = %v %s, want %v %s", tt.req, res.GetValue(), res.GetUnit(), tt.want, tt.wantUnit)
+		}
+	}
+
+	for _, req := range []*calculatorpb.EvaluateWithUnitsRequest{
+		{Expression: "1 m + 1 s"},
+		{Expression: "1 GB", Unit: "s"},
+		{Expression: "1 +"},
+		{Expression: "1 m", Unit: strings.Repeat("(", 1000) + "m" + strings.Repeat(")", 1000)},
+		{Expression: strings.Repeat("(", 1000) + "1 m" + strings.Repeat(")", 1000)},
+	} {
+		if _, err := c.EvaluateWithUnits(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("EvaluateWithUnits(%v) error = %v, want InvalidArgument", req, err)
+		}
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	c := harness.Calculator(t)
+	ctx := context.Background()
+
+	res, err := c.Integrate(ctx, &calculatorpb.NumericRequest{Expression: "exp(-t^2)", Variable: "t", Lower: -8, Upper: 8})
+	if err != nil {
+		t.Fatalf("Integrate: %v", err)
+	}
+	if !res.GetConverged() || math.Abs(res.GetValue()-math.Sqrt(math.Pi)) > 1e-8 {
+		t.Errorf("Integrate = %v, want sqrt(pi)", res)
+	}
+
+	res, err = c.FindRoot(ctx, &calculatorpb.NumericRequest{Expression: "x^2 - 2", Lower: 0, Upper: 2, Tolerance: 1e-12})
+	if err != nil {
+		t.Fatalf("FindRoot: %v", err)
+	}
+	if !res.GetConverged() || math.Abs(res.GetX()-math.Sqrt2) > 1e-10 {
+		t.Errorf("FindRoot = %v, want sqrt(2)", res)
+	}
+
+	res, err = c.Minimize(ctx, &calculatorpb.NumericRequest{Expression: "(x - 1)^2 + 3", Lower: -4, Upper: 4})
+	if err != nil {
+		t.Fatalf("Minimize: %v", err)
+	}
+	if !res.GetConverged() || math.Abs(res.GetX()-1) > 1e-5 || math.Abs(res.GetValue()-3) > 1e-9 {
+		t.Errorf("Minimize = %v, want 3 at 1", res)
+	}
+}
+
+func TestNumericInvalid(t *testing.T) {
+	c := harness.Calculator(t)
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func(context.Context, *calculatorpb.NumericRequest, ...grpc.CallOption) (*calculatorpb.NumericResponse, error)
+		req  *calculatorpb.NumericRequest
+	}{
+		{"syntax", c.Integrate, &calculatorpb.NumericRequest{Expression: "x +", Upper: 1}},
+		{"unknown variable", c.Integrate, &calculatorpb.NumericRequest{Expression: "y", Upper: 1}},
+		{"domain", c.Integrate, &calculatorpb.NumericRequest{Expression: "sqrt(x)", Lower: -1, Upper: 1}},
+		{"infinite bound", c.Integrate, &calculatorpb.NumericRequest{Expression: "x", Upper: math.Inf(1)}},
+		{"negative tolerance", c.Minimize, &calculatorpb.NumericRequest{Expression: "x", Upper: 1, Tolerance: -1}},
+		{"too many iterations", c.Minimize, &calculatorpb.NumericRequest{Expression: "x", Upper: 1, MaxIterations: 1 << 30}},
+		{"empty interval", c.FindRoot, &calculatorpb.NumericRequest{Expression: "x", Lower: 1, Upper: 1}},
+		{"not bracketed", c.FindRoot, &calculatorpb.NumericRequest{Expression: "x^2 + 1", Lower: -1, Upper: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.call(ctx, tt.req); status.Code(err) != codes.InvalidArgument {
+				t.Errorf("error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestIntegrateDeadline(t *testing.T) {
+	c := harness.Calculator(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// sin(1/x) near zero never meets the tolerance, so only the deadline
+	// stops the computation.
+	_, err := c.Integrate(ctx, &calculatorpb.NumericRequest{
+		Expression:    "sin(1 / x)",
+		Lower:         1e-9,
+		Upper:         1,
+		Tolerance:     1e-300,
+		MaxIterations: 10000000,
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Integrate error = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestPrimes(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name string
+		req  *calculatorpb.PrimesRequest
+		want []uint64
+	}{
+		{"range", &calculatorpb.PrimesRequest{Start: 10, End: 30}, []uint64{11, 13, 17, 19, 23, 29}},
+		{"count", &calculatorpb.PrimesRequest{Count: 4}, []uint64{2, 3, 5, 7}},
+		{"resume", &calculatorpb.PrimesRequest{Start: 8, Count: 2}, []uint64{11, 13}},
+		{"none", &calculatorpb.PrimesRequest{Start: 24, End: 28}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.Primes(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Primes: %v", err)
+			}
+			var got []uint64
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got = append(got, res.GetPrime())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Primes(%v) = %v, want %v", tt.req, got, tt.want)
+			}
+		})
+	}
+
+	stream, err := c.Primes(context.Background(), &calculatorpb.PrimesRequest{Start: 10, End: 5})
+	if err != nil {
+		t.Fatalf("Primes: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Primes with end < start error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestFibonacci(t *testing.T) {
+	c := harness.Calculator(t)
+	stream, err := c.Fibonacci(context.Background(), &calculatorpb.FibonacciRequest{Offset: 10, Count: 3})
+	if err != nil {
+		t.Fatalf("Fibonacci: %v", err)
+	}
+	var got []string
+	for i := uint64(10); ; i++ {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if res.GetIndex() != i {
+			t.Errorf("index = %d, want %d", res.GetIndex(), i)
+		}
+		got = append(got, res.GetValue())
+	}
+	if want := []string{"55", "89", "144"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Fibonacci = %v, want %v", got, want)
+	}
+
+	stream, err = c.Fibonacci(context.Background(), &calculatorpb.FibonacciRequest{Offset: 1 << 40})
+	if err != nil {
+		t.Fatalf("Fibonacci: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Fibonacci with a huge offset error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestProgression(t *testing.T) {
+	c := harness.Calculator(t)
+	tests := []struct {
+		name string
+		req  *calculatorpb.ProgressionRequest
+		want []float64
+	}{
+		{"arithmetic", &calculatorpb.ProgressionRequest{First: 1, Step: 0.5, Count: 4}, []float64{1, 1.5, 2, 2.5}},
+		{"arithmetic offset", &calculatorpb.ProgressionRequest{First: 1, Step: 0.5, Offset: 2, Count: 2}, []float64{2, 2.5}},
+		{"geometric", &calculatorpb.ProgressionRequest{Kind: calculatorpb.ProgressionKind_GEOMETRIC, First: 3, Step: 2, Count: 4}, []float64{3, 6, 12, 24}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.Progression(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Progression: %v", err)
+			}
+			var got []float64
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got = append(got, res.GetValue())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Progression(%v) = %v, want %v", tt.req, got, tt.want)
+			}
+		})
+	}
+
+	stream, err := c.Progression(context.Background(), &calculatorpb.ProgressionRequest{Kind: calculatorpb.ProgressionKind_GEOMETRIC, First: 1, Step: 1e100})
+	if err != nil {
+		t.Fatalf("Progression: %v", err)
+	}
+	var n int
+	for {
+		_, err := stream.Recv()
+		if err != nil {
+			if status.Code(err) != codes.OutOfRange {
+				t.Errorf("Progression error = %v, want OutOfRange", err)
+			}
+			break
+		}
+		n++
+	}
+	if n != 4 {
+		t.Errorf("got %d terms before overflowing, want 4", n)
+	}
+}
+
+func TestSequenceCancel(t *testing.T) {
+	c := harness.Calculator(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	// An arithmetic progression without a count never ends by itself.
+	stream, err := c.Progression(ctx, &calculatorpb.ProgressionRequest{Step: 1})
+	if err != nil {
+		t.Fatalf("Progression: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+	}
+	cancel()
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if status.Code(err) != codes.Canceled {
+				t.Errorf("Recv after cancel = %v, want Canceled", err)
+			}
+			break
+		}
+	}
+}
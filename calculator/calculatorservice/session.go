@@ -0,0 +1,96 @@
+package calculatorservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/session"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func (s *Server) sessionManager() *session.Manager {
+	s.sessionsOnce.Do(func() {
+		ttl, max := s.SessionTTL, s.MaxSessions
+		if ttl <= 0 {
+			ttl = 30 * time.Minute
+		}
+		if max <= 0 {
+			max = 10000
+		}
+		s.sessions = session.NewManager(ttl, max)
+	})
+	return s.sessions
+}
+
+func (s *Server) CreateSession(ctx context.Context, req *calculatorpb.CreateSessionRequest) (*calculatorpb.CreateSessionResponse, error) {
+	fmt.Println("Received CreateSession rpc")
+	m := s.sessionManager()
+	sess, err := m.Create()
+	if err != nil {
+		return nil, sessionError(err)
+	}
+	return &calculatorpb.CreateSessionResponse{
+		SessionId:   sess.ID,
+		IdleTimeout: durationpb.New(m.TTL()),
+	}, nil
+}
+
+func (s *Server) DeleteSession(ctx context.Context, req *calculatorpb.DeleteSessionRequest) (*calculatorpb.DeleteSessionResponse, error) {
+	fmt.Println("Received DeleteSession rpc")
+	if err := s.sessionManager().Delete(req.GetSessionId()); err != nil {
+		return nil, sessionError(err)
+	}
+	return &calculatorpb.DeleteSessionResponse{}, nil
+}
+
+func (s *Server) SessionEval(stream calculatorpb.CalculatorService_SessionEvalServer) error {
+	fmt.Println("Received SessionEval rpc")
+	m := s.sessionManager()
+	var id string
+	for first := true; ; first = false {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first {
+			id = req.GetSessionId()
+		}
+		// Looking the session up for every input keeps it alive while the
+		// stream is used, and notices when it is deleted meanwhile.
+		sess, err := m.Get(id)
+		if err != nil {
+			return sessionError(err)
+		}
+
+		res := &calculatorpb.SessionEvalResponse{}
+		if input := req.GetInput(); len(input) > maxExpressionLength {
+			res.Error = fmt.Sprintf("input longer than %d characters", maxExpressionLength)
+		} else if r, err := sess.Exec(input); err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Result, res.Name = r.Value, r.Name
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+func sessionError(err error) error {
+	switch {
+	case errors.Is(err, session.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, session.ErrTooManySessions):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
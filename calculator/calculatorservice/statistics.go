@@ -0,0 +1,81 @@
+package calculatorservice
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/stats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxPercentiles bounds the number of quantile sketches kept per stream.
+const maxPercentiles = 100
+
+func (*Server) ComputeStatistics(stream calculatorpb.CalculatorService_ComputeStatisticsServer) error {
+	fmt.Println("Received ComputeStatistics rpc")
+	var (
+		summary     stats.Summary
+		median      = stats.NewQuantile(0.5)
+		percentiles []*stats.Quantile
+	)
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if summary.Count() == 0 {
+			if percentiles, err = newPercentiles(req.GetPercentiles()); err != nil {
+				return err
+			}
+		}
+		x := req.GetNumber()
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return status.Errorf(codes.InvalidArgument, "number %d is %v", summary.Count()+1, x)
+		}
+		summary.Add(x)
+		median.Add(x)
+		for _, q := range percentiles {
+			q.Add(x)
+		}
+	}
+	if summary.Count() == 0 {
+		return status.Error(codes.InvalidArgument, "no numbers received")
+	}
+
+	res := &calculatorpb.ComputeStatisticsResponse{
+		Count:             summary.Count(),
+		Mean:              summary.Mean(),
+		Variance:          summary.Variance(),
+		StandardDeviation: summary.StdDev(),
+		Min:               summary.Min(),
+		Max:               summary.Max(),
+		Median:            median.Value(),
+	}
+	for _, q := range percentiles {
+		res.Percentiles = append(res.Percentiles, &calculatorpb.Percentile{
+			Percentile: q.P() * 100,
+			Value:      q.Value(),
+		})
+	}
+	return stream.SendAndClose(res)
+}
+
+func newPercentiles(ps []float64) ([]*stats.Quantile, error) {
+	if len(ps) > maxPercentiles {
+		return nil, status.Errorf(codes.InvalidArgument, "more than %d percentiles requested", maxPercentiles)
+	}
+	qs := make([]*stats.Quantile, len(ps))
+	for i, p := range ps {
+		if !(p > 0 && p < 100) {
+			return nil, status.Errorf(codes.InvalidArgument, "percentile %v is not between 0 and 100", p)
+		}
+		qs[i] = stats.NewQuantile(p / 100)
+	}
+	return qs, nil
+}
@@ -0,0 +1,72 @@
+package calculatorservice
+
+import (
+	"context"
+	"fmt"
+
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/units"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (*Server) Convert(ctx context.Context, req *calculatorpb.ConvertRequest) (*calculatorpb.ConvertResponse, error) {
+	fmt.Println("Received Convert rpc")
+	from, err := parseUnit("from_unit", req.GetFromUnit())
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseUnit("to_unit", req.GetToUnit())
+	if err != nil {
+		return nil, err
+	}
+	v, err := units.In(units.Quantity{Value: from.Factor*req.GetValue() + from.Offset, Dim: from.Dim}, to)
+	if err != nil {
+		return nil, expressionError("to_unit", err)
+	}
+	return &calculatorpb.ConvertResponse{
+		Value: v,
+	}, nil
+}
+
+func (*Server) EvaluateWithUnits(ctx context.Context, req *calculatorpb.EvaluateWithUnitsRequest) (*calculatorpb.EvaluateWithUnitsResponse, error) {
+	fmt.Println("Received EvaluateWithUnits rpc")
+	src := req.GetExpression()
+	if len(src) > maxExpressionLength {
+		return nil, status.Errorf(codes.InvalidArgument, "expression longer than %d characters", maxExpressionLength)
+	}
+	q, err := units.Evaluate(src)
+	if err != nil {
+		return nil, expressionError("expression", err)
+	}
+	if req.GetUnit() == "" {
+		return &calculatorpb.EvaluateWithUnitsResponse{
+			Value: q.Value,
+			Unit:  q.Dim.String(),
+		}, nil
+	}
+	u, err := parseUnit("unit", req.GetUnit())
+	if err != nil {
+		return nil, err
+	}
+	v, err := units.In(q, u)
+	if err != nil {
+		return nil, expressionError("unit", err)
+	}
+	return &calculatorpb.EvaluateWithUnitsResponse{
+		Value: v,
+		Unit:  req.GetUnit(),
+	}, nil
+}
+
+// parseUnit parses the unit in field of a request.
+func parseUnit(field, s string) (units.Unit, error) {
+	if len(s) > maxExpressionLength {
+		return units.Unit{}, status.Errorf(codes.InvalidArgument, "%s longer than %d characters", field, maxExpressionLength)
+	}
+	u, err := units.ParseUnit(s)
+	if err != nil {
+		return units.Unit{}, expressionError(field, err)
+	}
+	return u, nil
+}
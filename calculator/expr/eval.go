@@ -0,0 +1,236 @@
+package expr
+
+import (
+	"math"
+	"strconv"
+)
+
+// Func is a function callable from an expression.
+type Func struct {
+	// MinArgs and MaxArgs bound the number of arguments; a negative MaxArgs
+	// accepts any number of arguments from MinArgs up.
+	MinArgs, MaxArgs int
+	Fn               func(args []float64) (float64, error)
+}
+
+func fn1(f func(float64) float64) Func {
+	return Func{MinArgs: 1, MaxArgs: 1, Fn: func(a []float64) (float64, error) { return f(a[0]), nil }}
+}
+
+func fn2(f func(float64, float64) float64) Func {
+	return Func{MinArgs: 2, MaxArgs: 2, Fn: func(a []float64) (float64, error) { return f(a[0], a[1]), nil }}
+}
+
+// Constants are the identifiers known to every expression.
+var Constants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+	"phi": math.Phi,
+}
+
+// Builtins are the functions known to every expression.
+var Builtins = map[string]Func{
+	"sin":   fn1(math.Sin),
+	"cos":   fn1(math.Cos),
+	"tan":   fn1(math.Tan),
+	"asin":  fn1(math.Asin),
+	"acos":  fn1(math.Acos),
+	"atan":  fn1(math.Atan),
+	"atan2": fn2(math.Atan2),
+	"sinh":  fn1(math.Sinh),
+	"cosh":  fn1(math.Cosh),
+	"tanh":  fn1(math.Tanh),
+	"sqrt":  fn1(math.Sqrt),
+	"cbrt":  fn1(math.Cbrt),
+	"exp":   fn1(math.Exp),
+	"ln":    fn1(math.Log),
+	"log10": fn1(math.Log10),
+	"log2":  fn1(math.Log2),
+	"abs":   fn1(math.Abs),
+	"floor": fn1(math.Floor),
+	"ceil":  fn1(math.Ceil),
+	"round": fn1(math.Round),
+	"pow":   fn2(math.Pow),
+	"hypot": fn2(math.Hypot),
+	// log(x) is the natural logarithm, log(x, b) the logarithm in base b
+	"log": {MinArgs: 1, MaxArgs: 2, Fn: func(a []float64) (float64, error) {
+		if len(a) == 2 {
+			return math.Log(a[0]) / math.Log(a[1]), nil
+		}
+		return math.Log(a[0]), nil
+	}},
+	"min": {MinArgs: 1, MaxArgs: -1, Fn: func(a []float64) (float64, error) {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m, nil
+	}},
+	"max": {MinArgs: 1, MaxArgs: -1, Fn: func(a []float64) (float64, error) {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m, nil
+	}},
+}
+
+// Env holds the variables and functions an expression may refer to on top
+// of Constants and Builtins, which they shadow. A nil *Env is valid.
+type Env struct {
+	Vars  map[string]float64
+	Funcs map[string]Func
+}
+
+func (env *Env) lookupVar(name string) (float64, bool) {
+	if env != nil {
+		if v, ok := env.Vars[name]; ok {
+			return v, true
+		}
+	}
+	v, ok := Constants[name]
+	return v, ok
+}
+
+func (env *Env) lookupFunc(name string) (Func, bool) {
+	if env != nil {
+		if f, ok := env.Funcs[name]; ok {
+			return f, true
+		}
+	}
+	f, ok := Builtins[name]
+	return f, ok
+}
+
+// Evaluate parses and evaluates src with env.
+func Evaluate(src string, env *Env) (float64, error) {
+	n, err := Parse(src)
+	if err != nil {
+		return 0, err
+	}
+	return Eval(n, env)
+}
+
+// Eval evaluates n with env. Results that are not finite real numbers, such
+// as a division by zero or sqrt(-1), are reported as errors at the position
+// of the operation that produced them.
+func Eval(n Node, env *Env) (float64, error) {
+	switch n := n.(type) {
+	case *Number:
+		return n.Value, nil
+	case *Ident:
+		v, ok := env.lookupVar(n.Name)
+		if !ok {
+			if _, isFunc := env.lookupFunc(n.Name); isFunc {
+				return 0, errorf(n.Pos, "function %s used without arguments", n.Name)
+			}
+			return 0, errorf(n.Pos, "unknown identifier %q", n.Name)
+		}
+		return v, nil
+	case *Unary:
+		x, err := Eval(n.X, env)
+		if err != nil {
+			return 0, err
+		}
+		if n.Op == '-' {
+			return -x, nil
+		}
+		return x, nil
+	case *Binary:
+		x, err := Eval(n.X, env)
+		if err != nil {
+			return 0, err
+		}
+		y, err := Eval(n.Y, env)
+		if err != nil {
+			return 0, err
+		}
+		return binary(n, x, y)
+	case *Call:
+		f, ok := env.lookupFunc(n.Func)
+		if !ok {
+			return 0, errorf(n.Pos, "unknown function %q", n.Func)
+		}
+		if len(n.Args) < f.MinArgs || (f.MaxArgs >= 0 && len(n.Args) > f.MaxArgs) {
+			return 0, errorf(n.Pos, "%s expects %s, got %d", n.Func, arity(f), len(n.Args))
+		}
+		args := make([]float64, len(n.Args))
+		for i, arg := range n.Args {
+			v, err := Eval(arg, env)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		v, err := f.Fn(args)
+		if err != nil {
+			if e, ok := err.(*Error); ok {
+				return 0, e
+			}
+			return 0, errorf(n.Pos, "%s: %v", n.Func, err)
+		}
+		if math.IsNaN(v) {
+			return 0, errorf(n.Pos, "%s: argument out of domain", n.Func)
+		}
+		if math.IsInf(v, 0) {
+			return 0, errorf(n.Pos, "%s: result out of range", n.Func)
+		}
+		return v, nil
+	}
+	return 0, errorf(n.Offset(), "unsupported expression")
+}
+
+func binary(n *Binary, x, y float64) (float64, error) {
+	var v float64
+	switch n.Op {
+	case '+':
+		v = x + y
+	case '-':
+		v = x - y
+	case '*':
+		v = x * y
+	case '/':
+		if y == 0 {
+			return 0, errorf(n.Pos, "division by zero")
+		}
+		v = x / y
+	case '%':
+		if y == 0 {
+			return 0, errorf(n.Pos, "division by zero")
+		}
+		v = math.Mod(x, y)
+	case '^':
+		v = math.Pow(x, y)
+	default:
+		return 0, errorf(n.Pos, "unknown operator %q", n.Op)
+	}
+	if math.IsNaN(v) {
+		return 0, errorf(n.Pos, "result of %q is not a real number", n.Op)
+	}
+	if math.IsInf(v, 0) {
+		return 0, errorf(n.Pos, "result of %q is out of range", n.Op)
+	}
+	return v, nil
+}
+
+func arity(f Func) string {
+	switch {
+	case f.MaxArgs < 0:
+		return pluralArgs("at least", f.MinArgs)
+	case f.MinArgs == f.MaxArgs:
+		return pluralArgs("", f.MinArgs)
+	}
+	return pluralArgs("between "+strconv.Itoa(f.MinArgs)+" and", f.MaxArgs)
+}
+
+func pluralArgs(prefix string, n int) string {
+	s := strconv.Itoa(n) + " argument"
+	if n != 1 {
+		s += "s"
+	}
+	if prefix != "" {
+		s = prefix + " " + s
+	}
+	return s
+}
@@ -0,0 +1,240 @@
+package expr
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+	}{
+		{"1 + 2", 3},
+		{"(3 + 4.5) * sqrt(16) / 2 ^ 3", 3.75},
+		{"2 + 3 * 4", 14},
+		{"(2 + 3) * 4", 20},
+		{"10 - 4 - 3", 3},
+		{"2 ^ 3 ^ 2", 512},
+		{"-2 ^ 2", -4},
+		{"2 ^ -1", 0.5},
+		{"--3", 3},
+		{"7 % 4", 3},
+		{"1.5e3 + .5", 1500.5},
+		{"pi", math.Pi},
+		{"sin(pi / 2)", 1},
+		{"cos(0)", 1},
+		{"log(e)", 1},
+		{"log(8, 2)", 3},
+		{"exp(0)", 1},
+		{"abs(-3)", 3},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1, 2)", 3},
+		{"max(-1)", -1},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.src, nil)
+		if err != nil {
+			t.Errorf("Evaluate(%q): %v", tt.src, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{"", 1},
+		{"1 +", 4},
+		{"(1 + 2", 7},
+		{"1 + 2)", 6},
+		{"2 * * 3", 5},
+		{"3 # 4", 3},
+		{"1..2", 1},
+		{"foo + 1", 1},
+		{"1 + bar(2)", 5},
+		{"sqrt(1, 2)", 1},
+		{"min()", 1},
+		{"sin", 1},
+		{"1 / (2 - 2)", 3},
+		{"4 + sqrt(-1)", 5},
+		{"10 ^ 400", 4},
+		{"f(1,)", 5},
+		{"2e", 2},
+	}
+	for _, tt := range tests {
+		_, err := Evaluate(tt.src, nil)
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Evaluate(%q) error = %v, want *Error", tt.src, err)
+			continue
+		}
+		if e.Pos != tt.pos {
+			t.Errorf("Evaluate(%q) error at position %d (%v), want %d", tt.src, e.Pos, e, tt.pos)
+		}
+	}
+}
+
+func TestParseDepth(t *testing.T) {
+	nested := func(n int) string { return strings.Repeat("(", n) + "1" + strings.Repeat(")", n) }
+	if _, err := Parse(nested(maxDepth - 1)); err != nil {
+		t.Errorf("Parse of %d nested parentheses: %v", maxDepth-1, err)
+	}
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{nested(100000), maxDepth + 1},
+		{strings.Repeat("-", 300) + "1", maxDepth + 1},
+		{strings.Repeat("2^", 300) + "1", 2*maxDepth + 1},
+		{strings.Repeat("sqrt(", 300) + "1" + strings.Repeat(")", 300), 5*maxDepth + 1},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src, ImplicitMultiplication())
+		if e, ok := err.(*Error); !ok || e.Pos != tt.pos {
+			t.Errorf("Parse(%.20q...) error = %v, want one at position %d", tt.src, err, tt.pos)
+		}
+	}
+}
+
+func TestImplicitMultiplication(t *testing.T) {
+	tests := []struct {
+		src  string
+		want float64
+		pos  int // of the error, 0 when there is none
+	}{
+		{src: "2e", want: 2 * math.E},
+		{src: "2 pi", want: 2 * math.Pi},
+		{src: "3(1 + 1)", want: 6},
+		{src: "2 sqrt(4) ^ 2", want: 8},
+		{src: "1 / 2 e", want: 1 / (2 * math.E)},
+		{src: "-2 e", want: -2 * math.E},
+		{src: "2 3", pos: 3},
+		{src: "2 x", pos: 3},
+	}
+	for _, tt := range tests {
+		got, err := func() (float64, error) {
+			n, err := Parse(tt.src, ImplicitMultiplication())
+			if err != nil {
+				return 0, err
+			}
+			return Eval(n, nil)
+		}()
+		if tt.pos != 0 {
+			if e, ok := err.(*Error); !ok || e.Pos != tt.pos {
+				t.Errorf("%q: error = %v, want one at position %d", tt.src, err, tt.pos)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.src, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("%q = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEnv(t *testing.T) {
+	env := &Env{
+		Vars: map[string]float64{"x": 3, "pi": 3},
+		Funcs: map[string]Func{
+			"double": {MinArgs: 1, MaxArgs: 1, Fn: func(a []float64) (float64, error) { return 2 * a[0], nil }},
+		},
+	}
+	got, err := Evaluate("double(x) + pi", env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 9 {
+		t.Errorf("got %v, want 9", got)
+	}
+}
+
+func TestParseStatement(t *testing.T) {
+	tests := []struct {
+		src    string
+		kind   StatementKind
+		name   string
+		params []string
+	}{
+		{"1 + 2", ExprStatement, "", nil},
+		{"x", ExprStatement, "", nil},
+		{"f(2)", ExprStatement, "", nil},
+		{"x = 3", AssignStatement, "x", nil},
+		{"total = x * 2", AssignStatement, "total", nil},
+		{"f(x) = x ^ 2", DefineStatement, "f", []string{"x"}},
+		{"g(a, b) = a + b", DefineStatement, "g", []string{"a", "b"}},
+		{"k() = 4", DefineStatement, "k", []string{}},
+	}
+	for _, tt := range tests {
+		st, err := ParseStatement(tt.src)
+		if err != nil {
+			t.Errorf("ParseStatement(%q): %v", tt.src, err)
+			continue
+		}
+		if st.Kind != tt.kind || st.Name != tt.name || !reflect.DeepEqual(st.Params, tt.params) {
+			t.Errorf("ParseStatement(%q) = %v %q %q, want %v %q %q", tt.src, st.Kind, st.Name, st.Params, tt.kind, tt.name, tt.params)
+		}
+	}
+
+	for _, src := range []string{"x = ", "= 3", "1 = 2", "x = y = 3", "f(1) = 2", "f(x,) = x", "f(x = 1"} {
+		if _, err := ParseStatement(src); err == nil {
+			t.Errorf("ParseStatement(%q) succeeded, want error", src)
+		}
+	}
+}
+
+func TestDefine(t *testing.T) {
+	env := &Env{Vars: map[string]float64{"a": 2}, Funcs: map[string]Func{}}
+	define := func(src string) error {
+		st, err := ParseStatement(src)
+		if err != nil {
+			return err
+		}
+		f, err := Define(st.Params, st.Body, env)
+		if err != nil {
+			return err
+		}
+		env.Funcs[st.Name] = f
+		return nil
+	}
+	for _, src := range []string{"f(x) = a * x + 1", "g(x, y) = f(x) * y", "h(x) = 1 / x"} {
+		if err := define(src); err != nil {
+			t.Fatalf("define %q: %v", src, err)
+		}
+	}
+	// Functions capture a when they are defined.
+	env.Vars["a"] = 100
+	if got, err := Evaluate("g(3, 2)", env); err != nil || got != 14 {
+		t.Errorf("g(3, 2) = %v, %v, want 14", got, err)
+	}
+	if _, err := Evaluate("f(1, 2)", env); err == nil {
+		t.Error("f(1, 2) succeeded, want arity error")
+	}
+	_, err := Evaluate("1 + h(0)", env)
+	if e, ok := err.(*Error); !ok || e.Pos != 5 || !strings.Contains(e.Msg, "division by zero") {
+		t.Errorf("1 + h(0) error = %v, want division by zero at position 5", err)
+	}
+
+	// A function defined in terms of itself uses the previous definition.
+	if err := define("f(x) = f(x) + 1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Evaluate("f(1)", env); err != nil || got != 4 {
+		t.Errorf("redefined f(1) = %v, %v, want 4", got, err)
+	}
+	for _, src := range []string{"r(x) = r(x)", "u(x) = x + nope", "d(x, x) = x"} {
+		if err := define(src); err == nil {
+			t.Errorf("define %q succeeded, want error", src)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Package expr parses and evaluates arithmetic expressions such as
+// "(3 + 4.5) * sqrt(16) / 2 ^ 3".
+//
+// The grammar, from lowest to highest precedence:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/" | "%") unary }
+//	unary   = ("+" | "-") unary | power
+//	power   = primary [ "^" unary ]        (right associative)
+//	primary = number | ident | ident "(" [ expr { "," expr } ] ")" | "(" expr ")"
+//
+// so -2^2 is -4 and 2^3^2 is 2^9.
+//
+// With the ImplicitMultiplication option, a term is made of products
+//
+//	term    = product { ("*" | "/" | "%") product }
+//	product = unary { power }
+//
+// where the implicitly multiplied factors must start with an identifier or
+// "(", as in "2 pi" or "10 GiB". Then 3 x^2 is 3*(x^2) and 100 km / 2 h is
+// (100*km)/(2*h).
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Error is a syntax or evaluation error. Pos is the 1-based position of the
+// offending character in the expression.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(offset int, format string, args ...interface{}) *Error {
+	return &Error{Pos: offset + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Node is a node of a parsed expression.
+type Node interface {
+	// Offset is the 0-based byte offset of the node in the source.
+	Offset() int
+}
+
+// Number is a numeric literal.
+type Number struct {
+	Pos   int
+	Value float64
+}
+
+// Ident is a reference to a variable or constant.
+type Ident struct {
+	Pos  int
+	Name string
+}
+
+// Unary is a unary operation; Op is '+' or '-'.
+type Unary struct {
+	Pos int
+	Op  byte
+	X   Node
+}
+
+// Binary is a binary operation; Op is one of + - * / % ^.
+type Binary struct {
+	Pos  int
+	Op   byte
+	X, Y Node
+}
+
+// Call is a function call.
+type Call struct {
+	Pos  int
+	Func string
+	Args []Node
+}
+
+func (n *Number) Offset() int { return n.Pos }
+func (n *Ident) Offset() int  { return n.Pos }
+func (n *Unary) Offset() int  { return n.Pos }
+func (n *Binary) Offset() int { return n.Pos }
+func (n *Call) Offset() int   { return n.Pos }
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp // one of + - * / % ^ ( ) , =
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	text string
+	num  float64
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lex splits src into tokens.
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			// exponent, only when followed by digits
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				j := i + 1
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				if j < len(src) && isDigit(src[j]) {
+					for j < len(src) && isDigit(src[j]) {
+						j++
+					}
+					i = j
+				}
+			}
+			text := src[start:i]
+			v, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, errorf(start, "invalid number %q", text)
+			}
+			toks = append(toks, token{kind: tokNumber, pos: start, text: text, num: v})
+		case isLetter(rune(c)) || c >= 0x80:
+			start := i
+			for i < len(src) {
+				r, size := utf8.DecodeRuneInString(src[i:])
+				if !isLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			if i == start {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, errorf(start, "unexpected character %q", r)
+			}
+			toks = append(toks, token{kind: tokIdent, pos: start, text: src[start:i]})
+		case strings.IndexByte("+-*/%^(),=", c) >= 0:
+			toks = append(toks, token{kind: tokOp, pos: i, text: src[i : i+1]})
+			i++
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, errorf(i, "unexpected character %q", r)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+func isLetter(r rune) bool { return r == '_' || unicode.IsLetter(r) }
+
+// maxDepth bounds the nesting of parentheses, calls, signs and powers, so
+// that parsing a hostile expression cannot exhaust the stack.
+const maxDepth = 256
+
+type parser struct {
+	toks        []token
+	pos         int
+	depth       int
+	implicitMul bool
+}
+
+// An Option changes how Parse reads an expression.
+type Option func(*parser)
+
+// ImplicitMultiplication makes Parse read juxtaposed factors, as in
+// "2 pi" or "10 km", as a multiplication.
+func ImplicitMultiplication() Option {
+	return func(p *parser) { p.implicitMul = true }
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(ops string) bool {
+	t := p.peek()
+	return t.kind == tokOp && strings.Contains(ops, t.text)
+}
+
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokOp || t.text != op {
+		return errorf(t.pos, "expected %q, found %v", op, t)
+	}
+	return nil
+}
+
+// Parse parses a single expression.
+func Parse(src string, opts ...Option) (Node, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	for _, opt := range opts {
+		opt(p)
+	}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "unexpected %v", t)
+	}
+	return n, nil
+}
+
+func (p *parser) expr() (Node, error) {
+	x, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.next()
+		y, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Pos: op.pos, Op: op.text[0], X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) term() (Node, error) {
+	x, err := p.product()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/%") {
+		op := p.next()
+		y, err := p.product()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Pos: op.pos, Op: op.text[0], X: x, Y: y}
+	}
+	return x, nil
+}
+
+// product parses a unary and the factors implicitly multiplied with it.
+func (p *parser) product() (Node, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.implicitMul && (p.peek().kind == tokIdent || p.isOp("(")) {
+		pos := p.peek().pos
+		y, err := p.power()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Pos: pos, Op: '*', X: x, Y: y}
+	}
+	return x, nil
+}
+
+// unary is on every recursive path of the grammar, so it is where the
+// nesting depth is counted.
+func (p *parser) unary() (Node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, errorf(p.peek().pos, "expression nested more than %d deep", maxDepth)
+	}
+	if p.isOp("+-") {
+		op := p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Pos: op.pos, Op: op.text[0], X: x}, nil
+	}
+	return p.power()
+}
+
+func (p *parser) power() (Node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("^") {
+		op := p.next()
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Binary{Pos: op.pos, Op: '^', X: x, Y: y}, nil
+	}
+	return x, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokNumber:
+		return &Number{Pos: t.pos, Value: t.num}, nil
+	case t.kind == tokIdent:
+		if !p.isOp("(") {
+			return &Ident{Pos: t.pos, Name: t.text}, nil
+		}
+		p.next()
+		call := &Call{Pos: t.pos, Func: t.text}
+		if p.isOp(")") {
+			p.next()
+			return call, nil
+		}
+		for {
+			arg, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return call, nil
+	case t.kind == tokOp && t.text == "(":
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	}
+	return nil, errorf(t.pos, "unexpected %v", t)
+}
@@ -0,0 +1,169 @@
+package expr
+
+import "errors"
+
+// StatementKind distinguishes the forms of a Statement.
+type StatementKind int
+
+const (
+	// ExprStatement is a bare expression, e.g. "2 * x".
+	ExprStatement StatementKind = iota
+	// AssignStatement assigns a variable, e.g. "x = 3".
+	AssignStatement
+	// DefineStatement defines a function, e.g. "f(x, y) = x * y".
+	DefineStatement
+)
+
+// Statement is a parsed line of input.
+type Statement struct {
+	Kind StatementKind
+	// Pos is the 0-based offset of Name; Name and Params are empty for
+	// expressions.
+	Pos    int
+	Name   string
+	Params []string
+	Body   Node
+}
+
+// ParseStatement parses an expression, a variable assignment or a function
+// definition.
+func ParseStatement(src string) (*Statement, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{toks: toks}
+	st := &Statement{Kind: ExprStatement}
+	if head, ok := p.statementHead(); ok {
+		st = head
+	}
+	if st.Body, err = p.expr(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "unexpected %v", t)
+	}
+	return st, nil
+}
+
+// statementHead consumes the left-hand side and "=" of an assignment or
+// definition. Otherwise it consumes nothing and returns false.
+func (p *parser) statementHead() (*Statement, bool) {
+	name := p.peek()
+	if name.kind != tokIdent {
+		return nil, false
+	}
+	st := &Statement{Kind: AssignStatement, Pos: name.pos, Name: name.text}
+	i := 1
+	if p.opAt(i, "(") {
+		st.Kind = DefineStatement
+		st.Params = []string{}
+		i++
+		if !p.opAt(i, ")") {
+			for {
+				if p.toks[i].kind != tokIdent {
+					return nil, false
+				}
+				st.Params = append(st.Params, p.toks[i].text)
+				i++
+				if !p.opAt(i, ",") {
+					break
+				}
+				i++
+			}
+		}
+		if !p.opAt(i, ")") {
+			return nil, false
+		}
+		i++
+	}
+	if !p.opAt(i, "=") {
+		return nil, false
+	}
+	p.pos = i + 1
+	return st, true
+}
+
+func (p *parser) opAt(i int, op string) bool {
+	t := p.toks[i]
+	return t.kind == tokOp && t.text == op
+}
+
+// Walk calls fn for n and each node below it in depth-first order.
+func Walk(n Node, fn func(Node)) {
+	fn(n)
+	switch n := n.(type) {
+	case *Unary:
+		Walk(n.X, fn)
+	case *Binary:
+		Walk(n.X, fn)
+		Walk(n.Y, fn)
+	case *Call:
+		for _, arg := range n.Args {
+			Walk(arg, fn)
+		}
+	}
+}
+
+// Define returns a function evaluating body with params bound to its
+// arguments. Every other identifier and function in body is looked up in
+// env now, so later changes to env do not affect the result and a
+// function can never call itself.
+func Define(params []string, body Node, env *Env) (Func, error) {
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if seen[p] {
+			return Func{}, errorf(body.Offset(), "duplicate parameter %q", p)
+		}
+		seen[p] = true
+	}
+	captured := &Env{Vars: map[string]float64{}, Funcs: map[string]Func{}}
+	var err error
+	Walk(body, func(n Node) {
+		if err != nil {
+			return
+		}
+		switch n := n.(type) {
+		case *Ident:
+			if seen[n.Name] {
+				return
+			}
+			v, ok := env.lookupVar(n.Name)
+			if !ok {
+				err = errorf(n.Pos, "unknown identifier %q", n.Name)
+				return
+			}
+			captured.Vars[n.Name] = v
+		case *Call:
+			f, ok := env.lookupFunc(n.Func)
+			if !ok {
+				err = errorf(n.Pos, "unknown function %q", n.Func)
+				return
+			}
+			captured.Funcs[n.Func] = f
+		}
+	})
+	if err != nil {
+		return Func{}, err
+	}
+	return Func{
+		MinArgs: len(params),
+		MaxArgs: len(params),
+		Fn: func(args []float64) (float64, error) {
+			local := &Env{Vars: make(map[string]float64, len(captured.Vars)+len(params)), Funcs: captured.Funcs}
+			for k, v := range captured.Vars {
+				local.Vars[k] = v
+			}
+			for i, p := range params {
+				local.Vars[p] = args[i]
+			}
+			v, err := Eval(body, local)
+			if e, ok := err.(*Error); ok {
+				// Positions refer to the definition, not to the caller's
+				// source; Eval reports the error at the call instead.
+				return 0, errors.New(e.Msg)
+			}
+			return v, err
+		},
+	}, nil
+}
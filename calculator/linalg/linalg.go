@@ -0,0 +1,247 @@
+// Package linalg implements dense matrix and vector operations on float64.
+package linalg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	// ErrDimension is returned when operand shapes do not fit the operation.
+	ErrDimension = errors.New("dimension mismatch")
+	// ErrSingular is returned when a matrix has no inverse.
+	ErrSingular = errors.New("matrix is singular")
+)
+
+// Matrix is a dense matrix with its elements stored row by row in Data.
+type Matrix struct {
+	Rows, Cols int
+	Data       []float64
+}
+
+// New returns a rows×cols matrix backed by data, which must hold exactly
+// rows*cols elements. A nil data allocates a zero matrix.
+func New(rows, cols int, data []float64) (*Matrix, error) {
+	if rows <= 0 || cols <= 0 {
+		return nil, fmt.Errorf("%w: %d×%d matrix", ErrDimension, rows, cols)
+	}
+	if data == nil {
+		data = make([]float64, rows*cols)
+	}
+	if len(data) != rows*cols {
+		return nil, fmt.Errorf("%w: %d values for a %d×%d matrix", ErrDimension, len(data), rows, cols)
+	}
+	return &Matrix{Rows: rows, Cols: cols, Data: data}, nil
+}
+
+// Identity returns the n×n identity matrix.
+func Identity(n int) *Matrix {
+	m := &Matrix{Rows: n, Cols: n, Data: make([]float64, n*n)}
+	for i := 0; i < n; i++ {
+		m.Data[i*n+i] = 1
+	}
+	return m
+}
+
+// At returns the element in row i and column j.
+func (m *Matrix) At(i, j int) float64 { return m.Data[i*m.Cols+j] }
+
+func (m *Matrix) row(i int) []float64 { return m.Data[i*m.Cols : (i+1)*m.Cols] }
+
+func (m *Matrix) clone() *Matrix {
+	return &Matrix{Rows: m.Rows, Cols: m.Cols, Data: append([]float64(nil), m.Data...)}
+}
+
+// Mul returns the product a·b.
+func Mul(a, b *Matrix) (*Matrix, error) {
+	if a.Cols != b.Rows {
+		return nil, fmt.Errorf("%w: cannot multiply %d×%d by %d×%d", ErrDimension, a.Rows, a.Cols, b.Rows, b.Cols)
+	}
+	c := &Matrix{Rows: a.Rows, Cols: b.Cols, Data: make([]float64, a.Rows*b.Cols)}
+	// The i-k-j order walks b and c row by row.
+	for i := 0; i < a.Rows; i++ {
+		ci := c.row(i)
+		for k, aik := range a.row(i) {
+			if aik == 0 {
+				continue
+			}
+			for j, bkj := range b.row(k) {
+				ci[j] += aik * bkj
+			}
+		}
+	}
+	return c, nil
+}
+
+// Transpose returns the transpose of m.
+func Transpose(m *Matrix) *Matrix {
+	t := &Matrix{Rows: m.Cols, Cols: m.Rows, Data: make([]float64, len(m.Data))}
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			t.Data[j*t.Cols+i] = m.Data[i*m.Cols+j]
+		}
+	}
+	return t
+}
+
+// Dot returns the dot product of two vectors of the same length.
+func Dot(a, b []float64) (float64, error) {
+	if len(a) != len(b) {
+		return 0, fmt.Errorf("%w: vectors of length %d and %d", ErrDimension, len(a), len(b))
+	}
+	var s float64
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s, nil
+}
+
+// Cross returns the cross product of two three-dimensional vectors.
+func Cross(a, b []float64) ([]float64, error) {
+	if len(a) != 3 || len(b) != 3 {
+		return nil, fmt.Errorf("%w: cross product needs vectors of length 3, got %d and %d", ErrDimension, len(a), len(b))
+	}
+	return []float64{
+		a[1]*b[2] - a[2]*b[1],
+		a[2]*b[0] - a[0]*b[2],
+		a[0]*b[1] - a[1]*b[0],
+	}, nil
+}
+
+// lu is the LU decomposition with partial pivoting of a square matrix:
+// P·A = L·U, with L unit lower triangular and both factors packed in m.
+type lu struct {
+	m        *Matrix
+	perm     []int
+	sign     float64
+	singular bool
+}
+
+func decompose(a *Matrix) (*lu, error) {
+	if a.Rows != a.Cols {
+		return nil, fmt.Errorf("%w: %d×%d matrix is not square", ErrDimension, a.Rows, a.Cols)
+	}
+	n := a.Rows
+	f := &lu{m: a.clone(), perm: make([]int, n), sign: 1}
+	for i := range f.perm {
+		f.perm[i] = i
+	}
+	// Each row is measured against its largest element, so that rows of
+	// very different sizes are pivoted on and judged alike (scaled partial
+	// pivoting). Pivots this small relative to their row are rounding
+	// noise, so the matrix is treated as singular.
+	scale := make([]float64, n)
+	for i := range scale {
+		for _, v := range a.row(i) {
+			scale[i] = math.Max(scale[i], math.Abs(v))
+		}
+	}
+	tol := float64(n) * 0x1p-52
+	relative := func(i, k int) float64 {
+		if scale[i] == 0 {
+			return 0
+		}
+		return math.Abs(f.m.At(i, k)) / scale[i]
+	}
+
+	m := f.m
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if relative(i, k) > relative(p, k) {
+				p = i
+			}
+		}
+		if relative(p, k) <= tol {
+			f.singular = true
+			continue
+		}
+		if p != k {
+			rp, rk := m.row(p), m.row(k)
+			for j := range rk {
+				rp[j], rk[j] = rk[j], rp[j]
+			}
+			scale[p], scale[k] = scale[k], scale[p]
+			f.perm[p], f.perm[k] = f.perm[k], f.perm[p]
+			f.sign = -f.sign
+		}
+		pivot := m.At(k, k)
+		rk := m.row(k)
+		for i := k + 1; i < n; i++ {
+			ri := m.row(i)
+			l := ri[k] / pivot
+			ri[k] = l
+			for j := k + 1; j < n; j++ {
+				ri[j] -= l * rk[j]
+			}
+		}
+	}
+	return f, nil
+}
+
+// Det returns the determinant of a square matrix.
+func Det(a *Matrix) (float64, error) {
+	f, err := decompose(a)
+	if err != nil {
+		return 0, err
+	}
+	if f.singular {
+		return 0, nil
+	}
+	d := f.sign
+	for i := 0; i < a.Rows; i++ {
+		d *= f.m.At(i, i)
+	}
+	return d, nil
+}
+
+// Solve returns X with a·X = b. Each column of b is a right-hand side.
+func Solve(a, b *Matrix) (*Matrix, error) {
+	if b.Rows != a.Rows {
+		return nil, fmt.Errorf("%w: %d×%d system with %d right-hand side rows", ErrDimension, a.Rows, a.Cols, b.Rows)
+	}
+	f, err := decompose(a)
+	if err != nil {
+		return nil, err
+	}
+	if f.singular {
+		return nil, ErrSingular
+	}
+	n, m := a.Rows, f.m
+	x := &Matrix{Rows: n, Cols: b.Cols, Data: make([]float64, n*b.Cols)}
+	for i, p := range f.perm {
+		copy(x.row(i), b.row(p))
+	}
+	// Forward substitution with L, then back substitution with U.
+	for i := 0; i < n; i++ {
+		xi := x.row(i)
+		for k := 0; k < i; k++ {
+			l, xk := m.At(i, k), x.row(k)
+			for j := range xi {
+				xi[j] -= l * xk[j]
+			}
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		xi := x.row(i)
+		for k := i + 1; k < n; k++ {
+			u, xk := m.At(i, k), x.row(k)
+			for j := range xi {
+				xi[j] -= u * xk[j]
+			}
+		}
+		for j := range xi {
+			xi[j] /= m.At(i, i)
+		}
+	}
+	return x, nil
+}
+
+// Inverse returns the inverse of a square matrix.
+func Inverse(a *Matrix) (*Matrix, error) {
+	if a.Rows != a.Cols {
+		return nil, fmt.Errorf("%w: %d×%d matrix is not square", ErrDimension, a.Rows, a.Cols)
+	}
+	return Solve(a, Identity(a.Rows))
+}
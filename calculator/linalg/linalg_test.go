@@ -0,0 +1,143 @@
+package linalg
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func mustNew(t *testing.T, rows, cols int, data ...float64) *Matrix {
+	t.Helper()
+	m, err := New(rows, cols, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func approxEqual(a, b *Matrix, tol float64) bool {
+	if a.Rows != b.Rows || a.Cols != b.Cols {
+		return false
+	}
+	for i := range a.Data {
+		if math.Abs(a.Data[i]-b.Data[i]) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(2, 2, []float64{1, 2, 3}); !errors.Is(err, ErrDimension) {
+		t.Errorf("New with 3 values for 2×2 = %v, want ErrDimension", err)
+	}
+	if _, err := New(0, 2, nil); !errors.Is(err, ErrDimension) {
+		t.Errorf("New(0, 2) = %v, want ErrDimension", err)
+	}
+}
+
+func TestMulTranspose(t *testing.T) {
+	a := mustNew(t, 2, 3, 1, 2, 3, 4, 5, 6)
+	b := mustNew(t, 3, 2, 7, 8, 9, 10, 11, 12)
+	got, err := Mul(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustNew(t, 2, 2, 58, 64, 139, 154); !approxEqual(got, want, 0) {
+		t.Errorf("Mul = %v, want %v", got, want)
+	}
+	if _, err := Mul(a, a); !errors.Is(err, ErrDimension) {
+		t.Errorf("Mul(2×3, 2×3) = %v, want ErrDimension", err)
+	}
+	if got, want := Transpose(a), mustNew(t, 3, 2, 1, 4, 2, 5, 3, 6); !approxEqual(got, want, 0) {
+		t.Errorf("Transpose = %v, want %v", got, want)
+	}
+}
+
+func TestDet(t *testing.T) {
+	tests := []struct {
+		m    *Matrix
+		want float64
+	}{
+		{mustNew(t, 1, 1, -4), -4},
+		{mustNew(t, 2, 2, 0, 1, 1, 0), -1},
+		{mustNew(t, 3, 3, 2, -3, 1, 2, 0, -1, 1, 4, 5), 49},
+		{mustNew(t, 3, 3, 1, 2, 3, 4, 5, 6, 7, 8, 9), 0},
+	}
+	for _, tt := range tests {
+		got, err := Det(tt.m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Det(%v) = %v, want %v", tt.m.Data, got, tt.want)
+		}
+	}
+	// well conditioned, with entries of very different sizes
+	if got, err := Det(mustNew(t, 2, 2, 1e-20, 0, 0, 1)); err != nil || got != 1e-20 {
+		t.Errorf("Det(diag(1e-20, 1)) = %v, %v, want 1e-20", got, err)
+	}
+	if _, err := Det(mustNew(t, 2, 3, 1, 2, 3, 4, 5, 6)); !errors.Is(err, ErrDimension) {
+		t.Errorf("Det(2×3) = %v, want ErrDimension", err)
+	}
+}
+
+func TestSolveInverse(t *testing.T) {
+	a := mustNew(t, 3, 3, 0, 2, 1, 1, 1, 1, 2, 1, 0)
+	b := mustNew(t, 3, 1, 5, 4, 4)
+	x, err := Solve(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustNew(t, 3, 1, 1, 2, 1); !approxEqual(x, want, 1e-12) {
+		t.Errorf("Solve = %v, want %v", x.Data, want.Data)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	r := mustNew(t, 20, 20, nil...)
+	for i := range r.Data {
+		r.Data[i] = rng.NormFloat64()
+	}
+	inv, err := Inverse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := Mul(r, inv)
+	if !approxEqual(p, Identity(20), 1e-9) {
+		t.Errorf("A·Inverse(A) is not the identity: %v", p.Data)
+	}
+
+	tiny := mustNew(t, 2, 2, 1e-20, 0, 0, 1)
+	inv, err = Inverse(tiny)
+	if err != nil {
+		t.Fatalf("Inverse(diag(1e-20, 1)): %v", err)
+	}
+	if p, _ := Mul(tiny, inv); !approxEqual(p, Identity(2), 1e-12) {
+		t.Errorf("Inverse(diag(1e-20, 1)) = %v", inv.Data)
+	}
+
+	singular := mustNew(t, 3, 3, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	if _, err := Inverse(singular); !errors.Is(err, ErrSingular) {
+		t.Errorf("Inverse(singular) = %v, want ErrSingular", err)
+	}
+	if _, err := Solve(a, mustNew(t, 2, 1, 1, 2)); !errors.Is(err, ErrDimension) {
+		t.Errorf("Solve with 2 rows for 3×3 = %v, want ErrDimension", err)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	if d, err := Dot([]float64{1, 2, 3}, []float64{4, -5, 6}); err != nil || d != 12 {
+		t.Errorf("Dot = %v, %v, want 12", d, err)
+	}
+	if _, err := Dot([]float64{1}, []float64{1, 2}); !errors.Is(err, ErrDimension) {
+		t.Errorf("Dot of different lengths = %v, want ErrDimension", err)
+	}
+	c, err := Cross([]float64{1, 0, 0}, []float64{0, 1, 0})
+	if err != nil || c[0] != 0 || c[1] != 0 || c[2] != 1 {
+		t.Errorf("Cross(x, y) = %v, %v, want z", c, err)
+	}
+	if _, err := Cross([]float64{1, 2}, []float64{3, 4}); !errors.Is(err, ErrDimension) {
+		t.Errorf("Cross of 2-vectors = %v, want ErrDimension", err)
+	}
+}
@@ -0,0 +1,306 @@
+// Package numeric integrates, finds roots of and minimizes functions of one
+// variable.
+package numeric
+
+import (
+	"context"
+	"errors"
+	"math"
+)
+
+// Func is a function of one variable. Errors abort the computation.
+type Func func(x float64) (float64, error)
+
+// ErrNotBracketed is returned by FindRoot when the function has the same
+// sign at both ends of the interval.
+var ErrNotBracketed = errors.New("function has the same sign at both ends of the interval")
+
+// Result is the outcome of a computation. When Converged is false the
+// iteration limit was reached first and X and Value are the best estimates
+// found.
+type Result struct {
+	// X is the root or the minimum; it is unused by Integrate.
+	X float64
+	// Value is the integral, or f(X).
+	Value float64
+	// ErrorEstimate estimates the absolute error of an integral.
+	ErrorEstimate float64
+	// Iterations counts iterations, or function evaluations for Integrate.
+	Iterations int
+	Converged  bool
+}
+
+// checkEvery is how many function evaluations may pass between checks of
+// the context in Integrate.
+const checkEvery = 256
+
+// maxDepth bounds the recursion of Integrate, halving the interval each
+// time.
+const maxDepth = 50
+
+// Integrate returns the integral of f from a to b using adaptive Simpson
+// quadrature, refining until the estimated absolute error is below tol or
+// maxEvals evaluations of f have been made.
+func Integrate(ctx context.Context, f Func, a, b, tol float64, maxEvals int) (Result, error) {
+	in := &integrator{ctx: ctx, f: f, maxEvals: maxEvals, converged: true}
+	fa, err := in.eval(a)
+	if err != nil {
+		return Result{}, err
+	}
+	fb, err := in.eval(b)
+	if err != nil {
+		return Result{}, err
+	}
+	m := (a + b) / 2
+	fm, err := in.eval(m)
+	if err != nil {
+		return Result{}, err
+	}
+	whole := (b - a) / 6 * (fa + 4*fm + fb)
+	v, err := in.simpson(a, b, tol, whole, fa, fm, fb, maxDepth)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Value: v, ErrorEstimate: in.errEst, Iterations: in.evals, Converged: in.converged}, nil
+}
+
+type integrator struct {
+	ctx       context.Context
+	f         Func
+	evals     int
+	maxEvals  int
+	errEst    float64
+	converged bool
+}
+
+func (in *integrator) eval(x float64) (float64, error) {
+	in.evals++
+	if in.evals%checkEvery == 0 {
+		if err := in.ctx.Err(); err != nil {
+			return 0, err
+		}
+	}
+	return in.f(x)
+}
+
+// simpson refines whole, the Simpson estimate over [a, b], by comparing it
+// with the estimates over both halves.
+func (in *integrator) simpson(a, b, tol, whole, fa, fm, fb float64, depth int) (float64, error) {
+	m := (a + b) / 2
+	if in.evals+2 > in.maxEvals {
+		in.converged = false
+		return whole, nil
+	}
+	flm, err := in.eval((a + m) / 2)
+	if err != nil {
+		return 0, err
+	}
+	frm, err := in.eval((m + b) / 2)
+	if err != nil {
+		return 0, err
+	}
+	left := (m - a) / 6 * (fa + 4*flm + fm)
+	right := (b - m) / 6 * (fm + 4*frm + fb)
+	delta := left + right - whole
+	if math.Abs(delta) <= 15*tol || depth <= 0 {
+		if depth <= 0 && math.Abs(delta) > 15*tol {
+			in.converged = false
+		}
+		in.errEst += math.Abs(delta) / 15
+		return left + right + delta/15, nil
+	}
+	l, err := in.simpson(a, m, tol/2, left, fa, flm, fm, depth-1)
+	if err != nil {
+		return 0, err
+	}
+	r, err := in.simpson(m, b, tol/2, right, fm, frm, fb, depth-1)
+	if err != nil {
+		return 0, err
+	}
+	return l + r, nil
+}
+
+// FindRoot returns a root of f in [a, b] using Brent's method. f(a) and
+// f(b) must differ in sign. The root is located to within tol.
+func FindRoot(ctx context.Context, f Func, a, b, tol float64, maxIter int) (Result, error) {
+	fa, err := f(a)
+	if err != nil {
+		return Result{}, err
+	}
+	fb, err := f(b)
+	if err != nil {
+		return Result{}, err
+	}
+	switch {
+	case fa == 0:
+		return Result{X: a, Value: fa, Converged: true}, nil
+	case fb == 0:
+		return Result{X: b, Value: fb, Converged: true}, nil
+	case math.Signbit(fa) == math.Signbit(fb):
+		return Result{}, ErrNotBracketed
+	}
+
+	c, fc := b, fb
+	var d, e float64
+	for iter := 1; iter <= maxIter; iter++ {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		if math.Signbit(fb) == math.Signbit(fc) {
+			// Keep the root between b and c.
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+		tol1 := 2*eps*math.Abs(b) + tol/2
+		xm := (c - b) / 2
+		if math.Abs(xm) <= tol1 || fb == 0 {
+			return Result{X: b, Value: fb, Iterations: iter, Converged: true}, nil
+		}
+		if math.Abs(e) >= tol1 && math.Abs(fa) > math.Abs(fb) {
+			// Try inverse quadratic interpolation, or the secant method
+			// when only two points are distinct.
+			var p, q float64
+			s := fb / fa
+			if a == c {
+				p = 2 * xm * s
+				q = 1 - s
+			} else {
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*xm*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			}
+			p = math.Abs(p)
+			if 2*p < math.Min(3*xm*q-math.Abs(tol1*q), math.Abs(e*q)) {
+				e = d
+				d = p / q
+			} else {
+				// Interpolation would be slow, bisect instead.
+				d = xm
+				e = d
+			}
+		} else {
+			d = xm
+			e = d
+		}
+		a, fa = b, fb
+		if math.Abs(d) > tol1 {
+			b += d
+		} else {
+			b += math.Copysign(tol1, xm)
+		}
+		if fb, err = f(b); err != nil {
+			return Result{}, err
+		}
+	}
+	return Result{X: b, Value: fb, Iterations: maxIter}, nil
+}
+
+const (
+	eps     = 0x1p-52
+	sqrtEps = 0x1p-26
+	// golden is the golden section ratio (3 - sqrt(5)) / 2.
+	golden = 0.3819660112501051
+)
+
+// Minimize returns a minimum of f in [a, b] using Brent's method, which
+// combines golden section search with parabolic interpolation. It finds
+// the global minimum of unimodal functions and a local one otherwise; the
+// ends of the interval are considered too, unless f fails there. The
+// minimum is located to within about tol.
+func Minimize(ctx context.Context, f Func, a, b, tol float64, maxIter int) (Result, error) {
+	if a > b {
+		a, b = b, a
+	}
+	lo, hi := a, b
+	x := a + golden*(b-a)
+	fx, err := f(x)
+	if err != nil {
+		return Result{}, err
+	}
+	w, v, fw, fv := x, x, fx, fx
+	var d, e float64
+	res := Result{Iterations: maxIter}
+	for iter := 1; iter <= maxIter; iter++ {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		xm := (a + b) / 2
+		tol1 := sqrtEps*math.Abs(x) + tol/3
+		tol2 := 2 * tol1
+		if math.Abs(x-xm) <= tol2-(b-a)/2 {
+			res = Result{Iterations: iter, Converged: true}
+			break
+		}
+		useGolden := true
+		if math.Abs(e) > tol1 {
+			// Fit a parabola through x, w and v.
+			r := (x - w) * (fx - fv)
+			q := (x - v) * (fx - fw)
+			p := (x-v)*q - (x-w)*r
+			q = 2 * (q - r)
+			if q > 0 {
+				p = -p
+			}
+			q = math.Abs(q)
+			etemp := e
+			e = d
+			if math.Abs(p) < math.Abs(q*etemp/2) && p > q*(a-x) && p < q*(b-x) {
+				d = p / q
+				if u := x + d; u-a < tol2 || b-u < tol2 {
+					d = math.Copysign(tol1, xm-x)
+				}
+				useGolden = false
+			}
+		}
+		if useGolden {
+			if x >= xm {
+				e = a - x
+			} else {
+				e = b - x
+			}
+			d = golden * e
+		}
+		u := x + math.Copysign(math.Max(math.Abs(d), tol1), d)
+		fu, err := f(u)
+		if err != nil {
+			return Result{}, err
+		}
+		if fu <= fx {
+			if u >= x {
+				a = x
+			} else {
+				b = x
+			}
+			v, w, x = w, x, u
+			fv, fw, fx = fw, fx, fu
+			continue
+		}
+		if u < x {
+			a = u
+		} else {
+			b = u
+		}
+		if fu <= fw || w == x {
+			v, w = w, u
+			fv, fw = fw, fu
+		} else if fu <= fv || v == x || v == w {
+			v, fv = u, fu
+		}
+	}
+	res.X, res.Value = x, fx
+	for _, end := range []float64{lo, hi} {
+		if fe, err := f(end); err == nil && fe < res.Value {
+			res.X, res.Value = end, fe
+		}
+	}
+	return res, nil
+}
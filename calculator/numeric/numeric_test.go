@@ -0,0 +1,113 @@
+package numeric
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func pure(f func(float64) float64) Func {
+	return func(x float64) (float64, error) { return f(x), nil }
+}
+
+func TestIntegrate(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(float64) float64
+		a, b float64
+		want float64
+	}{
+		{"polynomial", func(x float64) float64 { return x * x }, 0, 3, 9},
+		{"sin", math.Sin, 0, math.Pi, 2},
+		{"reversed", math.Sin, math.Pi, 0, -2},
+		{"gaussian", func(x float64) float64 { return math.Exp(-x * x) }, -10, 10, math.Sqrt(math.Pi)},
+		{"sqrt", math.Sqrt, 0, 1, 2.0 / 3},
+		{"empty", math.Exp, 1, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Integrate(context.Background(), pure(tt.f), tt.a, tt.b, 1e-10, 1e6)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Converged || math.Abs(res.Value-tt.want) > 1e-8 {
+				t.Errorf("Integrate = %+v, want %v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntegrateLimits(t *testing.T) {
+	f := pure(func(x float64) float64 { return math.Sin(1 / x) })
+	res, err := Integrate(context.Background(), f, 0.001, 1, 1e-12, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Converged || res.Iterations > 100 {
+		t.Errorf("Integrate with 100 evaluations = %+v, want unconverged", res)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Integrate(ctx, f, 0.001, 1, 1e-15, 1e9); !errors.Is(err, context.Canceled) {
+		t.Errorf("Integrate with a canceled context error = %v", err)
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(float64) float64
+		a, b float64
+		want float64
+	}{
+		{"sqrt2", func(x float64) float64 { return x*x - 2 }, 0, 2, math.Sqrt2},
+		{"cos", math.Cos, 0, 3, math.Pi / 2},
+		{"cubic", func(x float64) float64 { return (x - 1) * (x - 1) * (x - 1) }, -5, 4, 1},
+		{"endpoint", func(x float64) float64 { return x }, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := FindRoot(context.Background(), pure(tt.f), tt.a, tt.b, 1e-12, 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Converged || math.Abs(res.X-tt.want) > 1e-6 {
+				t.Errorf("FindRoot = %+v, want %v", res, tt.want)
+			}
+		})
+	}
+	if _, err := FindRoot(context.Background(), pure(math.Exp), 0, 1, 1e-12, 100); !errors.Is(err, ErrNotBracketed) {
+		t.Errorf("FindRoot(exp) error = %v, want ErrNotBracketed", err)
+	}
+	res, err := FindRoot(context.Background(), pure(math.Cos), 0, 3, 1e-15, 2)
+	if err != nil || res.Converged {
+		t.Errorf("FindRoot with 2 iterations = %+v, %v, want unconverged", res, err)
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(float64) float64
+		a, b float64
+		want float64
+	}{
+		{"parabola", func(x float64) float64 { return (x - 2) * (x - 2) }, 0, 5, 2},
+		{"cos", math.Cos, 0, 2 * math.Pi, math.Pi},
+		{"endpoint", func(x float64) float64 { return x }, -1, 1, -1},
+		{"x log x", func(x float64) float64 { return x * math.Log(x) }, 0.01, 1, 1 / math.E},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Minimize(context.Background(), pure(tt.f), tt.a, tt.b, 1e-10, 200)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(res.X-tt.want) > 1e-5 {
+				t.Errorf("Minimize = %+v, want %v", res, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+// Package primes tests primality and factorizes integers of any size.
+//
+// Numbers that fit in 64 bits use deterministic Miller–Rabin and Brent's
+// variant of Pollard's rho on machine words; larger numbers fall back to
+// math/big.
+package primes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// ErrNotPositive is returned when asked to factorize zero or a negative
+// number.
+var ErrNotPositive = errors.New("number must be positive")
+
+// smallPrimes are removed by trial division before running Pollard's rho.
+var smallPrimes = sieve(1000)
+
+// sieve returns the primes below n.
+func sieve(n int) []uint64 {
+	composite := make([]bool, n)
+	var ps []uint64
+	for i := 2; i < n; i++ {
+		if composite[i] {
+			continue
+		}
+		ps = append(ps, uint64(i))
+		for j := i * i; j < n; j += i {
+			composite[j] = true
+		}
+	}
+	return ps
+}
+
+// An IncompleteError is returned by Factorize when ctx is done before all
+// the factors are found.
+type IncompleteError struct {
+	// Rest is the product of the factors that were not emitted.
+	Rest *big.Int
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("%v left unfactored: %v", e.Rest, e.Err)
+}
+
+func (e *IncompleteError) Unwrap() error { return e.Err }
+
+// Factorize calls emit with every distinct prime factor of n and its
+// multiplicity, in the order the factors are found. It stops early when
+// emit returns an error, and returns that error, or when ctx is done, and
+// returns an *IncompleteError.
+func Factorize(ctx context.Context, n *big.Int, emit func(p *big.Int, multiplicity int) error) (err error) {
+	if n.Sign() <= 0 {
+		return ErrNotPositive
+	}
+	f := &factorizer{ctx: ctx, rest: new(big.Int).Set(n), emit: emit}
+	defer func() {
+		if err != nil && err == ctx.Err() {
+			err = &IncompleteError{Rest: f.rest, Err: err}
+		}
+	}()
+	for _, p := range smallPrimes {
+		if f.rest.Cmp(one) == 0 {
+			return nil
+		}
+		if err := f.found(new(big.Int).SetUint64(p)); err != nil {
+			return err
+		}
+	}
+	f.pending = []*big.Int{new(big.Int).Set(f.rest)}
+	for len(f.pending) > 0 {
+		m := f.pending[len(f.pending)-1]
+		f.pending = f.pending[:len(f.pending)-1]
+		if m.Cmp(one) == 0 {
+			continue
+		}
+		if IsPrime(m) {
+			if err := f.found(m); err != nil {
+				return err
+			}
+			continue
+		}
+		d, err := split(ctx, m)
+		if err != nil {
+			return err
+		}
+		f.pending = append(f.pending, d, new(big.Int).Quo(m, d))
+	}
+	return nil
+}
+
+var one = big.NewInt(1)
+
+type factorizer struct {
+	ctx context.Context
+	// rest is the part of n whose factors have not been emitted yet
+	rest *big.Int
+	// pending are the cofactors of rest still to be split
+	pending []*big.Int
+	emit    func(p *big.Int, multiplicity int) error
+}
+
+// found emits the prime p with its multiplicity in rest, and divides it out
+// of rest and of the pending cofactors.
+func (f *factorizer) found(p *big.Int) error {
+	if err := f.ctx.Err(); err != nil {
+		return err
+	}
+	k := divideOut(f.rest, p)
+	if k == 0 {
+		return nil
+	}
+	for _, m := range f.pending {
+		divideOut(m, p)
+	}
+	return f.emit(new(big.Int).Set(p), k)
+}
+
+// divideOut divides m by p as long as possible and returns how many times
+// it did.
+func divideOut(m, p *big.Int) int {
+	k := 0
+	q, r := new(big.Int), new(big.Int)
+	for {
+		q.QuoRem(m, p, r)
+		if r.Sign() != 0 {
+			return k
+		}
+		m.Set(q)
+		k++
+	}
+}
+
+// IsPrime reports whether n is prime. The answer is exact below 2^64 and
+// wrong with negligible probability above.
+func IsPrime(n *big.Int) bool {
+	if n.Sign() <= 0 {
+		return false
+	}
+	if n.IsUint64() {
+		return IsPrime64(n.Uint64())
+	}
+	// 20 Miller–Rabin rounds plus a Baillie-PSW test
+	return n.ProbablyPrime(20)
+}
+
+// millerRabinBases make Miller–Rabin deterministic for every 64-bit number.
+var millerRabinBases = []uint64{2, 325, 9375, 28178, 450775, 9780504, 1795265022}
+
+// IsPrime64 reports whether n is prime.
+func IsPrime64(n uint64) bool {
+	if n < 2 {
+		return false
+	}
+	for _, p := range smallPrimes[:12] {
+		if n%p == 0 {
+			return n == p
+		}
+	}
+	d, s := n-1, 0
+	for d%2 == 0 {
+		d /= 2
+		s++
+	}
+	for _, a := range millerRabinBases {
+		a %= n
+		if a == 0 {
+			continue
+		}
+		x := powMod(a, d, n)
+		if x == 1 || x == n-1 {
+			continue
+		}
+		composite := true
+		for i := 1; i < s; i++ {
+			x = mulMod(x, x, n)
+			if x == n-1 {
+				composite = false
+				break
+			}
+		}
+		if composite {
+			return false
+		}
+	}
+	return true
+}
+
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return bits.Rem64(hi, lo, m)
+}
+
+func addMod(a, b, m uint64) uint64 {
+	s, carry := bits.Add64(a, b, 0)
+	if carry != 0 || s >= m {
+		s -= m
+	}
+	return s
+}
+
+func powMod(a, e, m uint64) uint64 {
+	r := uint64(1)
+	for a %= m; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = mulMod(r, a, m)
+		}
+		a = mulMod(a, a, m)
+	}
+	return r
+}
+
+func gcd64(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// cancelCheckEvery is the number of rho iterations between context checks.
+const cancelCheckEvery = 1 << 12
+
+// split returns a non-trivial divisor of the odd composite m.
+func split(ctx context.Context, m *big.Int) (*big.Int, error) {
+	if m.IsUint64() {
+		d, err := rho64(ctx, m.Uint64())
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetUint64(d), nil
+	}
+	return rhoBig(ctx, m)
+}
+
+// rho64 finds a non-trivial divisor of the odd composite n with Brent's
+// variant of Pollard's rho.
+func rho64(ctx context.Context, n uint64) (uint64, error) {
+	const batch = 128
+	for c := uint64(1); ; c++ {
+		f := func(x uint64) uint64 { return addMod(mulMod(x, x, n), c, n) }
+		y, g, q := uint64(2), uint64(1), uint64(1)
+		var x, ys uint64
+		for r := 1; g == 1; r *= 2 {
+			x = y
+			for i := 0; i < r; i++ {
+				y = f(y)
+			}
+			for k := 0; k < r && g == 1; k += batch {
+				if err := ctx.Err(); err != nil {
+					return 0, err
+				}
+				ys = y
+				for i := 0; i < batch && i < r-k; i++ {
+					y = f(y)
+					q = mulMod(q, absDiff(x, y), n)
+				}
+				g = gcd64(q, n)
+			}
+		}
+		if g == n {
+			// the batch overshot, redo it one step at a time
+			for g = 1; g == 1; {
+				ys = f(ys)
+				g = gcd64(absDiff(x, ys), n)
+			}
+		}
+		if g != n {
+			return g, nil
+		}
+	}
+}
+
+// rhoBig is rho64 for numbers that do not fit in 64 bits.
+func rhoBig(ctx context.Context, n *big.Int) (*big.Int, error) {
+	const batch = 128
+	for c := int64(1); ; c++ {
+		cc := big.NewInt(c)
+		f := func(x *big.Int) {
+			x.Mul(x, x)
+			x.Add(x, cc)
+			x.Mod(x, n)
+		}
+		y, g, q := big.NewInt(2), big.NewInt(1), big.NewInt(1)
+		x, ys, diff := new(big.Int), new(big.Int), new(big.Int)
+		iterations := 0
+		for r := 1; g.Cmp(one) == 0; r *= 2 {
+			x.Set(y)
+			for i := 0; i < r; i++ {
+				f(y)
+				if iterations++; iterations%cancelCheckEvery == 0 {
+					if err := ctx.Err(); err != nil {
+						return nil, err
+					}
+				}
+			}
+			for k := 0; k < r && g.Cmp(one) == 0; k += batch {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				ys.Set(y)
+				for i := 0; i < batch && i < r-k; i++ {
+					f(y)
+					diff.Sub(x, y)
+					q.Mul(q, diff.Abs(diff))
+					q.Mod(q, n)
+				}
+				g.GCD(nil, nil, q, n)
+			}
+		}
+		if g.Cmp(n) == 0 {
+			for g.SetInt64(1); g.Cmp(one) == 0; {
+				f(ys)
+				diff.Sub(x, ys)
+				g.GCD(nil, nil, diff.Abs(diff), n)
+			}
+		}
+		if g.Cmp(n) != 0 {
+			return g, nil
+		}
+	}
+}
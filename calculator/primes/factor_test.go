@@ -0,0 +1,128 @@
+package primes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+type factor struct {
+	p string
+	k int
+}
+
+func factorize(t *testing.T, n string) []factor {
+	t.Helper()
+	v, ok := new(big.Int).SetString(n, 10)
+	if !ok {
+		t.Fatalf("bad number %q", n)
+	}
+	var got []factor
+	err := Factorize(context.Background(), v, func(p *big.Int, k int) error {
+		got = append(got, factor{p.String(), k})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Factorize(%s): %v", n, err)
+	}
+	sort.Slice(got, func(i, j int) bool {
+		a, _ := new(big.Int).SetString(got[i].p, 10)
+		b, _ := new(big.Int).SetString(got[j].p, 10)
+		return a.Cmp(b) < 0
+	})
+	return got
+}
+
+func TestFactorize(t *testing.T) {
+	tests := []struct {
+		n    string
+		want []factor
+	}{
+		{"1", nil},
+		{"2", []factor{{"2", 1}}},
+		{"120", []factor{{"2", 3}, {"3", 1}, {"5", 1}}},
+		{"2147483647", []factor{{"2147483647", 1}}},
+		// product of two 32-bit primes
+		{"18446743979220271189", []factor{{"4294967279", 1}, {"4294967291", 1}}},
+		{"9223372036854775807", []factor{{"7", 2}, {"73", 1}, {"127", 1}, {"337", 1}, {"92737", 1}, {"649657", 1}}},
+		// square of a prime above the trial division bound
+		{"1018081", []factor{{"1009", 2}}},
+		{"18446744073709551615", []factor{{"3", 1}, {"5", 1}, {"17", 1}, {"257", 1}, {"641", 1}, {"65537", 1}, {"6700417", 1}}},
+		// beyond 64 bits: (2^61-1) * (2^31-1)^2 * 2^3
+		{"85070591651006453333132570225048289272", nil},
+		// 37-bit prime times 81-bit prime
+		{"83076749774033942464542339036610963", nil},
+	}
+	// fill in the large cases from their known factors
+	tests[8].want = []factor{{"2", 3}, {"2147483647", 2}, {"2305843009213693951", 1}}
+	tests[9].want = []factor{{"68719476767", 1}, {"1208925819614629174706189", 1}}
+	for _, tt := range tests {
+		got := factorize(t, tt.n)
+		if len(got) != len(tt.want) {
+			t.Errorf("Factorize(%s) = %v, want %v", tt.n, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Factorize(%s) = %v, want %v", tt.n, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFactorizeRejectsNonPositive(t *testing.T) {
+	for _, n := range []int64{0, -1, -120} {
+		err := Factorize(context.Background(), big.NewInt(n), func(*big.Int, int) error { return nil })
+		if err != ErrNotPositive {
+			t.Errorf("Factorize(%d) error = %v, want ErrNotPositive", n, err)
+		}
+	}
+}
+
+func TestFactorizeCancel(t *testing.T) {
+	// product of two ~100-bit primes, far too slow for Pollard's rho
+	n, _ := new(big.Int).SetString("1606938044258990275541962093111894167460966469892788384261671", 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var found []string
+	err := Factorize(ctx, new(big.Int).Mul(n, big.NewInt(24)), func(p *big.Int, k int) error {
+		found = append(found, fmt.Sprintf("%v^%d", p, k))
+		return nil
+	})
+	var incomplete *IncompleteError
+	if !errors.As(err, &incomplete) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Factorize error = %v, want an IncompleteError for DeadlineExceeded", err)
+	}
+	// the small factors are found, the rest is left
+	if want := []string{"2^3", "3^1"}; !reflect.DeepEqual(found, want) || incomplete.Rest.Cmp(n) != 0 {
+		t.Errorf("Factorize found %v and left %v, want %v and %v", found, incomplete.Rest, want, n)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Factorize took %v to notice the deadline", elapsed)
+	}
+}
+
+func TestIsPrime64(t *testing.T) {
+	tests := []struct {
+		n    uint64
+		want bool
+	}{
+		{0, false}, {1, false}, {2, true}, {4, false}, {97, true},
+		{561, false},                  // Carmichael number
+		{3215031751, false},           // strong pseudoprime to bases 2, 3, 5, 7
+		{18446744073709551557, true},  // largest 64-bit prime
+		{18446744073709551615, false}, // 2^64-1
+	}
+	for _, tt := range tests {
+		if got := IsPrime64(tt.n); got != tt.want {
+			t.Errorf("IsPrime64(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package primes
+
+import (
+	"context"
+	"math"
+)
+
+const (
+	// sieveLimit is the largest number Range sieves. Beyond it the base
+	// primes would take too much memory, so candidates are tested with
+	// IsPrime64 instead.
+	sieveLimit = 1 << 40
+	// segmentSize is the number of integers sieved at a time.
+	segmentSize = 1 << 16
+)
+
+// Range calls fn for every prime p with lo <= p <= hi in increasing order,
+// stopping at the first error fn returns. Memory use does not depend on
+// the size of the range, and ctx is checked between segments.
+func Range(ctx context.Context, lo, hi uint64, fn func(p uint64) error) error {
+	if lo < 2 {
+		lo = 2
+	}
+	if hi < lo {
+		return nil
+	}
+	if lo <= sieveLimit {
+		if err := segmented(ctx, lo, min64(hi, sieveLimit), fn); err != nil {
+			return err
+		}
+	}
+	if hi <= sieveLimit {
+		return nil
+	}
+	n := max64(lo, sieveLimit+1) | 1
+	for i := 0; n <= hi; i++ {
+		if i%cancelCheckEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if IsPrime64(n) {
+			if err := fn(n); err != nil {
+				return err
+			}
+		}
+		if n > math.MaxUint64-2 {
+			break
+		}
+		n += 2
+	}
+	return nil
+}
+
+// segmented sieves [lo, hi] one segment at a time, with lo >= 2.
+func segmented(ctx context.Context, lo, hi uint64, fn func(p uint64) error) error {
+	base := sieve(int(isqrt(hi)) + 1)
+	composite := make([]bool, segmentSize)
+	for low := lo; low <= hi; low += segmentSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		high := min64(low+segmentSize-1, hi)
+		for i := range composite {
+			composite[i] = false
+		}
+		for _, p := range base {
+			if p*p > high {
+				break
+			}
+			start := (low + p - 1) / p * p
+			if start < p*p {
+				start = p * p
+			}
+			for m := start; m <= high; m += p {
+				composite[m-low] = true
+			}
+		}
+		for n := low; n <= high; n++ {
+			if !composite[n-low] {
+				if err := fn(n); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isqrt returns the largest r with r*r <= n.
+func isqrt(n uint64) uint64 {
+	r := uint64(math.Sqrt(float64(n)))
+	for r*r > n {
+		r--
+	}
+	for (r+1)*(r+1) <= n {
+		r++
+	}
+	return r
+}
+
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,80 @@
+package primes
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRange(t *testing.T) {
+	collect := func(lo, hi uint64) []uint64 {
+		var got []uint64
+		err := Range(context.Background(), lo, hi, func(p uint64) error {
+			got = append(got, p)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Range(%d, %d): %v", lo, hi, err)
+		}
+		return got
+	}
+	if got, want := collect(0, 30), []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Range(0, 30) = %v, want %v", got, want)
+	}
+	if got := collect(24, 28); len(got) != 0 {
+		t.Errorf("Range(24, 28) = %v, want none", got)
+	}
+	if got := collect(10, 5); len(got) != 0 {
+		t.Errorf("Range(10, 5) = %v, want none", got)
+	}
+
+	// Several segments, checked against trial division.
+	got := collect(1000000, 1000000+3*segmentSize)
+	var want []uint64
+	for n := uint64(1000000); n <= 1000000+3*segmentSize; n++ {
+		if IsPrime64(n) {
+			want = append(want, n)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Range over three segments found %d primes, want %d", len(got), len(want))
+	}
+
+	// Across the end of the sieve.
+	got = collect(sieveLimit-100, sieveLimit+100)
+	want = nil
+	for n := uint64(sieveLimit - 100); n <= sieveLimit+100; n++ {
+		if IsPrime64(n) {
+			want = append(want, n)
+		}
+	}
+	if !reflect.DeepEqual(got, want) || len(want) == 0 {
+		t.Errorf("Range around the sieve limit = %v, want %v", got, want)
+	}
+
+	if got := collect(math.MaxUint64-100, math.MaxUint64); len(got) == 0 || got[len(got)-1] != 18446744073709551557 {
+		t.Errorf("Range near the top of uint64 = %v", got)
+	}
+}
+
+func TestRangeStops(t *testing.T) {
+	stop := errors.New("stop")
+	n := 0
+	err := Range(context.Background(), 0, 1<<30, func(uint64) error {
+		if n++; n == 5 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 5 {
+		t.Errorf("Range returned %v after %d primes, want stop after 5", err, n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Range(ctx, 0, 1<<30, func(uint64) error { return nil }); err != context.Canceled {
+		t.Errorf("Range with a canceled context = %v", err)
+	}
+}
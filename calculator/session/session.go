@@ -0,0 +1,218 @@
+// Package session keeps the variables and functions of calculator
+// sessions between evaluations.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"example.com/calculator/expr"
+)
+
+// Limits on the state of a single session.
+const (
+	MaxVars  = 1000
+	MaxFuncs = 100
+	// MaxFuncCost bounds the number of nodes evaluated by one call of a
+	// user function, counting the bodies of the functions it calls.
+	MaxFuncCost = 100000
+)
+
+var (
+	// ErrNotFound is returned for unknown, deleted and expired sessions.
+	ErrNotFound = errors.New("session not found")
+	// ErrTooManySessions is returned when the manager is full.
+	ErrTooManySessions = errors.New("too many sessions")
+)
+
+// Manager creates sessions and expires them after a period of inactivity.
+type Manager struct {
+	ttl         time.Duration
+	maxSessions int
+	now         func() time.Time
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	lastSweep time.Time
+}
+
+// NewManager returns a manager holding at most maxSessions sessions, each
+// expiring once unused for ttl.
+func NewManager(ttl time.Duration, maxSessions int) *Manager {
+	return &Manager{
+		ttl:         ttl,
+		maxSessions: maxSessions,
+		now:         time.Now,
+		sessions:    map[string]*Session{},
+	}
+}
+
+// TTL returns the inactivity period after which sessions expire.
+func (m *Manager) TTL() time.Duration { return m.ttl }
+
+// Create starts a new, empty session.
+func (m *Manager) Create() (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if len(m.sessions) >= m.maxSessions || now.Sub(m.lastSweep) > m.ttl {
+		m.sweep(now)
+	}
+	if len(m.sessions) >= m.maxSessions {
+		return nil, ErrTooManySessions
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		ID:       id,
+		vars:     map[string]float64{},
+		funcs:    map[string]expr.Func{},
+		costs:    map[string]int{},
+		lastUsed: now,
+	}
+	m.sessions[id] = s
+	return s, nil
+}
+
+// Get returns the session with the given id and marks it as used.
+func (m *Manager) Get(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	now := m.now()
+	if m.expired(s, now) {
+		delete(m.sessions, id)
+		return nil, ErrNotFound
+	}
+	s.lastUsed = now
+	return s, nil
+}
+
+// Delete removes the session with the given id.
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.sessions, id)
+	if m.expired(s, m.now()) {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m *Manager) expired(s *Session, now time.Time) bool {
+	return now.Sub(s.lastUsed) >= m.ttl
+}
+
+func (m *Manager) sweep(now time.Time) {
+	for id, s := range m.sessions {
+		if m.expired(s, now) {
+			delete(m.sessions, id)
+		}
+	}
+	m.lastSweep = now
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Session holds the variables and functions defined by a client. It is
+// safe for concurrent use.
+type Session struct {
+	ID string
+
+	mu    sync.Mutex
+	vars  map[string]float64
+	funcs map[string]expr.Func
+	costs map[string]int
+	// lastUsed is guarded by the manager's mutex.
+	lastUsed time.Time
+}
+
+// Result is the outcome of executing a statement.
+type Result struct {
+	Kind expr.StatementKind
+	// Name is the variable assigned or the function defined.
+	Name string
+	// Value is the value of the expression or of the assigned variable.
+	Value float64
+}
+
+// Exec parses and executes a statement: it evaluates an expression,
+// assigns a variable or defines a function. Errors are *expr.Error values
+// describing the statement, apart from limit violations.
+func (s *Session) Exec(src string) (Result, error) {
+	st, err := expr.ParseStatement(src)
+	if err != nil {
+		return Result{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env := &expr.Env{Vars: s.vars, Funcs: s.funcs}
+	res := Result{Kind: st.Kind, Name: st.Name}
+
+	switch st.Kind {
+	case expr.ExprStatement:
+		res.Value, err = expr.Eval(st.Body, env)
+		return res, err
+	case expr.AssignStatement:
+		if _, ok := s.vars[st.Name]; !ok && len(s.vars) >= MaxVars {
+			return Result{}, fmt.Errorf("session already holds %d variables", MaxVars)
+		}
+		if res.Value, err = expr.Eval(st.Body, env); err != nil {
+			return Result{}, err
+		}
+		s.vars[st.Name] = res.Value
+		return res, nil
+	default:
+		if _, ok := s.funcs[st.Name]; !ok && len(s.funcs) >= MaxFuncs {
+			return Result{}, fmt.Errorf("session already holds %d functions", MaxFuncs)
+		}
+		cost := s.cost(st.Body)
+		if cost > MaxFuncCost {
+			return Result{}, fmt.Errorf("function %s is too expensive to evaluate", st.Name)
+		}
+		f, err := expr.Define(st.Params, st.Body, env)
+		if err != nil {
+			return Result{}, err
+		}
+		s.funcs[st.Name] = f
+		s.costs[st.Name] = cost
+		return res, nil
+	}
+}
+
+// cost returns the number of nodes evaluated for n, where each call of a
+// user function adds the cost of its body. Since functions only ever call
+// earlier definitions this is finite, but it can grow exponentially with
+// nesting, which is what MaxFuncCost guards against.
+func (s *Session) cost(n expr.Node) int {
+	cost := 0
+	expr.Walk(n, func(n expr.Node) {
+		cost++
+		if call, ok := n.(*expr.Call); ok {
+			cost += s.costs[call.Func]
+		}
+		if cost > MaxFuncCost {
+			cost = MaxFuncCost + 1
+		}
+	})
+	return cost
+}
@@ -0,0 +1,115 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/calculator/expr"
+)
+
+func TestExec(t *testing.T) {
+	m := NewManager(time.Minute, 10)
+	s, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		src  string
+		want Result
+	}{
+		{"x = 3", Result{Kind: expr.AssignStatement, Name: "x", Value: 3}},
+		{"sq(v) = v * v", Result{Kind: expr.DefineStatement, Name: "sq"}},
+		{"sq(x) + 1", Result{Kind: expr.ExprStatement, Value: 10}},
+		{"x = x + 1", Result{Kind: expr.AssignStatement, Name: "x", Value: 4}},
+		{"sq(x)", Result{Kind: expr.ExprStatement, Value: 16}},
+	}
+	for _, step := range steps {
+		got, err := s.Exec(step.src)
+		if err != nil {
+			t.Fatalf("Exec(%q): %v", step.src, err)
+		}
+		if got != step.want {
+			t.Errorf("Exec(%q) = %+v, want %+v", step.src, got, step.want)
+		}
+	}
+	if _, err := s.Exec("y + 1"); err == nil {
+		t.Error("Exec of an unknown variable succeeded")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s, err := NewManager(time.Minute, 1).Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < MaxVars; i++ {
+		if _, err := s.Exec(fmt.Sprintf("v%d = %d", i, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Exec("one_more = 1"); err == nil {
+		t.Error("assigning beyond MaxVars succeeded")
+	}
+	if _, err := s.Exec("v0 = 42"); err != nil {
+		t.Errorf("reassigning an existing variable: %v", err)
+	}
+
+	// Each function calls the previous one twice, doubling the cost.
+	if _, err := s.Exec("f0(x) = x"); err != nil {
+		t.Fatal(err)
+	}
+	var costErr error
+	for i := 1; i < 40 && costErr == nil; i++ {
+		_, costErr = s.Exec(fmt.Sprintf("f%d(x) = f%d(x) + f%d(x)", i, i-1, i-1))
+	}
+	if costErr == nil || !strings.Contains(costErr.Error(), "too expensive") {
+		t.Errorf("nested definitions error = %v, want too expensive", costErr)
+	}
+}
+
+func TestManager(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := NewManager(time.Minute, 2)
+	m.now = func() time.Time { return now }
+
+	a, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := m.Create()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID == b.ID {
+		t.Fatalf("sessions share id %q", a.ID)
+	}
+	if _, err := m.Create(); !errors.Is(err, ErrTooManySessions) {
+		t.Errorf("third Create = %v, want ErrTooManySessions", err)
+	}
+
+	now = now.Add(40 * time.Second)
+	if _, err := m.Get(a.ID); err != nil {
+		t.Errorf("Get(a): %v", err)
+	}
+	// b has now been idle for a minute, a for 20 seconds.
+	now = now.Add(20 * time.Second)
+	if _, err := m.Get(b.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(expired b) = %v, want ErrNotFound", err)
+	}
+	if _, err := m.Create(); err != nil {
+		t.Errorf("Create after expiry: %v", err)
+	}
+
+	if err := m.Delete(a.ID); err != nil {
+		t.Errorf("Delete(a): %v", err)
+	}
+	if _, err := m.Get(a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(deleted a) = %v, want ErrNotFound", err)
+	}
+	if err := m.Delete(a.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second Delete(a) = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,109 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Quantile estimates a single quantile of a stream with the P² algorithm
+// of Jain and Chlamtac, which keeps five markers instead of the samples.
+// Until five values have been added the quantile is exact.
+type Quantile struct {
+	p   float64
+	n   int
+	q   [5]float64 // marker heights
+	pos [5]float64 // marker positions
+	des [5]float64 // desired marker positions
+	inc [5]float64 // increments of the desired positions
+}
+
+// NewQuantile returns an estimator for the quantile p, which must be in
+// the open interval (0, 1).
+func NewQuantile(p float64) *Quantile {
+	return &Quantile{
+		p:   p,
+		des: [5]float64{0, 2 * p, 4 * p, 2 + 2*p, 4},
+		inc: [5]float64{0, p / 2, p, (1 + p) / 2, 1},
+	}
+}
+
+// P returns the quantile being estimated.
+func (e *Quantile) P() float64 { return e.p }
+
+// Add adds x to the stream.
+func (e *Quantile) Add(x float64) {
+	if e.n < 5 {
+		e.q[e.n] = x
+		e.n++
+		if e.n == 5 {
+			sort.Float64s(e.q[:])
+			for i := range e.pos {
+				e.pos[i] = float64(i)
+			}
+		}
+		return
+	}
+	e.n++
+
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for k = 0; x >= e.q[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.pos[i]++
+	}
+	for i := range e.des {
+		e.des[i] += e.inc[i]
+	}
+
+	for i := 1; i <= 3; i++ {
+		d := e.des[i] - e.pos[i]
+		if (d >= 1 && e.pos[i+1]-e.pos[i] > 1) || (d <= -1 && e.pos[i-1]-e.pos[i] < -1) {
+			d = math.Copysign(1, d)
+			q := e.parabolic(i, d)
+			if e.q[i-1] < q && q < e.q[i+1] {
+				e.q[i] = q
+			} else {
+				e.q[i] = e.linear(i, int(d))
+			}
+			e.pos[i] += d
+		}
+	}
+}
+
+func (e *Quantile) parabolic(i int, d float64) float64 {
+	q, n := e.q, e.pos
+	return q[i] + d/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+d)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-d)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+func (e *Quantile) linear(i, d int) float64 {
+	return e.q[i] + float64(d)*(e.q[i+d]-e.q[i])/(e.pos[i+d]-e.pos[i])
+}
+
+// Value returns the current estimate, or 0 if nothing has been added.
+func (e *Quantile) Value() float64 {
+	switch {
+	case e.n == 0:
+		return 0
+	case e.n >= 5:
+		return e.q[2]
+	}
+	s := append([]float64(nil), e.q[:e.n]...)
+	sort.Float64s(s)
+	h := e.p * float64(len(s)-1)
+	lo := math.Floor(h)
+	if int(lo)+1 >= len(s) {
+		return s[len(s)-1]
+	}
+	return s[int(lo)] + (h-lo)*(s[int(lo)+1]-s[int(lo)])
+}
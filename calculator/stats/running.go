@@ -0,0 +1,134 @@
+package stats
+
+import "time"
+
+// Aggregate selects what a Running aggregator computes.
+type Aggregate int
+
+const (
+	Max Aggregate = iota
+	Min
+	Sum
+	Mean
+)
+
+// Window limits a Running aggregator to recent values. A value stays in
+// the window while fewer than Count values have been added after it and
+// it is younger than Duration. Zero fields impose no limit, so the zero
+// Window covers all values ever added.
+type Window struct {
+	Count    int
+	Duration time.Duration
+}
+
+func (w Window) bounded() bool { return w.Count > 0 || w.Duration > 0 }
+
+// Running maintains an aggregate over a sliding window in O(1) amortized
+// time per value. Memory is proportional to the number of values in the
+// window, and constant for an unbounded window.
+type Running struct {
+	agg    Aggregate
+	window Window
+	seq    uint64
+
+	// items holds the values in the window for Sum and Mean; extremes
+	// holds the monotonic subsequence of candidates for Max and Min.
+	items    queue
+	extremes queue
+	sum      float64
+	n        int
+	best     float64
+}
+
+type entry struct {
+	x   float64
+	seq uint64
+	at  time.Time
+}
+
+// NewRunning returns an aggregator computing agg over w.
+func NewRunning(agg Aggregate, w Window) *Running {
+	return &Running{agg: agg, window: w}
+}
+
+// Add adds x, observed at now, and returns the aggregate over the window.
+// Times must not decrease between calls.
+func (r *Running) Add(x float64, now time.Time) float64 {
+	r.seq++
+	e := entry{x: x, seq: r.seq, at: now}
+
+	switch r.agg {
+	case Max, Min:
+		if !r.window.bounded() {
+			if r.seq == 1 || r.better(x, r.best) {
+				r.best = x
+			}
+			return r.best
+		}
+		// A value that is no better than a newer one can never become
+		// the extreme again, so it is dropped.
+		for r.extremes.len() > 0 && !r.better(r.extremes.back().x, x) {
+			r.extremes.popBack()
+		}
+		r.extremes.push(e)
+		r.evict(&r.extremes, now)
+		return r.extremes.front().x
+	default:
+		r.sum += x
+		r.n++
+		if r.window.bounded() {
+			r.items.push(e)
+			for r.expired(r.items.front(), now) {
+				r.sum -= r.items.popFront().x
+				r.n--
+			}
+		}
+		if r.agg == Mean {
+			return r.sum / float64(r.n)
+		}
+		return r.sum
+	}
+}
+
+func (r *Running) better(a, b float64) bool {
+	if r.agg == Max {
+		return a > b
+	}
+	return a < b
+}
+
+func (r *Running) evict(q *queue, now time.Time) {
+	for r.expired(q.front(), now) {
+		q.popFront()
+	}
+}
+
+func (r *Running) expired(e entry, now time.Time) bool {
+	if r.window.Count > 0 && e.seq+uint64(r.window.Count) <= r.seq {
+		return true
+	}
+	return r.window.Duration > 0 && now.Sub(e.at) >= r.window.Duration
+}
+
+// queue is a FIFO that can also be popped from the back. Popped slots at
+// the front are reclaimed once they make up half of the backing array.
+type queue struct {
+	buf  []entry
+	head int
+}
+
+func (q *queue) len() int       { return len(q.buf) - q.head }
+func (q *queue) front() entry   { return q.buf[q.head] }
+func (q *queue) back() entry    { return q.buf[len(q.buf)-1] }
+func (q *queue) push(e entry)   { q.buf = append(q.buf, e) }
+func (q *queue) popBack() entry { e := q.back(); q.buf = q.buf[:len(q.buf)-1]; return e }
+
+func (q *queue) popFront() entry {
+	e := q.buf[q.head]
+	q.head++
+	if q.head > 32 && q.head*2 >= len(q.buf) {
+		q.buf = append(q.buf[:0], q.buf[q.head:]...)
+		q.head = 0
+	}
+	return e
+}
@@ -0,0 +1,153 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSummary(t *testing.T) {
+	var s Summary
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		s.Add(x)
+	}
+	if s.Count() != 8 || s.Mean() != 5 || s.Min() != 2 || s.Max() != 9 {
+		t.Errorf("got count %v mean %v min %v max %v", s.Count(), s.Mean(), s.Min(), s.Max())
+	}
+	if want := 32.0 / 7; math.Abs(s.Variance()-want) > 1e-12 {
+		t.Errorf("Variance() = %v, want %v", s.Variance(), want)
+	}
+}
+
+func TestSummaryStable(t *testing.T) {
+	// A large offset ruins the naive sum of squares but not Welford.
+	var s Summary
+	for _, x := range []float64{4, 7, 13, 16} {
+		s.Add(1e9 + x)
+	}
+	if math.Abs(s.Variance()-30) > 1e-6 {
+		t.Errorf("Variance() = %v, want 30", s.Variance())
+	}
+}
+
+func TestSummaryEmpty(t *testing.T) {
+	var s Summary
+	if s.Count() != 0 || s.Mean() != 0 || s.Variance() != 0 {
+		t.Errorf("empty summary not zero: %+v", s)
+	}
+	s.Add(-3)
+	if s.Variance() != 0 || s.Min() != -3 || s.Max() != -3 {
+		t.Errorf("single value summary: %+v", s)
+	}
+}
+
+func TestQuantileSmall(t *testing.T) {
+	e := NewQuantile(0.5)
+	if e.Value() != 0 {
+		t.Errorf("empty Value() = %v", e.Value())
+	}
+	for _, x := range []float64{3, 1, 4, 2} {
+		e.Add(x)
+	}
+	if e.Value() != 2.5 {
+		t.Errorf("Value() = %v, want 2.5", e.Value())
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	dists := map[string]func() float64{
+		"uniform": r.Float64,
+		"normal":  r.NormFloat64,
+		"exp":     r.ExpFloat64,
+	}
+	for name, gen := range dists {
+		data := make([]float64, 100000)
+		for i := range data {
+			data[i] = gen()
+		}
+		for _, p := range []float64{0.1, 0.5, 0.9, 0.99} {
+			e := NewQuantile(p)
+			for _, x := range data {
+				e.Add(x)
+			}
+			sorted := append([]float64(nil), data...)
+			sort.Float64s(sorted)
+			// Compare ranks rather than values so that the tolerance
+			// does not depend on the spread of the distribution.
+			rank := float64(sort.SearchFloat64s(sorted, e.Value())) / float64(len(sorted))
+			if math.Abs(rank-p) > 0.01 {
+				t.Errorf("%s: quantile %v estimate %v has rank %v", name, p, e.Value(), rank)
+			}
+		}
+	}
+}
+
+func TestRunning(t *testing.T) {
+	base := time.Unix(0, 0)
+	tests := []struct {
+		name   string
+		agg    Aggregate
+		window Window
+		xs     []float64
+		want   []float64
+	}{
+		{"max all", Max, Window{}, []float64{-3, -5, -1, -2}, []float64{-3, -3, -1, -1}},
+		{"min all", Min, Window{}, []float64{3, 5, 1, 2}, []float64{3, 3, 1, 1}},
+		{"sum all", Sum, Window{}, []float64{1, 2, 3}, []float64{1, 3, 6}},
+		{"mean all", Mean, Window{}, []float64{1, 2, 3}, []float64{1, 1.5, 2}},
+		{"max last 3", Max, Window{Count: 3}, []float64{9, 1, 2, 3, 8, 1, 1, 1}, []float64{9, 9, 9, 3, 8, 8, 8, 1}},
+		{"min last 2", Min, Window{Count: 2}, []float64{1, 5, 4, 6, 7}, []float64{1, 1, 4, 4, 6}},
+		{"sum last 2", Sum, Window{Count: 2}, []float64{1, 2, 3, 4}, []float64{1, 3, 5, 7}},
+		{"mean last 3", Mean, Window{Count: 3}, []float64{3, 6, 9, 12}, []float64{3, 4.5, 6, 9}},
+		// Values arrive one second apart, so a 2s window holds two.
+		{"max 2s", Max, Window{Duration: 2 * time.Second}, []float64{5, 1, 0, 3}, []float64{5, 5, 1, 3}},
+		{"sum 2s", Sum, Window{Duration: 2 * time.Second}, []float64{1, 2, 3, 4}, []float64{1, 3, 5, 7}},
+		{"count and duration", Sum, Window{Count: 2, Duration: time.Hour}, []float64{1, 2, 3}, []float64{1, 3, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRunning(tt.agg, tt.window)
+			var got []float64
+			for i, x := range tt.xs {
+				got = append(got, r.Add(x, base.Add(time.Duration(i)*time.Second)))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunningMatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	const n, window = 5000, 17
+	for _, agg := range []Aggregate{Max, Min} {
+		r := NewRunning(agg, Window{Count: window})
+		var xs []float64
+		for i := 0; i < n; i++ {
+			x := float64(rng.Intn(100))
+			xs = append(xs, x)
+			got := r.Add(x, time.Time{})
+			lo := len(xs) - window
+			if lo < 0 {
+				lo = 0
+			}
+			want := xs[lo]
+			for _, y := range xs[lo:] {
+				if (agg == Max && y > want) || (agg == Min && y < want) {
+					want = y
+				}
+			}
+			if got != want {
+				t.Fatalf("aggregate %v step %d: got %v, want %v", agg, i, got, want)
+			}
+		}
+		if len(r.extremes.buf) > 2*window+64 {
+			t.Errorf("aggregate %v kept %d entries for a window of %d", agg, len(r.extremes.buf), window)
+		}
+	}
+}
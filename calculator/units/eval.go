@@ -0,0 +1,220 @@
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"example.com/calculator/expr"
+)
+
+// maxExponent bounds the integer powers of quantities with a dimension.
+const maxExponent = 64
+
+func errorf(n expr.Node, format string, args ...interface{}) *expr.Error {
+	return &expr.Error{Pos: n.Offset() + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// ParseUnit parses a unit such as "km", "GiB/s", "kg*m/s^2" or "degC".
+func ParseUnit(s string) (Unit, error) {
+	s = strings.TrimSpace(s)
+	if u, ok := Units[s]; ok {
+		return u, nil
+	}
+	if s == "" {
+		return Unit{}, fmt.Errorf("missing unit")
+	}
+	n, err := expr.Parse(s, expr.ImplicitMultiplication())
+	if err != nil {
+		return Unit{}, fmt.Errorf("unit %q: %v", s, err)
+	}
+	q, err := (&evaluator{unitsOnly: true}).eval(n)
+	if err != nil {
+		return Unit{}, fmt.Errorf("unit %q: %v", s, err)
+	}
+	if q.Value <= 0 {
+		return Unit{}, fmt.Errorf("unit %q is not positive", s)
+	}
+	return Unit{Factor: q.Value, Dim: q.Dim}, nil
+}
+
+// Evaluate parses and evaluates an expression with units, e.g.
+// "10 GiB/s * 1 day" or "5 km + 300 m". Identifiers are units or the
+// constants of the expr package. The temperature scales degC and degF can
+// only be used as in "-40 degC", for the whole expression.
+func Evaluate(src string) (Quantity, error) {
+	n, err := expr.Parse(src, expr.ImplicitMultiplication())
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Eval(n)
+}
+
+// Eval evaluates a parsed expression with units.
+func Eval(n expr.Node) (Quantity, error) {
+	ev := &evaluator{}
+	if b, ok := n.(*expr.Binary); ok && b.Op == '*' {
+		if id, ok := b.Y.(*expr.Ident); ok && Units[id.Name].affine() {
+			x, err := ev.eval(b.X)
+			if err != nil {
+				return Quantity{}, err
+			}
+			if !x.Dim.Dimensionless() {
+				return Quantity{}, errorf(b, "%s must follow a plain number", id.Name)
+			}
+			u := Units[id.Name]
+			return Quantity{Value: u.Factor*x.Value + u.Offset, Dim: u.Dim}, nil
+		}
+	}
+	return ev.eval(n)
+}
+
+type evaluator struct {
+	// unitsOnly rejects the constants, for parsing units.
+	unitsOnly bool
+}
+
+func (ev *evaluator) eval(n expr.Node) (Quantity, error) {
+	switch n := n.(type) {
+	case *expr.Number:
+		return Quantity{Value: n.Value}, nil
+	case *expr.Ident:
+		if u, ok := Units[n.Name]; ok {
+			if u.affine() {
+				return Quantity{}, errorf(n, "%s is a temperature scale, use K for arithmetic on temperatures", n.Name)
+			}
+			return Quantity{Value: u.Factor, Dim: u.Dim}, nil
+		}
+		if v, ok := expr.Constants[n.Name]; ok && !ev.unitsOnly {
+			return Quantity{Value: v}, nil
+		}
+		return Quantity{}, errorf(n, "unknown unit %q", n.Name)
+	case *expr.Unary:
+		x, err := ev.eval(n.X)
+		if n.Op == '-' {
+			x.Value = -x.Value
+		}
+		return x, err
+	case *expr.Binary:
+		x, err := ev.eval(n.X)
+		if err != nil {
+			return Quantity{}, err
+		}
+		y, err := ev.eval(n.Y)
+		if err != nil {
+			return Quantity{}, err
+		}
+		return binary(n, x, y)
+	case *expr.Call:
+		if ev.unitsOnly {
+			return Quantity{}, errorf(n, "unexpected function %s in unit", n.Func)
+		}
+		args := make([]Quantity, len(n.Args))
+		for i, arg := range n.Args {
+			q, err := ev.eval(arg)
+			if err != nil {
+				return Quantity{}, err
+			}
+			args[i] = q
+		}
+		return call(n, args)
+	}
+	return Quantity{}, errorf(n, "unsupported expression")
+}
+
+func binary(n *expr.Binary, x, y Quantity) (Quantity, error) {
+	var q Quantity
+	switch n.Op {
+	case '+', '-', '%':
+		if x.Dim != y.Dim {
+			return Quantity{}, errorf(n, "%q of %s and %s", n.Op, describe(x.Dim), describe(y.Dim))
+		}
+		q.Dim = x.Dim
+		switch n.Op {
+		case '+':
+			q.Value = x.Value + y.Value
+		case '-':
+			q.Value = x.Value - y.Value
+		default:
+			if y.Value == 0 {
+				return Quantity{}, errorf(n, "division by zero")
+			}
+			q.Value = math.Mod(x.Value, y.Value)
+		}
+	case '*':
+		q = Quantity{Value: x.Value * y.Value, Dim: x.Dim.add(y.Dim, 1)}
+	case '/':
+		if y.Value == 0 {
+			return Quantity{}, errorf(n, "division by zero")
+		}
+		q = Quantity{Value: x.Value / y.Value, Dim: x.Dim.add(y.Dim, -1)}
+	case '^':
+		if !y.Dim.Dimensionless() {
+			return Quantity{}, errorf(n, "exponent has dimension %s", y.Dim)
+		}
+		q.Value = math.Pow(x.Value, y.Value)
+		if !x.Dim.Dimensionless() {
+			k := y.Value
+			if k != math.Trunc(k) || math.Abs(k) > maxExponent {
+				return Quantity{}, errorf(n, "%s can only be raised to integer powers up to %d", x.Dim, maxExponent)
+			}
+			q.Dim = x.Dim.scale(int(k))
+		}
+	default:
+		return Quantity{}, errorf(n, "unknown operator %q", n.Op)
+	}
+	return checked(n, q)
+}
+
+func call(n *expr.Call, args []Quantity) (Quantity, error) {
+	f, ok := expr.Builtins[n.Func]
+	if !ok {
+		return Quantity{}, errorf(n, "unknown function %q", n.Func)
+	}
+	if len(args) < f.MinArgs || (f.MaxArgs >= 0 && len(args) > f.MaxArgs) {
+		return Quantity{}, errorf(n, "wrong number of arguments for %s", n.Func)
+	}
+	values := make([]float64, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	var d Dim
+	switch n.Func {
+	case "abs", "min", "max", "floor", "ceil", "round":
+		// Rounding happens in base units.
+		d = args[0].Dim
+		for _, a := range args[1:] {
+			if a.Dim != d {
+				return Quantity{}, errorf(n, "%s of %s and %s", n.Func, describe(d), describe(a.Dim))
+			}
+		}
+	case "sqrt":
+		for i, e := range args[0].Dim {
+			if e%2 != 0 {
+				return Quantity{}, errorf(n, "square root of %s", args[0].Dim)
+			}
+			d[i] = e / 2
+		}
+	default:
+		for _, a := range args {
+			if !a.Dim.Dimensionless() {
+				return Quantity{}, errorf(n, "%s expects dimensionless arguments, got %s", n.Func, a.Dim)
+			}
+		}
+	}
+	v, err := f.Fn(values)
+	if err != nil {
+		return Quantity{}, errorf(n, "%s: %v", n.Func, err)
+	}
+	return checked(n, Quantity{Value: v, Dim: d})
+}
+
+func checked(n expr.Node, q Quantity) (Quantity, error) {
+	if math.IsNaN(q.Value) {
+		return Quantity{}, errorf(n, "result is not a real number")
+	}
+	if math.IsInf(q.Value, 0) {
+		return Quantity{}, errorf(n, "result is out of range")
+	}
+	return q, nil
+}
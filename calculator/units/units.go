@@ -0,0 +1,220 @@
+// Package units evaluates expressions whose values carry physical units,
+// such as "10 GiB/s * 1 day", and converts between compatible units.
+package units
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Base dimensions. Quantities are stored in the matching base unit: metre,
+// kilogram, second, byte and kelvin.
+const (
+	Length = iota
+	Mass
+	Time
+	Data
+	Temperature
+	numDims
+)
+
+var baseSymbols = [numDims]string{"m", "kg", "s", "B", "K"}
+
+// Dim holds the exponent of each base dimension.
+type Dim [numDims]int
+
+// Dimensionless reports whether all exponents are zero.
+func (d Dim) Dimensionless() bool { return d == Dim{} }
+
+func (d Dim) add(e Dim, sign int) Dim {
+	for i := range d {
+		d[i] += sign * e[i]
+	}
+	return d
+}
+
+func (d Dim) scale(k int) Dim {
+	for i := range d {
+		d[i] *= k
+	}
+	return d
+}
+
+// String formats d in base units, e.g. "m/s^2" or "1/s".
+func (d Dim) String() string {
+	var num, den []string
+	for i, e := range d {
+		switch {
+		case e > 0:
+			num = append(num, power(baseSymbols[i], e))
+		case e < 0:
+			den = append(den, power(baseSymbols[i], -e))
+		}
+	}
+	s := strings.Join(num, "*")
+	if len(den) > 0 {
+		if s == "" {
+			s = "1"
+		}
+		s += "/" + strings.Join(den, "/")
+	}
+	return s
+}
+
+func power(sym string, e int) string {
+	if e == 1 {
+		return sym
+	}
+	return fmt.Sprintf("%s^%d", sym, e)
+}
+
+// Quantity is a value in base units with its dimension.
+type Quantity struct {
+	Value float64
+	Dim   Dim
+}
+
+// String formats q in base units, e.g. "9.81 m/s^2".
+func (q Quantity) String() string {
+	if q.Dim.Dimensionless() {
+		return fmt.Sprint(q.Value)
+	}
+	return fmt.Sprintf("%v %v", q.Value, q.Dim)
+}
+
+// Unit is a named unit: a value in unit u is Factor*value + Offset in base
+// units. Offset is only non-zero for the affine temperature scales.
+type Unit struct {
+	Factor, Offset float64
+	Dim            Dim
+}
+
+func (u Unit) affine() bool { return u.Offset != 0 }
+
+func dim(i int) Dim {
+	var d Dim
+	d[i] = 1
+	return d
+}
+
+var siPrefixes = map[string]float64{
+	"E": 1e18, "P": 1e15, "T": 1e12, "G": 1e9, "M": 1e6, "k": 1e3,
+	"c": 1e-2, "m": 1e-3, "u": 1e-6, "µ": 1e-6, "n": 1e-9,
+}
+
+var binaryPrefixes = map[string]float64{
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+}
+
+// Units lists every unit name known to expressions.
+var Units = buildUnits()
+
+func buildUnits() map[string]Unit {
+	u := map[string]Unit{}
+	add := func(u0 Unit, names ...string) {
+		for _, n := range names {
+			u[n] = u0
+		}
+	}
+	prefixed := func(sym string, base Unit, prefixes map[string]float64) {
+		for p, f := range prefixes {
+			u[p+sym] = Unit{Factor: base.Factor * f, Dim: base.Dim}
+		}
+		u[sym] = base
+	}
+
+	metre := Unit{Factor: 1, Dim: dim(Length)}
+	prefixed("m", metre, siPrefixes)
+	add(metre, "meter", "meters", "metre", "metres")
+	add(Unit{Factor: 1e3, Dim: dim(Length)}, "kilometer", "kilometers", "kilometre", "kilometres")
+	add(Unit{Factor: 0.0254, Dim: dim(Length)}, "in", "inch", "inches")
+	add(Unit{Factor: 0.3048, Dim: dim(Length)}, "ft", "foot", "feet")
+	add(Unit{Factor: 0.9144, Dim: dim(Length)}, "yd", "yard", "yards")
+	add(Unit{Factor: 1609.344, Dim: dim(Length)}, "mi", "mile", "miles")
+	add(Unit{Factor: 1852, Dim: dim(Length)}, "nmi")
+
+	gram := Unit{Factor: 1e-3, Dim: dim(Mass)}
+	prefixed("g", gram, siPrefixes)
+	add(gram, "gram", "grams")
+	add(Unit{Factor: 1, Dim: dim(Mass)}, "kilogram", "kilograms")
+	add(Unit{Factor: 1e3, Dim: dim(Mass)}, "t", "tonne", "tonnes")
+	add(Unit{Factor: 0.45359237, Dim: dim(Mass)}, "lb", "lbs", "pound", "pounds")
+	add(Unit{Factor: 0.028349523125, Dim: dim(Mass)}, "oz", "ounce", "ounces")
+
+	second := Unit{Factor: 1, Dim: dim(Time)}
+	prefixed("s", second, map[string]float64{"m": 1e-3, "u": 1e-6, "µ": 1e-6, "n": 1e-9})
+	add(second, "sec", "second", "seconds")
+	add(Unit{Factor: 60, Dim: dim(Time)}, "min", "minute", "minutes")
+	add(Unit{Factor: 3600, Dim: dim(Time)}, "h", "hr", "hour", "hours")
+	add(Unit{Factor: 86400, Dim: dim(Time)}, "d", "day", "days")
+	add(Unit{Factor: 7 * 86400, Dim: dim(Time)}, "wk", "week", "weeks")
+	// Julian year
+	add(Unit{Factor: 365.25 * 86400, Dim: dim(Time)}, "yr", "year", "years")
+
+	byte_ := Unit{Factor: 1, Dim: dim(Data)}
+	prefixed("B", byte_, siPrefixes)
+	prefixed("B", byte_, binaryPrefixes)
+	add(byte_, "byte", "bytes")
+	bit := Unit{Factor: 0.125, Dim: dim(Data)}
+	prefixed("bit", bit, siPrefixes)
+	prefixed("bit", bit, binaryPrefixes)
+	add(bit, "bits")
+	// network rates are often written Mb or Gb
+	for _, p := range []string{"k", "M", "G", "T"} {
+		u[p+"b"] = u[p+"bit"]
+	}
+	// The SI prefixes below one make no sense for data.
+	for _, n := range []string{"cB", "mB", "uB", "µB", "nB", "cbit", "mbit", "ubit", "µbit", "nbit"} {
+		delete(u, n)
+	}
+
+	add(Unit{Factor: 1, Dim: dim(Temperature)}, "K", "kelvin")
+	add(Unit{Factor: 1, Offset: 273.15, Dim: dim(Temperature)}, "degC", "celsius")
+	add(Unit{Factor: 5.0 / 9, Offset: 459.67 * 5 / 9, Dim: dim(Temperature)}, "degF", "fahrenheit")
+	return u
+}
+
+// IncompatibleError reports a conversion or operation between quantities
+// of different dimensions.
+type IncompatibleError struct {
+	From, To Dim
+}
+
+func (e *IncompatibleError) Error() string {
+	return fmt.Sprintf("cannot convert %s to %s", describe(e.From), describe(e.To))
+}
+
+func describe(d Dim) string {
+	if d.Dimensionless() {
+		return "a dimensionless number"
+	}
+	return d.String()
+}
+
+// Convert converts value from one unit to another, e.g. Convert(1, "GiB/s",
+// "TB/day"). Units are products and quotients of unit names and powers;
+// the temperature scales degC and degF must stand alone.
+func Convert(value float64, from, to string) (float64, error) {
+	f, err := ParseUnit(from)
+	if err != nil {
+		return 0, err
+	}
+	t, err := ParseUnit(to)
+	if err != nil {
+		return 0, err
+	}
+	return In(Quantity{Value: f.Factor*value + f.Offset, Dim: f.Dim}, t)
+}
+
+// In returns q expressed in unit u.
+func In(q Quantity, u Unit) (float64, error) {
+	if q.Dim != u.Dim {
+		return 0, &IncompatibleError{From: q.Dim, To: u.Dim}
+	}
+	v := (q.Value - u.Offset) / u.Factor
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("result is out of range")
+	}
+	return v, nil
+}
@@ -0,0 +1,113 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func close(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value    float64
+		from, to string
+		want     float64
+	}{
+		{1, "km", "m", 1000},
+		{1, "mi", "ft", 5280},
+		{1, "GiB", "MiB", 1024},
+		{1, "GiB/s", "TB/day", 1 << 30 * 86400 / 1e12},
+		{100, "Mbit/s", "MB/s", 12.5},
+		{1, "Gb", "Mbit", 1000},
+		{2, "lb", "kg", 0.90718474},
+		{90, "min", "h", 1.5},
+		{1, "yr", "days", 365.25},
+		{100, "km/h", "m/s", 100 / 3.6},
+		{9.81, "m/s^2", "ft/s^2", 9.81 / 0.3048},
+		{1, "kg*m^2/s^2", "g*cm^2/s^2", 1e7},
+		{1, "1/ms", "1/s", 1000},
+		{0, "degC", "K", 273.15},
+		{-40, "degC", "degF", -40},
+		{212, "degF", "degC", 100},
+		{0, "K", "degF", -459.67},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("Convert(%v, %q, %q): %v", tt.value, tt.from, tt.to, err)
+			continue
+		}
+		if !close(got, tt.want) && math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Convert(%v, %q, %q) = %v, want %v", tt.value, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	var incompatible *IncompatibleError
+	if _, err := Convert(1, "km", "kg"); !errors.As(err, &incompatible) {
+		t.Errorf("Convert km to kg error = %v, want IncompatibleError", err)
+	}
+	if _, err := Convert(1, "GB/s", "GB"); !errors.As(err, &incompatible) {
+		t.Errorf("Convert GB/s to GB error = %v, want IncompatibleError", err)
+	}
+	for _, u := range []string{"", "furlong", "degC/s", "m^x", "-m", "sin(m)", "pi*m"} {
+		if _, err := Convert(1, u, "m"); err == nil {
+			t.Errorf("Convert from %q succeeded", u)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		src  string
+		want Quantity
+	}{
+		{"5 km + 300 m", Quantity{5300, dim(Length)}},
+		{"10 GiB/s * 1 day", Quantity{10 * (1 << 30) * 86400, dim(Data)}},
+		{"(3 m)^2", Quantity{9, Dim{Length: 2}}},
+		{"sqrt(16 m^2)", Quantity{4, dim(Length)}},
+		{"2 pi", Quantity{2 * math.Pi, Dim{}}},
+		{"1 km / 1 m", Quantity{1000, Dim{}}},
+		{"max(1 m, 2 ft)", Quantity{1, dim(Length)}},
+		{"20 degC", Quantity{293.15, dim(Temperature)}},
+		{"-40 degF", Quantity{233.15, dim(Temperature)}},
+		{"1 / 4 s", Quantity{0.25, Dim{Time: -1}}},
+		{"100 km / 2 h", Quantity{100000.0 / 7200, Dim{Length: 1, Time: -1}}},
+		{"1 / (4 s)", Quantity{0.25, Dim{Time: -1}}},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.src)
+		if err != nil {
+			t.Errorf("Evaluate(%q): %v", tt.src, err)
+			continue
+		}
+		if got.Dim != tt.want.Dim || !close(got.Value, tt.want.Value) {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []string{"1 m + 1 s", "2 ^ (1 m)", "(1 m) ^ 0.5", "sin(1 m)", "20 degC + 1 K", "1 m / 0", "3 parsecs"} {
+		if _, err := Evaluate(src); err == nil {
+			t.Errorf("Evaluate(%q) succeeded", src)
+		}
+	}
+}
+
+func TestDimString(t *testing.T) {
+	tests := map[Dim]string{
+		{}:                             "",
+		{Length: 1, Time: -2}:          "m/s^2",
+		{Time: -1}:                     "1/s",
+		{Mass: 1, Length: 2, Time: -2}: "m^2*kg/s^2",
+		{Data: 1, Time: -1}:            "B/s",
+	}
+	for d, want := range tests {
+		if got := d.String(); got != want {
+			t.Errorf("%v.String() = %q, want %q", [numDims]int(d), got, want)
+		}
+	}
+}
@@ -5,4 +5,4 @@ protoc greet/greetpb/greet.proto --go_out=plugins=grpc:.
 protoc calculator/calculatorpb/calculator.proto --go_out=plugins=grpc:.
 
 #blog
-protoc blog/blogpb/blog.proto --go_out=plugins=grpc:.
+protoc -I . -I third_party/googleapis blog/blogpb/blog.proto --go_out=plugins=grpc:. --grpc-gateway_out=.
@@ -0,0 +1,218 @@
+// Package catalog holds the localized greetings of the greet service.
+//
+// A catalog is a set of JSON files named after the BCP 47 tag of their
+// locale, such as "de-AT.json":
+//
+//	{
+//	    "informal": "Servus {first}!",
+//	    "formal": "Grüß Gott, {first} {last}.",
+//	    "fallback": "de"
+//	}
+//
+// Templates may use {first} and {last} for the parts of the name. A locale
+// may leave out either template, which is then taken from its fallback
+// chain: the fallback locale if given, the parent locales (de-AT, de) and
+// finally the default locale, which must define both.
+package catalog
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLocale is the locale used when no requested locale is available.
+const DefaultLocale = "en"
+
+// Style selects the variant of a greeting.
+type Style int
+
+const (
+	Informal Style = iota
+	// Formal greetings use the last name; they fall back to informal ones
+	// when it is missing.
+	Formal
+)
+
+type entry struct {
+	Informal string `json:"informal"`
+	Formal   string `json:"formal"`
+	Fallback string `json:"fallback"`
+}
+
+func (e entry) template(s Style) string {
+	if s == Formal {
+		return e.Formal
+	}
+	return e.Informal
+}
+
+// Catalog maps locales to greeting templates. It is immutable once loaded
+// and safe for concurrent use.
+type Catalog struct {
+	entries map[string]entry
+	// locales lists the locales of entries, DefaultLocale first, in the
+	// order matcher was built with.
+	locales []string
+	matcher language.Matcher
+}
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+// Default returns the catalog built into the binary.
+func Default() *Catalog { return defaultCatalog }
+
+var defaultCatalog = mustLoadBuiltin()
+
+func mustLoadBuiltin() *Catalog {
+	sub, err := fs.Sub(builtin, "locales")
+	if err != nil {
+		panic(err)
+	}
+	c, err := Load(sub)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// LoadDir loads the catalog files in dir.
+func LoadDir(dir string) (*Catalog, error) {
+	return Load(os.DirFS(dir))
+}
+
+var placeholder = regexp.MustCompile(`\{[^}]*\}`)
+
+// Load loads the *.json files at the root of fsys.
+func Load(fsys fs.FS) (*Catalog, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{entries: map[string]entry{}}
+	for _, name := range names {
+		tag, err := language.Parse(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			return nil, fmt.Errorf("catalog file %s: invalid locale: %v", name, err)
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var e entry
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&e); err != nil {
+			return nil, fmt.Errorf("catalog file %s: %v", name, err)
+		}
+		for _, tmpl := range []string{e.Informal, e.Formal} {
+			for _, p := range placeholder.FindAllString(tmpl, -1) {
+				if p != "{first}" && p != "{last}" {
+					return nil, fmt.Errorf("catalog file %s: unknown placeholder %s", name, p)
+				}
+			}
+		}
+		if e.Fallback != "" {
+			fb, err := language.Parse(e.Fallback)
+			if err != nil {
+				return nil, fmt.Errorf("catalog file %s: invalid fallback: %v", name, err)
+			}
+			e.Fallback = fb.String()
+		}
+		locale := tag.String()
+		if _, dup := c.entries[locale]; dup {
+			return nil, fmt.Errorf("catalog file %s: locale %s defined twice", name, locale)
+		}
+		c.entries[locale] = e
+	}
+	def, ok := c.entries[DefaultLocale]
+	if !ok || def.Informal == "" || def.Formal == "" {
+		return nil, fmt.Errorf("catalog must define both greetings for %s", DefaultLocale)
+	}
+	for locale, e := range c.entries {
+		if _, ok := c.entries[e.Fallback]; e.Fallback != "" && !ok {
+			return nil, fmt.Errorf("catalog locale %s falls back to missing locale %s", locale, e.Fallback)
+		}
+	}
+	// the matcher falls back to its first tag
+	c.locales = []string{DefaultLocale}
+	for _, locale := range c.Locales() {
+		if locale != DefaultLocale {
+			c.locales = append(c.locales, locale)
+		}
+	}
+	tags := make([]language.Tag, len(c.locales))
+	for i, locale := range c.locales {
+		tags[i] = language.Make(locale)
+	}
+	c.matcher = language.NewMatcher(tags)
+	return c, nil
+}
+
+// Locales returns the locales of the catalog in sorted order.
+func (c *Catalog) Locales() []string {
+	ls := make([]string, 0, len(c.entries))
+	for l := range c.entries {
+		ls = append(ls, l)
+	}
+	sort.Strings(ls)
+	return ls
+}
+
+// Match returns the catalog locale that best serves the preferred
+// locales, given in decreasing order of preference, as chosen by a
+// language.Matcher. Besides parents, so de-CH matches de, this matches
+// locales of the same language in another script or region, so zh-TW
+// matches zh when the catalog has no zh-Hant. The default locale is
+// returned if nothing matches.
+func (c *Catalog) Match(preferred ...language.Tag) string {
+	_, i, _ := c.matcher.Match(preferred...)
+	return c.locales[i]
+}
+
+// Greet formats the greeting in style for locale, which should come from
+// Match.
+func (c *Catalog) Greet(locale string, style Style, first, last string) string {
+	if style == Formal && strings.TrimSpace(last) == "" {
+		style = Informal
+	}
+	r := strings.NewReplacer("{first}", first, "{last}", last)
+	return r.Replace(c.template(locale, style))
+}
+
+// template follows the fallback chain of locale to a template for style.
+func (c *Catalog) template(locale string, style Style) string {
+	seen := map[string]bool{}
+	for locale != "" && !seen[locale] {
+		seen[locale] = true
+		e, ok := c.entries[locale]
+		if ok {
+			if t := e.template(style); t != "" {
+				return t
+			}
+			if e.Fallback != "" {
+				locale = e.Fallback
+				continue
+			}
+		}
+		tag, err := language.Parse(locale)
+		if err != nil || tag.IsRoot() {
+			break
+		}
+		if parent := tag.Parent(); !parent.IsRoot() {
+			locale = parent.String()
+			continue
+		}
+		break
+	}
+	return c.entries[DefaultLocale].template(style)
+}
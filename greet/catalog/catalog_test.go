@@ -0,0 +1,101 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/text/language"
+)
+
+func TestMatch(t *testing.T) {
+	c := Default()
+	tests := []struct {
+		preferred []string
+		want      string
+	}{
+		{nil, "en"},
+		{[]string{"de"}, "de"},
+		{[]string{"de-AT"}, "de-AT"},
+		{[]string{"de-CH"}, "de"},
+		{[]string{"pt-BR"}, "pt-BR"},
+		{[]string{"pt-AO"}, "pt"},
+		// en-AU is closer to en-GB than to American English
+		{[]string{"en-AU"}, "en-GB"},
+		{[]string{"en-US"}, "en"},
+		{[]string{"zh-CN"}, "zh"},
+		// the catalog has no Traditional Chinese, zh stands in for it
+		{[]string{"zh-TW"}, "zh"},
+		{[]string{"zh-HK"}, "zh"},
+		{[]string{"zh-Hant"}, "zh"},
+		{[]string{"sr-Latn"}, "en"},
+		{[]string{"sw", "fr-CA", "de"}, "fr"},
+		{[]string{"sw"}, "en"},
+	}
+	for _, tt := range tests {
+		var tags []language.Tag
+		for _, p := range tt.preferred {
+			tags = append(tags, language.MustParse(p))
+		}
+		if got := c.Match(tags...); got != tt.want {
+			t.Errorf("Match(%v) = %q, want %q", tt.preferred, got, tt.want)
+		}
+	}
+}
+
+func TestGreet(t *testing.T) {
+	c := Default()
+	tests := []struct {
+		locale string
+		style  Style
+		last   string
+		want   string
+	}{
+		{"en", Informal, "Poonia", "hello Rahul"},
+		{"en", Formal, "Poonia", "Good day, Rahul Poonia."},
+		{"en", Formal, "", "hello Rahul"},
+		{"de", Formal, "Poonia", "Guten Tag, Rahul Poonia."},
+		{"ja", Formal, "Poonia", "Poonia様、こんにちは。"},
+		// pt-BR has no formal greeting and falls back to pt.
+		{"pt-BR", Informal, "Poonia", "Oi, Rahul!"},
+		{"pt-BR", Formal, "Poonia", "Bom dia, Rahul Poonia."},
+		// en-GB has no informal greeting; its parent en has.
+		{"en-GB", Informal, "Poonia", "hello Rahul"},
+		{"en-GB", Formal, "Poonia", "Good morning, Rahul Poonia."},
+	}
+	for _, tt := range tests {
+		if got := c.Greet(tt.locale, tt.style, "Rahul", tt.last); got != tt.want {
+			t.Errorf("Greet(%q, %v, last %q) = %q, want %q", tt.locale, tt.style, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	en := &fstest.MapFile{Data: []byte(`{"informal": "hi {first}", "formal": "Dear {first} {last}"}`)}
+	c, err := Load(fstest.MapFS{
+		"en.json":    en,
+		"en_us.json": &fstest.MapFile{Data: []byte(`{"informal": "hey {first}"}`)},
+		"README.md":  &fstest.MapFile{Data: []byte("not a catalog file")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(c.Locales(), ","); got != "en,en-US" {
+		t.Errorf("Locales() = %s, want en,en-US", got)
+	}
+
+	bad := map[string]fstest.MapFS{
+		"no default":          {"de.json": en},
+		"incomplete default":  {"en.json": &fstest.MapFile{Data: []byte(`{"informal": "hi"}`)}},
+		"invalid json":        {"en.json": en, "de.json": &fstest.MapFile{Data: []byte(`{`)}},
+		"unknown field":       {"en.json": en, "de.json": &fstest.MapFile{Data: []byte(`{"informel": "hallo"}`)}},
+		"unknown placeholder": {"en.json": en, "de.json": &fstest.MapFile{Data: []byte(`{"informal": "hallo {name}"}`)}},
+		"invalid locale":      {"en.json": en, "not a locale.json": en},
+		"missing fallback":    {"en.json": en, "de.json": &fstest.MapFile{Data: []byte(`{"fallback": "fr"}`)}},
+	}
+	for name, fsys := range bad {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: Load succeeded", name)
+		}
+	}
+}
@@ -2,19 +2,27 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"time"
 
 	"example.com/greet/greetpb"
+	"example.com/internal/dial"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 )
 
+var (
+	addr          = flag.String("addr", "localhost:50051", "server address, or a file:///path or dnssrv:///name target listing several replicas")
+	serviceConfig = flag.String("service-config", "", "JSON file with the retry, hedging and load balancing policies of the connection (default built-in policies)")
+)
+
 func main() {
+	flag.Parse()
 
 	fmt.Println("welcome to greet client")
 	certFile := "ssl/ca.crt"
@@ -23,7 +31,11 @@ func main() {
 		log.Fatalf("Failed to load ca trust certificate: %v", sslErr)
 	}
 	opts := grpc.WithTransportCredentials(creds)
-	conn, err := grpc.Dial("localhost:50051", opts)
+	config, err := dial.Load(*serviceConfig)
+	if err != nil {
+		log.Fatalf("failed to load service config: %v", err)
+	}
+	conn, err := dial.Dial(*addr, config, opts)
 
 	if err != nil {
 		log.Fatalf("could not connect : %v", err)
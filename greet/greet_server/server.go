@@ -1,128 +1,113 @@
 package main
 
 import (
-	"context"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net"
-	"strconv"
-	"time"
+	"net/http"
+	"strings"
 
+	"example.com/greet/catalog"
 	"example.com/greet/greetpb"
+	"example.com/greet/greetservice"
+	"example.com/greet/room"
+	"example.com/internal/admission"
+	"example.com/internal/auth"
+	"example.com/internal/deadline"
+	"example.com/internal/httpserve"
+	"example.com/internal/ratelimit"
+	"example.com/internal/recovery"
+	"example.com/internal/serverconfig"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-type server struct {
-}
-
-func (*server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
-	fmt.Println("greet function is invoked:", req)
-	firstName := req.GetGreeting().GetFirstName()
-	result := "hello " + firstName
-	res := &greetpb.GreetResponse{
-		Result: result,
-	}
-	return res, nil
-}
-
-func (*server) GreetMAnyTimes(req *greetpb.GreetManyTimesRequest, stream greetpb.GreetService_GreetMAnyTimesServer) error {
-	firstName := req.GetGreeting().GetFirstName()
-	for i := 0; i < 10; i++ {
-		result := "hello " + firstName + " number " + strconv.Itoa(i)
-		res := &greetpb.GreetManyTimesResponse{
-			Result: result,
-		}
-		stream.Send(res)
-		time.Sleep(1000 * time.Millisecond)
-	}
-	return nil
-}
-
-func (*server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
-	fmt.Println("greet function is invoked with a streaming request")
-	result := ""
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			//porcessing finish
-			return stream.SendAndClose(
-				&greetpb.LongGreetResponse{
-					Result: result,
-				},
-			)
+var (
+	addr           = flag.String("addr", "0.0.0.0:50051", "address the server listens on")
+	corsOrigins    = flag.String("cors-origins", "", "comma separated origins allowed to call the server from a browser, \"*\" for any; none when empty")
+	catalogDir     = flag.String("catalog-dir", "", "directory of greeting catalog files, the built-in catalog when empty")
+	roomBuffer     = flag.Int("room-buffer", 64, "events buffered for each GreetEveryone room member")
+	slowMembers    = flag.String("slow-members", "drop", "what happens to room members whose buffer is full: drop (their oldest events) or disconnect")
+	configFile     = flag.String("config", "", "JSON server config file, reloaded on SIGHUP; see internal/serverconfig")
+	maxConcurrency = flag.Int("max-concurrency", 1000, "upper bound of the adaptive limit on concurrent calls; calls over the limit fail with Unavailable")
 
-		}
-		if err != nil {
-			log.Fatalf("Error while reading client stream %v", err)
-			return err
-		}
+	budgets deadline.Budgets
+)
 
-		firstName := req.GetGreeting().GetFirstName()
-		result += "Hello " + firstName + " ! "
+func main() {
+	flag.Var(&budgets, "min-budget", "comma separated /package.Service/Method=duration minimum deadlines; shorter calls are rejected")
+	flag.Parse()
+	fmt.Println("welcome to greet server")
+	certFile := "ssl/server.crt"
+	keyFile := "ssl/server.pem"
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("failed to listen %v", err)
 	}
-}
-
-func (*server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) error {
-	fmt.Println("GreetEveryone function is invoked with a streaming request")
-	for {
-		req, err := stream.Recv()
-		if err == io.EOF {
-			return nil
-		}
+	greetings := catalog.Default()
+	if *catalogDir != "" {
+		greetings, err = catalog.LoadDir(*catalogDir)
 		if err != nil {
-			log.Fatalf("Error while reading client stream %v", err)
-			return err
-		}
-		firstName := req.GetGreeting().GetFirstName()
-		result := "Hello " + firstName + " ! "
-		err = stream.Send(&greetpb.GreetEveryoneResponse{
-			Result: result,
-		})
-		if err != nil {
-			log.Fatalf("Error while sending data to  client: %v", err)
-			return err
+			log.Fatalf("failed to load greeting catalog: %v", err)
 		}
 	}
-}
-
-func (*server) GreetWithDeadline(ctx context.Context, req *greetpb.GreetWithDeadlineRequest) (*greetpb.GreetWithDeadlineResponse, error) {
-	fmt.Println("GreetWithDeadline function is invoked: \n", req)
-	for i := 0; i < 3; i++ {
-		if ctx.Err() == context.Canceled {
-			fmt.Println("client canceled the request !!")
-			return nil, status.Error(codes.Canceled, " the client canceled the request")
-		}
-		time.Sleep(1 * time.Second)
+	fmt.Println("greeting locales:", strings.Join(greetings.Locales(), " "))
+	var policy room.Policy
+	switch *slowMembers {
+	case "drop":
+		policy = room.DropOldest
+	case "disconnect":
+		policy = room.Disconnect
+	default:
+		log.Fatalf("unknown -slow-members policy %q", *slowMembers)
 	}
-	firstName := req.GetGreeting().GetFirstName()
-	result := "hello " + firstName
-	res := &greetpb.GreetWithDeadlineResponse{
-		Result: result,
+	config, err := serverconfig.Load(*configFile)
+	if err != nil {
+		log.Fatalf("failed to load server config: %v", err)
 	}
-	return res, nil
-}
+	callers := auth.New(config.Callers)
+	limiter := ratelimit.New(&config.RateLimits, ratelimit.Options{Authenticator: callers.Identity})
+	serverconfig.ReloadOnHangup(*configFile, func(c *serverconfig.Config) {
+		callers.SetCallers(c.Callers)
+		limiter.SetConfig(&c.RateLimits)
+	})
+	shedder := admission.New(admission.Options{MaxLimit: *maxConcurrency, Admin: callers.Admin})
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			recovery.UnaryServerInterceptor(),
+			deadline.UnaryServerInterceptor(budgets),
+			limiter.UnaryServerInterceptor(),
+			shedder.UnaryServerInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(),
+			deadline.StreamServerInterceptor(budgets),
+			limiter.StreamServerInterceptor(),
+			shedder.StreamServerInterceptor(),
+		),
+	)
+	greetpb.RegisterGreetServiceServer(s, &greetservice.Server{
+		Catalog: greetings,
+		Rooms:   room.NewHub(*roomBuffer, policy),
+	})
 
-func main() {
-	fmt.Println("welcome to greet server")
-	certFile := "ssl/server.crt"
-	keyFile := "ssl/server.pem"
-	creds, sslErr := credentials.NewServerTLSFromFile(certFile, keyFile)
-	if sslErr != nil {
-		log.Fatalf("Faled loading certificate : %v", sslErr)
+	// client load balancers stop sending calls to servers that are not
+	// serving, see healthCheckConfig in internal/dial
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	for name := range s.GetServiceInfo() {
+		healthServer.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
 	}
-	opts := grpc.Creds(creds)
-	lis, err := net.Listen("tcp", "0.0.0.0:50051")
+
+	// gRPC, gRPC-Web and Connect share the listener; TLS is terminated by the
+	// http server, which negotiates HTTP/2 for gRPC clients via ALPN
+	h, err := httpserve.Handler(s, strings.Split(*corsOrigins, ",")...)
 	if err != nil {
-		log.Fatalf("failed to listen %v", err)
+		log.Fatalf("failed to create http handler : %v", err)
 	}
-	s := grpc.NewServer(opts)
-	greetpb.RegisterGreetServiceServer(s, &server{})
-
-	if err := s.Serve(lis); err != nil {
+	if err := http.ServeTLS(lis, h, certFile, keyFile); err != nil {
 		log.Fatalf("failed to serve : %v", err)
 	}
 }
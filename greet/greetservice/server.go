@@ -0,0 +1,372 @@
+// Package greetservice implements the GreetService defined in greetpb.
+package greetservice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"example.com/greet/catalog"
+	"example.com/greet/greetpb"
+	"example.com/greet/room"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Server implements greetpb.GreetServiceServer.
+type Server struct {
+	// Interval is the default pause between GreetMAnyTimes messages and the
+	// pause between the work steps of GreetWithDeadline. Zero means one
+	// second.
+	Interval time.Duration
+	// Catalog holds the greetings of Greet. Nil means catalog.Default().
+	Catalog *catalog.Catalog
+	// Rooms broadcasts the greetings of GreetEveryone rooms. Nil means a hub
+	// buffering defaultRoomBuffer events per member that drops the oldest.
+	Rooms *room.Hub
+
+	roomsOnce sync.Once
+}
+
+const defaultRoomBuffer = 64
+
+func (s *Server) interval() time.Duration {
+	if s.Interval <= 0 {
+		return time.Second
+	}
+	return s.Interval
+}
+
+func (s *Server) rooms() *room.Hub {
+	s.roomsOnce.Do(func() {
+		if s.Rooms == nil {
+			s.Rooms = room.NewHub(defaultRoomBuffer, room.DropOldest)
+		}
+	})
+	return s.Rooms
+}
+
+func (s *Server) catalog() *catalog.Catalog {
+	if s.Catalog == nil {
+		return catalog.Default()
+	}
+	return s.Catalog
+}
+
+func (s *Server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
+	fmt.Println("greet function is invoked:", req)
+	greeting := req.GetGreeting()
+	preferred, err := preferredLocales(ctx, greeting.GetLocale())
+	if err != nil {
+		return nil, err
+	}
+	c := s.catalog()
+	locale := c.Match(preferred...)
+	style := catalog.Informal
+	if greeting.GetFormality() == greetpb.Formality_FORMAL {
+		style = catalog.Formal
+	}
+	res := &greetpb.GreetResponse{
+		Result: c.Greet(locale, style, greeting.GetFirstName(), greeting.GetLastName()),
+		Locale: locale,
+	}
+	return res, nil
+}
+
+// preferredLocales returns the locale requested in the message followed by
+// those of the accept-language metadata, by decreasing preference. A
+// malformed accept-language is ignored, as HTTP servers do.
+func preferredLocales(ctx context.Context, locale string) ([]language.Tag, error) {
+	var tags []language.Tag
+	if locale != "" {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid locale %q", locale)
+		}
+		tags = append(tags, tag)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("accept-language") {
+		accepted, _, err := language.ParseAcceptLanguage(header)
+		if err == nil {
+			tags = append(tags, accepted...)
+		}
+	}
+	return tags, nil
+}
+
+// Limits on GreetMAnyTimes requests.
+const (
+	defaultGreetCount = 10
+	maxGreetCount     = 10000
+	minGreetInterval  = 10 * time.Millisecond
+	maxGreetInterval  = time.Minute
+)
+
+func (s *Server) GreetMAnyTimes(req *greetpb.GreetManyTimesRequest, stream greetpb.GreetService_GreetMAnyTimesServer) error {
+	fmt.Println("Received GreetMAnyTimes rpc")
+	count, err := greetCount(req)
+	if err != nil {
+		return err
+	}
+	interval, err := optionalDuration("interval", req.GetInterval(), s.interval())
+	if err != nil {
+		return err
+	}
+	jitter, err := optionalDuration("jitter", req.GetJitter(), 0)
+	if err != nil {
+		return err
+	}
+	// an interval set by the client must be long enough not to turn a
+	// heartbeat stream into a busy loop
+	if req.GetInterval() != nil && interval < minGreetInterval {
+		return status.Errorf(codes.InvalidArgument, "interval must be at least %v", minGreetInterval)
+	}
+	if interval > maxGreetInterval {
+		return status.Errorf(codes.InvalidArgument, "interval must be at most %v", maxGreetInterval)
+	}
+	if jitter > interval {
+		return status.Error(codes.InvalidArgument, "jitter must not exceed the interval")
+	}
+
+	ctx := stream.Context()
+	timer := time.NewTimer(jittered(interval, jitter))
+	defer timer.Stop()
+	firstName := req.GetGreeting().GetFirstName()
+	for i := 0; ; i++ {
+		res := &greetpb.GreetManyTimesResponse{Sequence: uint64(i)}
+		if req.GetHeartbeat() && i > 0 {
+			res.Heartbeat = true
+		} else {
+			res.Result = "hello " + firstName + " number " + strconv.Itoa(i)
+		}
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+		if i+1 == count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+		timer.Reset(jittered(interval, jitter))
+	}
+}
+
+// greetCount returns the number of GreetMAnyTimes messages to send, or -1
+// for a heartbeat stream that runs until the client cancels it.
+func greetCount(req *greetpb.GreetManyTimesRequest) (int, error) {
+	count := int(req.GetCount())
+	switch {
+	case req.GetHeartbeat() && count != 0:
+		return 0, status.Error(codes.InvalidArgument, "count must be zero in heartbeat mode")
+	case req.GetHeartbeat():
+		return -1, nil
+	case count == 0:
+		return defaultGreetCount, nil
+	case count > maxGreetCount:
+		return 0, status.Errorf(codes.InvalidArgument, "count must be at most %d", maxGreetCount)
+	}
+	return count, nil
+}
+
+// optionalDuration converts d, returning def when it is unset.
+func optionalDuration(name string, d *durationpb.Duration, def time.Duration) (time.Duration, error) {
+	if d == nil {
+		return def, nil
+	}
+	if err := d.CheckValid(); err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s: %v", name, err)
+	}
+	if d.AsDuration() < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "%s must not be negative", name)
+	}
+	return d.AsDuration(), nil
+}
+
+// jittered returns a duration drawn uniformly from interval ± jitter.
+func jittered(interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return interval - jitter + time.Duration(rand.Int63n(int64(2*jitter)+1))
+}
+
+func (*Server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
+	fmt.Println("greet function is invoked with a streaming request")
+	result := ""
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			//porcessing finish
+			return stream.SendAndClose(
+				&greetpb.LongGreetResponse{
+					Result: result,
+				},
+			)
+
+		}
+		if err != nil {
+			return err
+		}
+
+		firstName := req.GetGreeting().GetFirstName()
+		result += "Hello " + firstName + " ! "
+	}
+}
+
+// GreetEveryone echoes greetings back to the sender, or broadcasts them to
+// a room named by the "room" metadata or the first message. The first
+// greeting names the member in join and leave events. Room members that
+// close their side of the stream keep receiving the events of the room
+// until they cancel the call; with the "room" metadata, a client may join
+// without sending anything.
+func (s *Server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) error {
+	fmt.Println("GreetEveryone function is invoked with a streaming request")
+	name := ""
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	if v := md.Get("room"); len(v) > 0 {
+		name = v[0]
+	}
+	req, err := stream.Recv()
+	if err == io.EOF {
+		if name == "" {
+			return nil
+		}
+		return s.greetRoom(stream, name, nil)
+	}
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = req.GetRoom()
+	}
+	if name == "" {
+		return greetEcho(stream, req)
+	}
+	return s.greetRoom(stream, name, req)
+}
+
+func greetEcho(stream greetpb.GreetService_GreetEveryoneServer, req *greetpb.GreetEveryoneRequest) error {
+	for {
+		firstName := req.GetGreeting().GetFirstName()
+		result := "Hello " + firstName + " ! "
+		err := stream.Send(&greetpb.GreetEveryoneResponse{
+			Result: result,
+		})
+		if err != nil {
+			return err
+		}
+		req, err = stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// greetRoom makes the client a member of a room. first is nil when the
+// client closed its side of the stream without sending anything.
+func (s *Server) greetRoom(stream greetpb.GreetService_GreetEveryoneServer, name string, first *greetpb.GreetEveryoneRequest) error {
+	ctx := stream.Context()
+	m := s.rooms().Join(name, first.GetGreeting().GetFirstName())
+	defer m.Leave()
+	if first.GetGreeting() != nil {
+		m.Publish("Hello " + m.Name + " ! ")
+	}
+	// The handler owns Send; received greetings are published from here.
+	// Once the client is done sending, recvErr is nil and the member only
+	// listens.
+	var recvErr chan error
+	if first != nil {
+		recvErr = make(chan error, 1)
+		go func() {
+			for {
+				req, err := stream.Recv()
+				if err != nil {
+					recvErr <- err
+					return
+				}
+				if req.GetGreeting() != nil {
+					m.Publish("Hello " + req.GetGreeting().GetFirstName() + " ! ")
+				}
+			}
+		}()
+	}
+	for {
+		select {
+		case err := <-recvErr:
+			if err != io.EOF {
+				return err
+			}
+			recvErr = nil
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case e, ok := <-m.Events():
+			if !ok {
+				return status.Errorf(codes.ResourceExhausted, "disconnected from room %q for falling behind", name)
+			}
+			if err := stream.Send(roomResponse(name, e, m.Dropped())); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func roomResponse(name string, e room.Event, dropped uint64) *greetpb.GreetEveryoneResponse {
+	res := &greetpb.GreetEveryoneResponse{Room: name, Member: e.Member, Dropped: dropped}
+	switch e.Kind {
+	case room.Message:
+		res.Result = e.Text
+	case room.Join:
+		res.Event = greetpb.RoomEvent_JOIN
+		res.Result = e.Member + " joined " + name
+	case room.Leave:
+		res.Event = greetpb.RoomEvent_LEAVE
+		res.Result = e.Member + " left " + name
+	}
+	return res
+}
+
+// maxProcessingTime bounds the simulated work of GreetWithDeadline.
+const maxProcessingTime = time.Minute
+
+func (s *Server) GreetWithDeadline(ctx context.Context, req *greetpb.GreetWithDeadlineRequest) (*greetpb.GreetWithDeadlineResponse, error) {
+	fmt.Println("GreetWithDeadline function is invoked: \n", req)
+	work, err := optionalDuration("processing time", req.GetProcessingTime(), 3*s.interval())
+	if err != nil {
+		return nil, err
+	}
+	if work > maxProcessingTime {
+		return nil, status.Errorf(codes.InvalidArgument, "processing time must be at most %v", maxProcessingTime)
+	}
+	timer := time.NewTimer(work)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			fmt.Println("deadline exceeded before the greeting was ready !!")
+		} else {
+			fmt.Println("client canceled the request !!")
+		}
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case <-timer.C:
+	}
+	firstName := req.GetGreeting().GetFirstName()
+	result := "hello " + firstName
+	res := &greetpb.GreetWithDeadlineResponse{
+		Result: result,
+	}
+	return res, nil
+}
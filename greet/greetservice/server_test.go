@@ -0,0 +1,468 @@
+package greetservice_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"example.com/greet/greetpb"
+	"example.com/greet/greetservice"
+	"example.com/internal/deadline"
+	"example.com/internal/harness"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestGreet(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	tests := []struct {
+		name      string
+		firstName string
+		want      string
+	}{
+		{"name", "rahul", "hello rahul"},
+		{"empty name", "", "hello "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.Greet(context.Background(), &greetpb.GreetRequest{
+				Greeting: &greetpb.Greeting{FirstName: tt.firstName, LastName: "poonia"},
+			})
+			if err != nil {
+				t.Fatalf("Greet: %v", err)
+			}
+			if res.GetResult() != tt.want {
+				t.Errorf("Greet = %q, want %q", res.GetResult(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGreetLocalized(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	tests := []struct {
+		name           string
+		greeting       *greetpb.Greeting
+		acceptLanguage string
+		want           string
+		wantLocale     string
+	}{
+		{"default", &greetpb.Greeting{FirstName: "rahul"}, "", "hello rahul", "en"},
+		{"locale", &greetpb.Greeting{FirstName: "rahul", Locale: "es"}, "", "¡Hola, rahul!", "es"},
+		{"parent locale", &greetpb.Greeting{FirstName: "rahul", Locale: "de-CH"}, "", "Hallo rahul!", "de"},
+		{"formal", &greetpb.Greeting{FirstName: "rahul", LastName: "poonia", Locale: "fr", Formality: greetpb.Formality_FORMAL}, "", "Bonjour, rahul poonia.", "fr"},
+		{"formal without last name", &greetpb.Greeting{FirstName: "rahul", Formality: greetpb.Formality_FORMAL}, "", "hello rahul", "en"},
+		{"accept-language", &greetpb.Greeting{FirstName: "rahul"}, "sw, it;q=0.8, de;q=0.9", "Hallo rahul!", "de"},
+		{"locale before accept-language", &greetpb.Greeting{FirstName: "rahul", Locale: "it"}, "de", "Ciao rahul!", "it"},
+		{"unknown locale falls back", &greetpb.Greeting{FirstName: "rahul", Locale: "sw"}, "ja", "こんにちは、rahulさん！", "ja"},
+		{"malformed accept-language", &greetpb.Greeting{FirstName: "rahul"}, ";;;", "hello rahul", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.acceptLanguage != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "accept-language", tt.acceptLanguage)
+			}
+			res, err := c.Greet(ctx, &greetpb.GreetRequest{Greeting: tt.greeting})
+			if err != nil {
+				t.Fatalf("Greet: %v", err)
+			}
+			if res.GetResult() != tt.want || res.GetLocale() != tt.wantLocale {
+				t.Errorf("Greet = %q in %q, want %q in %q", res.GetResult(), res.GetLocale(), tt.want, tt.wantLocale)
+			}
+		})
+	}
+
+	_, err := c.Greet(context.Background(), &greetpb.GreetRequest{Greeting: &greetpb.Greeting{Locale: "not a locale!"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Greet with a malformed locale error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGreetManyTimes(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	stream, err := c.GreetMAnyTimes(context.Background(), &greetpb.GreetManyTimesRequest{
+		Greeting: &greetpb.Greeting{FirstName: "rahul"},
+	})
+	if err != nil {
+		t.Fatalf("GreetMAnyTimes: %v", err)
+	}
+	var got []string
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		got = append(got, res.GetResult())
+	}
+	if len(got) != 10 {
+		t.Fatalf("got %d messages, want 10", len(got))
+	}
+	if got[0] != "hello rahul number 0" || got[9] != "hello rahul number 9" {
+		t.Errorf("unexpected messages %q", got)
+	}
+}
+
+func TestGreetManyTimesCadence(t *testing.T) {
+	c := harness.Greet(t, time.Hour)
+	start := time.Now()
+	stream, err := c.GreetMAnyTimes(context.Background(), &greetpb.GreetManyTimesRequest{
+		Greeting: &greetpb.Greeting{FirstName: "rahul"},
+		Count:    4,
+		Interval: durationpb.New(20 * time.Millisecond),
+		Jitter:   durationpb.New(10 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("GreetMAnyTimes: %v", err)
+	}
+	var n uint64
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if res.GetSequence() != n || res.GetHeartbeat() {
+			t.Errorf("message %d = %v", n, res)
+		}
+		n++
+	}
+	if n != 4 {
+		t.Errorf("got %d messages, want 4", n)
+	}
+	// three pauses of at least 10ms each
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("stream took %v, want at least 30ms", elapsed)
+	}
+}
+
+func TestGreetManyTimesHeartbeat(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.GreetMAnyTimes(ctx, &greetpb.GreetManyTimesRequest{
+		Greeting:  &greetpb.Greeting{FirstName: "rahul"},
+		Heartbeat: true,
+	})
+	if err != nil {
+		t.Fatalf("GreetMAnyTimes: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if i == 0 && (res.GetResult() != "hello rahul number 0" || res.GetHeartbeat()) {
+			t.Errorf("first message = %v, want the greeting", res)
+		}
+		if i > 0 && (res.GetResult() != "" || !res.GetHeartbeat() || res.GetSequence() != uint64(i)) {
+			t.Errorf("message %d = %v, want a heartbeat", i, res)
+		}
+	}
+	cancel()
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if status.Code(err) != codes.Canceled {
+				t.Errorf("Recv after cancel error = %v, want Canceled", err)
+			}
+			break
+		}
+	}
+}
+
+func TestGreetManyTimesErrors(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	tests := []struct {
+		name string
+		req  *greetpb.GreetManyTimesRequest
+	}{
+		{"count too large", &greetpb.GreetManyTimesRequest{Count: 10001}},
+		{"count in heartbeat mode", &greetpb.GreetManyTimesRequest{Count: 5, Heartbeat: true}},
+		{"negative interval", &greetpb.GreetManyTimesRequest{Interval: durationpb.New(-time.Second)}},
+		{"zero interval", &greetpb.GreetManyTimesRequest{Interval: durationpb.New(0)}},
+		{"zero interval heartbeat", &greetpb.GreetManyTimesRequest{Interval: durationpb.New(0), Heartbeat: true}},
+		{"interval too short", &greetpb.GreetManyTimesRequest{Interval: durationpb.New(time.Millisecond)}},
+		{"interval too long", &greetpb.GreetManyTimesRequest{Interval: durationpb.New(time.Hour)}},
+		{"jitter above interval", &greetpb.GreetManyTimesRequest{Interval: durationpb.New(time.Second), Jitter: durationpb.New(2 * time.Second)}},
+		{"invalid jitter", &greetpb.GreetManyTimesRequest{Jitter: &durationpb.Duration{Seconds: 1, Nanos: -1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.GreetMAnyTimes(context.Background(), tt.req)
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("error = %v, want InvalidArgument", err)
+			}
+		})
+	}
+}
+
+func TestLongGreet(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	tests := []struct {
+		name  string
+		names []string
+		want  string
+	}{
+		{"no greetings", nil, ""},
+		{"one greeting", []string{"rahul"}, "Hello rahul ! "},
+		{"many greetings", []string{"rahul", "mike"}, "Hello rahul ! Hello mike ! "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.LongGreet(context.Background())
+			if err != nil {
+				t.Fatalf("LongGreet: %v", err)
+			}
+			for _, name := range tt.names {
+				if err := stream.Send(&greetpb.LongGreetRequest{Greeting: &greetpb.Greeting{FirstName: name}}); err != nil {
+					t.Fatalf("Send: %v", err)
+				}
+			}
+			res, err := stream.CloseAndRecv()
+			if err != nil {
+				t.Fatalf("CloseAndRecv: %v", err)
+			}
+			if res.GetResult() != tt.want {
+				t.Errorf("LongGreet = %q, want %q", res.GetResult(), tt.want)
+			}
+		})
+	}
+}
+
+func TestClientStreamCanceled(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.LongGreet(ctx)
+	if err != nil {
+		t.Fatalf("LongGreet: %v", err)
+	}
+	if err := stream.Send(&greetpb.LongGreetRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	cancel()
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.Canceled {
+		t.Errorf("CloseAndRecv after cancel = %v, want Canceled", err)
+	}
+	// the server survives the disconnect
+	if _, err := c.Greet(context.Background(), &greetpb.GreetRequest{}); err != nil {
+		t.Errorf("Greet after a canceled stream: %v", err)
+	}
+}
+
+func TestGreetEveryone(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	stream, err := c.GreetEveryone(context.Background())
+	if err != nil {
+		t.Fatalf("GreetEveryone: %v", err)
+	}
+	for _, name := range []string{"rahul", "mike"} {
+		if err := stream.Send(&greetpb.GreetEveryoneRequest{Greeting: &greetpb.Greeting{FirstName: name}}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		if want := "Hello " + name + " ! "; res.GetResult() != want {
+			t.Errorf("GreetEveryone = %q, want %q", res.GetResult(), want)
+		}
+	}
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Recv after CloseSend = %v, want EOF", err)
+	}
+}
+
+func TestGreetEveryoneRoom(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	recv := func(stream greetpb.GreetService_GreetEveryoneClient) *greetpb.GreetEveryoneResponse {
+		t.Helper()
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		return res
+	}
+	check := func(res *greetpb.GreetEveryoneResponse, event greetpb.RoomEvent, member, result string) {
+		t.Helper()
+		if res.GetRoom() != "lobby" || res.GetEvent() != event || res.GetMember() != member || res.GetResult() != result {
+			t.Errorf("got %v, want %v from %q: %q", res, event, member, result)
+		}
+	}
+
+	// rahul joins through metadata, mike through his first message
+	ctx, cancelRahul := context.WithCancel(metadata.AppendToOutgoingContext(context.Background(), "room", "lobby"))
+	defer cancelRahul()
+	rahul, err := c.GreetEveryone(ctx)
+	if err != nil {
+		t.Fatalf("GreetEveryone: %v", err)
+	}
+	if err := rahul.Send(&greetpb.GreetEveryoneRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	check(recv(rahul), greetpb.RoomEvent_JOIN, "rahul", "rahul joined lobby")
+	check(recv(rahul), greetpb.RoomEvent_GREETING, "rahul", "Hello rahul ! ")
+
+	mike, err := c.GreetEveryone(context.Background())
+	if err != nil {
+		t.Fatalf("GreetEveryone: %v", err)
+	}
+	if err := mike.Send(&greetpb.GreetEveryoneRequest{Room: "lobby", Greeting: &greetpb.Greeting{FirstName: "mike"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	for _, stream := range []greetpb.GreetService_GreetEveryoneClient{rahul, mike} {
+		check(recv(stream), greetpb.RoomEvent_JOIN, "mike", "mike joined lobby")
+		check(recv(stream), greetpb.RoomEvent_GREETING, "mike", "Hello mike ! ")
+	}
+
+	if err := rahul.Send(&greetpb.GreetEveryoneRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	for _, stream := range []greetpb.GreetService_GreetEveryoneClient{rahul, mike} {
+		check(recv(stream), greetpb.RoomEvent_GREETING, "rahul", "Hello rahul ! ")
+	}
+
+	// rahul is done sending but keeps listening until he leaves
+	if err := rahul.CloseSend(); err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+	if err := mike.Send(&greetpb.GreetEveryoneRequest{Greeting: &greetpb.Greeting{FirstName: "mike"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	for _, stream := range []greetpb.GreetService_GreetEveryoneClient{rahul, mike} {
+		check(recv(stream), greetpb.RoomEvent_GREETING, "mike", "Hello mike ! ")
+	}
+	cancelRahul()
+	check(recv(mike), greetpb.RoomEvent_LEAVE, "rahul", "rahul left lobby")
+}
+
+func TestGreetEveryoneListener(t *testing.T) {
+	c := harness.Greet(t, time.Millisecond)
+	recv := func(stream greetpb.GreetService_GreetEveryoneClient) *greetpb.GreetEveryoneResponse {
+		t.Helper()
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		return res
+	}
+
+	// a listener joins through metadata and closes its side at once
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "room", "hall")
+	listener, err := c.GreetEveryone(ctx)
+	if err != nil {
+		t.Fatalf("GreetEveryone: %v", err)
+	}
+	if err := listener.CloseSend(); err != nil {
+		t.Fatalf("CloseSend: %v", err)
+	}
+	if res := recv(listener); res.GetEvent() != greetpb.RoomEvent_JOIN || res.GetMember() != "" {
+		t.Fatalf("first event = %v, want the listener joining", res)
+	}
+
+	mike, err := c.GreetEveryone(context.Background())
+	if err != nil {
+		t.Fatalf("GreetEveryone: %v", err)
+	}
+	if err := mike.Send(&greetpb.GreetEveryoneRequest{Room: "hall", Greeting: &greetpb.Greeting{FirstName: "mike"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if res := recv(listener); res.GetEvent() != greetpb.RoomEvent_JOIN || res.GetMember() != "mike" {
+		t.Errorf("listener got %v, want mike joining", res)
+	}
+	if res := recv(listener); res.GetResult() != "Hello mike ! " {
+		t.Errorf("listener got %v, want mike's greeting", res)
+	}
+}
+
+func TestGreetWithDeadline(t *testing.T) {
+	// serverErr records the error each GreetWithDeadline handler returned
+	serverErr := make(chan error, 1)
+	conn := harness.Start(t, func(s *grpc.Server) {
+		greetpb.RegisterGreetServiceServer(s, &greetservice.Server{Interval: 10 * time.Millisecond})
+	}, grpc.ChainUnaryInterceptor(
+		deadline.UnaryServerInterceptor(deadline.Budgets{"/greet.GreetService/GreetWithDeadline": 20 * time.Millisecond}),
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			res, err := handler(ctx, req)
+			serverErr <- err
+			return res, err
+		},
+	))
+	c := greetpb.NewGreetServiceClient(conn)
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		cancel     time.Duration
+		processing time.Duration
+		code       codes.Code
+		serverCode codes.Code
+	}{
+		{"enough time", 5 * time.Second, 0, 0, codes.OK, codes.OK},
+		{"requested processing time", 5 * time.Second, 0, time.Millisecond, codes.OK, codes.OK},
+		{"deadline exceeded", 100 * time.Millisecond, 0, time.Minute, codes.DeadlineExceeded, codes.DeadlineExceeded},
+		{"canceled", 5 * time.Second, 50 * time.Millisecond, time.Minute, codes.Canceled, codes.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			if tt.cancel > 0 {
+				time.AfterFunc(tt.cancel, cancel)
+			}
+			req := &greetpb.GreetWithDeadlineRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}}
+			if tt.processing > 0 {
+				req.ProcessingTime = durationpb.New(tt.processing)
+			}
+			start := time.Now()
+			res, err := c.GreetWithDeadline(ctx, req)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("GreetWithDeadline code = %v, want %v (err %v)", code, tt.code, err)
+			}
+			if err == nil && res.GetResult() != "hello rahul" {
+				t.Errorf("GreetWithDeadline = %q, want %q", res.GetResult(), "hello rahul")
+			}
+			select {
+			case err := <-serverErr:
+				if code := status.Code(err); code != tt.serverCode {
+					t.Errorf("handler code = %v, want %v (err %v)", code, tt.serverCode, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("handler kept working after the call ended")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("call took %v", elapsed)
+			}
+		})
+	}
+
+	// calls with less time left than the budget are rejected before the
+	// handler runs
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := c.GreetWithDeadline(ctx, &greetpb.GreetWithDeadlineRequest{ProcessingTime: durationpb.New(time.Millisecond)})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("call below the budget error = %v, want DeadlineExceeded", err)
+	}
+	select {
+	case err := <-serverErr:
+		t.Errorf("handler ran for a call below the budget and returned %v", err)
+	default:
+	}
+
+	_, err = c.GreetWithDeadline(context.Background(), &greetpb.GreetWithDeadlineRequest{ProcessingTime: durationpb.New(time.Hour)})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GreetWithDeadline with an hour of processing error = %v, want InvalidArgument", err)
+	}
+}
@@ -0,0 +1,178 @@
+// Package room broadcasts messages between the members of named chat rooms.
+//
+// Every member has a bounded buffer of pending events. A member that falls
+// behind either loses its oldest events or is disconnected, depending on the
+// Policy of the Hub.
+package room
+
+import (
+	"sync"
+)
+
+// Kind is the kind of an Event.
+type Kind int
+
+const (
+	// Message is a message published by a member.
+	Message Kind = iota
+	// Join reports a member entering the room, including the receiver.
+	Join
+	// Leave reports a member leaving the room or being disconnected.
+	Leave
+)
+
+// Event is delivered to every member of a room.
+type Event struct {
+	Kind   Kind
+	Member string
+	Text   string
+}
+
+// Policy decides what happens to a member whose buffer is full.
+type Policy int
+
+const (
+	// DropOldest discards the oldest pending event to make room for the new
+	// one. Members learn how many events they lost from Member.Dropped.
+	DropOldest Policy = iota
+	// Disconnect removes the member from the room and closes its events.
+	Disconnect
+)
+
+// Hub holds the rooms and their members. Rooms exist while they have
+// members.
+type Hub struct {
+	buffer int
+	policy Policy
+
+	mu    sync.Mutex
+	rooms map[string]map[*Member]struct{}
+}
+
+// NewHub returns a hub buffering up to buffer events per member.
+func NewHub(buffer int, policy Policy) *Hub {
+	if buffer < 1 {
+		buffer = 1
+	}
+	return &Hub{buffer: buffer, policy: policy, rooms: map[string]map[*Member]struct{}{}}
+}
+
+// Member is the membership of one subscriber in a room.
+type Member struct {
+	Name string
+	Room string
+
+	hub    *Hub
+	events chan Event
+	// guarded by hub.mu
+	dropped uint64
+	left    bool
+	evicted bool
+}
+
+// Join adds a member called name to the room and announces it to everyone
+// in the room.
+func (h *Hub) Join(room, name string) *Member {
+	m := &Member{Name: name, Room: room, hub: h, events: make(chan Event, h.buffer)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	members := h.rooms[room]
+	if members == nil {
+		members = map[*Member]struct{}{}
+		h.rooms[room] = members
+	}
+	members[m] = struct{}{}
+	h.broadcast(room, Event{Kind: Join, Member: name})
+	return m
+}
+
+// Members returns the number of members of the room.
+func (h *Hub) Members(room string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.rooms[room])
+}
+
+// broadcast delivers e to the members of room, applying the policy to those
+// that are full. Disconnected members are announced in turn.
+func (h *Hub) broadcast(room string, e Event) {
+	pending := []Event{e}
+	for len(pending) > 0 {
+		e, pending = pending[0], pending[1:]
+		for m := range h.rooms[room] {
+			select {
+			case m.events <- e:
+				continue
+			default:
+			}
+			if h.policy == Disconnect {
+				m.evicted = true
+				h.remove(m)
+				pending = append(pending, Event{Kind: Leave, Member: m.Name})
+				continue
+			}
+			// Only broadcasts send, under h.mu, so a slot is free once
+			// one event has been taken out.
+			select {
+			case <-m.events:
+				m.dropped++
+			default:
+			}
+			m.events <- e
+		}
+	}
+}
+
+// remove takes m out of its room and closes its events.
+func (h *Hub) remove(m *Member) {
+	m.left = true
+	close(m.events)
+	members := h.rooms[m.Room]
+	delete(members, m)
+	if len(members) == 0 {
+		delete(h.rooms, m.Room)
+	}
+}
+
+// Events returns the events of the room. It is closed once the member left
+// or was disconnected.
+func (m *Member) Events() <-chan Event { return m.events }
+
+// Publish sends text from m to everyone in the room, m included. It does
+// nothing once m left.
+func (m *Member) Publish(text string) {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	if !m.left {
+		m.hub.broadcast(m.Room, Event{Kind: Message, Member: m.Name, Text: text})
+	}
+}
+
+// Leave removes m from the room and announces it to the remaining members.
+// It can be called more than once.
+func (m *Member) Leave() {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	if m.left {
+		return
+	}
+	m.hub.remove(m)
+	m.hub.broadcast(m.Room, Event{Kind: Leave, Member: m.Name})
+}
+
+// Dropped returns the number of events discarded for m since the previous
+// call.
+func (m *Member) Dropped() uint64 {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	n := m.dropped
+	m.dropped = 0
+	return n
+}
+
+// Evicted reports whether m was disconnected for falling behind.
+func (m *Member) Evicted() bool {
+	m.hub.mu.Lock()
+	defer m.hub.mu.Unlock()
+	return m.evicted
+}
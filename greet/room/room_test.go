@@ -0,0 +1,104 @@
+package room
+
+import (
+	"reflect"
+	"testing"
+)
+
+// drain returns the pending events of m without blocking.
+func drain(m *Member) []Event {
+	var events []Event
+	for {
+		select {
+		case e, ok := <-m.Events():
+			if !ok {
+				return events
+			}
+			events = append(events, e)
+		default:
+			return events
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	h := NewHub(10, DropOldest)
+	a := h.Join("lobby", "rahul")
+	b := h.Join("lobby", "mike")
+	other := h.Join("kitchen", "ana")
+	a.Publish("hi")
+	b.Leave()
+	b.Leave()
+	b.Publish("ignored")
+
+	want := []Event{
+		{Kind: Join, Member: "rahul"},
+		{Kind: Join, Member: "mike"},
+		{Kind: Message, Member: "rahul", Text: "hi"},
+		{Kind: Leave, Member: "mike"},
+	}
+	if got := drain(a); !reflect.DeepEqual(got, want) {
+		t.Errorf("rahul got %v, want %v", got, want)
+	}
+	if got := drain(b); !reflect.DeepEqual(got, want[1:3]) {
+		t.Errorf("mike got %v, want %v", got, want[1:3])
+	}
+	if got := drain(other); len(got) != 1 {
+		t.Errorf("ana got %v, want her own join only", got)
+	}
+	if n := h.Members("lobby"); n != 1 {
+		t.Errorf("lobby has %d members, want 1", n)
+	}
+	a.Leave()
+	if _, ok := h.rooms["lobby"]; ok {
+		t.Error("empty room was kept")
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	h := NewHub(2, DropOldest)
+	slow := h.Join("lobby", "slow")
+	fast := h.Join("lobby", "fast")
+	for _, text := range []string{"1", "2", "3"} {
+		fast.Publish(text)
+		drain(fast)
+	}
+	got := drain(slow)
+	if len(got) != 2 || got[0].Text != "2" || got[1].Text != "3" {
+		t.Errorf("slow member got %v, want the last two messages", got)
+	}
+	if n := slow.Dropped(); n != 3 {
+		t.Errorf("Dropped() = %d, want 3", n)
+	}
+	if n := slow.Dropped(); n != 0 {
+		t.Errorf("second Dropped() = %d, want 0", n)
+	}
+	if slow.Evicted() {
+		t.Error("slow member was evicted")
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	h := NewHub(2, Disconnect)
+	slow := h.Join("lobby", "slow")
+	fast := h.Join("lobby", "fast")
+	drain(fast)
+	fast.Publish("1")
+
+	got := drain(slow)
+	if len(got) != 2 || !slow.Evicted() {
+		t.Fatalf("slow member got %v, evicted %v; want two events then eviction", got, slow.Evicted())
+	}
+	if _, ok := <-slow.Events(); ok {
+		t.Error("events of an evicted member are still open")
+	}
+	want := []Event{{Kind: Message, Member: "fast", Text: "1"}, {Kind: Leave, Member: "slow"}}
+	if got := drain(fast); !reflect.DeepEqual(got, want) {
+		t.Errorf("fast member got %v, want %v", got, want)
+	}
+	slow.Leave()
+	slow.Publish("ignored")
+	if n := h.Members("lobby"); n != 1 {
+		t.Errorf("lobby has %d members, want 1", n)
+	}
+}
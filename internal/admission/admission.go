@@ -0,0 +1,323 @@
+// Package admission sheds calls a saturated server would only queue.
+//
+// A Limiter caps the unary and server-streaming calls a server handles at
+// once. The cap adapts to the latency of the unary calls (additive
+// increase, multiplicative decrease): it grows by one for each call that
+// finishes about as fast as the fastest recent calls to the same method
+// while the server is busy, and shrinks by a fraction once calls take more
+// than Tolerance times as long while the server is near the cap. Failed
+// calls leave the cap alone: how fast a call fails says little about the
+// load. Calls over the cap fail at once with codes.Unavailable, which the
+// retry policies of internal/dial retry on another replica or after a
+// backoff.
+//
+// Streams the client sends on, such as chat rooms, are refused while the
+// server is over the cap but hold no place under it: they mostly wait on
+// their client. The maxStreams limits of internal/ratelimit cap how many
+// of them each caller keeps open.
+//
+// Callers choose the priority of a call with the PriorityKey metadata.
+// Sheddable calls are refused first, while the server still has headroom
+// for others; critical calls are never refused. Only health checks, server
+// reflection and the callers Options.Admin vouches for are critical; other
+// calls asking to be critical are treated as default ones.
+package admission
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PriorityKey is the metadata key holding the priority of a call: one of
+// "critical", "default" or "sheddable".
+const PriorityKey = "x-priority"
+
+// Priority is how important a call is to its caller.
+type Priority int
+
+const (
+	// Sheddable calls are refused once calls in flight reach
+	// SheddableShare of the limit.
+	Sheddable Priority = iota
+	// Default calls are refused once calls in flight reach the limit.
+	Default
+	// Critical calls are never refused. They are reserved for health
+	// checks, reflection and administrators.
+	Critical
+)
+
+var priorityNames = map[string]Priority{
+	"sheddable": Sheddable,
+	"default":   Default,
+	"critical":  Critical,
+}
+
+// criticalServices are the services whose calls are always critical.
+var criticalServices = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+// Options tune a Limiter. Zero fields take the value of DefaultOptions.
+type Options struct {
+	// InitialLimit is the limit before any call finished.
+	InitialLimit int
+	// MinLimit and MaxLimit bound the limit.
+	MinLimit, MaxLimit int
+	// Tolerance is how many times slower than the fastest recent calls a
+	// call may be before the limit shrinks.
+	Tolerance float64
+	// Backoff is the factor the limit shrinks by.
+	Backoff float64
+	// SheddableShare is the share of the limit sheddable calls may use.
+	SheddableShare float64
+	// Window is how long the latency of the fastest call is remembered.
+	Window time.Duration
+	// Admin reports whether the caller of ctx was authenticated as an
+	// administrator, whose calls may be critical. With a nil Admin, no
+	// caller is.
+	Admin func(ctx context.Context) bool
+}
+
+// DefaultOptions returns the options used by the servers.
+func DefaultOptions() Options {
+	return Options{
+		InitialLimit:   20,
+		MinLimit:       4,
+		MaxLimit:       1000,
+		Tolerance:      2,
+		Backoff:        0.9,
+		SheddableShare: 0.75,
+		Window:         30 * time.Second,
+	}
+}
+
+func (o Options) withDefaults() Options {
+	d := DefaultOptions()
+	if o.InitialLimit <= 0 {
+		o.InitialLimit = d.InitialLimit
+	}
+	if o.MinLimit <= 0 {
+		o.MinLimit = d.MinLimit
+	}
+	if o.MaxLimit <= 0 {
+		o.MaxLimit = d.MaxLimit
+	}
+	if o.MinLimit > o.MaxLimit {
+		o.MinLimit = o.MaxLimit
+	}
+	if o.InitialLimit < o.MinLimit {
+		o.InitialLimit = o.MinLimit
+	}
+	if o.InitialLimit > o.MaxLimit {
+		o.InitialLimit = o.MaxLimit
+	}
+	if o.Tolerance <= 1 {
+		o.Tolerance = d.Tolerance
+	}
+	if o.Backoff <= 0 || o.Backoff >= 1 {
+		o.Backoff = d.Backoff
+	}
+	if o.SheddableShare <= 0 || o.SheddableShare > 1 {
+		o.SheddableShare = d.SheddableShare
+	}
+	if o.Window <= 0 {
+		o.Window = d.Window
+	}
+	return o
+}
+
+// Limiter is an adaptive limit on the calls in flight.
+type Limiter struct {
+	opts Options
+	now  func() time.Time
+
+	mu        sync.Mutex
+	limit     float64
+	inFlight  int
+	baselines map[string]*baseline
+	// lastCut is when the limit last shrank. Calls that started before
+	// then do not shrink it again: they were slowed by the same overload.
+	lastCut time.Time
+}
+
+// baseline tracks the latency of the fastest successful calls to a method.
+type baseline struct {
+	// latency is that of the fastest call of the last window, or of the
+	// current one during the first window.
+	latency, windowMin time.Duration
+	windowStart        time.Time
+}
+
+// New returns a limiter tuned by o.
+func New(o Options) *Limiter {
+	o = o.withDefaults()
+	return &Limiter{
+		opts:      o,
+		now:       time.Now,
+		limit:     float64(o.InitialLimit),
+		baselines: map[string]*baseline{},
+	}
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// UnaryServerInterceptor sheds unary calls over the limit. It should run
+// last, next to the handler, so that calls refused by other interceptors
+// do not count as fast calls.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start, err := l.acquire(l.priority(ctx, info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+		ok := false
+		defer func() { l.release(info.FullMethod, start, ok) }()
+		resp, err := handler(ctx, req)
+		ok = err == nil
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor sheds new streams while the server is over the
+// limit. Server-streaming calls hold a place under the limit until they
+// end, but how long they stay open does not adapt the limit: that depends
+// on how much they send more than on the load of the server.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		p := l.priority(ss.Context(), info.FullMethod)
+		if info.IsClientStream {
+			if err := l.admit(p); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}
+		if _, err := l.acquire(p); err != nil {
+			return err
+		}
+		defer l.leave()
+		return handler(srv, ss)
+	}
+}
+
+// priority returns the priority of a call to fullMethod.
+func (l *Limiter) priority(ctx context.Context, fullMethod string) Priority {
+	for _, prefix := range criticalServices {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return Critical
+		}
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(PriorityKey); len(v) > 0 {
+		if p, ok := priorityNames[strings.ToLower(v[0])]; ok {
+			if p == Critical && (l.opts.Admin == nil || !l.opts.Admin(ctx)) {
+				return Default
+			}
+			return p
+		}
+	}
+	return Default
+}
+
+// admit reports whether a call of priority p may start now.
+func (l *Limiter) admit(p Priority) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.admitLocked(p)
+}
+
+func (l *Limiter) admitLocked(p Priority) error {
+	limit := l.limit
+	switch p {
+	case Critical:
+		return nil
+	case Sheddable:
+		limit *= l.opts.SheddableShare
+	}
+	if float64(l.inFlight) >= limit {
+		return status.Error(codes.Unavailable, "server overloaded, try again later")
+	}
+	return nil
+}
+
+// acquire starts a call of priority p, which release must end.
+func (l *Limiter) acquire(p Priority) (start time.Time, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.admitLocked(p); err != nil {
+		return time.Time{}, err
+	}
+	l.inFlight++
+	return l.now(), nil
+}
+
+// leave ends a call started by acquire without adapting the limit.
+func (l *Limiter) leave() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+}
+
+// release ends a call to method that started at start. The latency of a
+// call that succeeded adapts the limit.
+func (l *Limiter) release(method string, start time.Time, succeeded bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	inFlight := l.inFlight
+	l.inFlight--
+	if !succeeded {
+		return
+	}
+	now := l.now()
+	latency := now.Sub(start)
+
+	b := l.baselines[method]
+	if b == nil {
+		b = &baseline{windowStart: now}
+		l.baselines[method] = b
+	}
+	if now.Sub(b.windowStart) >= l.opts.Window {
+		if b.windowMin > 0 {
+			b.latency = b.windowMin
+		}
+		b.windowMin, b.windowStart = 0, now
+	}
+	if b.windowMin == 0 || latency < b.windowMin {
+		b.windowMin = latency
+	}
+	if b.latency == 0 || latency < b.latency {
+		b.latency = latency
+	}
+
+	switch {
+	case float64(latency) > float64(b.latency)*l.opts.Tolerance:
+		// slow calls on a server far from the limit are slow for reasons
+		// of their own, such as a large request
+		if float64(inFlight) < l.limit*l.opts.SheddableShare || start.Before(l.lastCut) {
+			return
+		}
+		l.limit *= l.opts.Backoff
+		l.lastCut = now
+	case float64(inFlight) >= l.limit/2:
+		l.limit++
+	}
+	if l.limit < float64(l.opts.MinLimit) {
+		l.limit = float64(l.opts.MinLimit)
+	}
+	if l.limit > float64(l.opts.MaxLimit) {
+		l.limit = float64(l.opts.MaxLimit)
+	}
+}
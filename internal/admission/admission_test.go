@@ -0,0 +1,257 @@
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func withPriority(p string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityKey, p))
+}
+
+// isAdmin is an Options.Admin trusting the callers sending the admin token.
+func isAdmin(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get("authorization")
+	return len(v) > 0 && v[0] == "Bearer admin"
+}
+
+func TestShedding(t *testing.T) {
+	l := New(Options{InitialLimit: 4, MinLimit: 1, MaxLimit: 4, SheddableShare: 0.5, Admin: isAdmin})
+	unary := l.UnaryServerInterceptor()
+	stream := l.StreamServerInterceptor()
+	call := func(ctx context.Context, method string) error {
+		_, err := unary(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+	open := func(ctx context.Context, method string, clientStream bool) error {
+		info := &grpc.StreamServerInfo{FullMethod: method, IsClientStream: clientStream, IsServerStream: true}
+		return stream(nil, &fakeStream{ctx: ctx}, info, func(interface{}, grpc.ServerStream) error {
+			return nil
+		})
+	}
+	const greet = "/greet.GreetService/Greet"
+
+	for i := 0; i < 2; i++ {
+		if _, err := l.acquire(Default); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	// sheddable calls only get half of the limit
+	if err := call(withPriority("sheddable"), greet); status.Code(err) != codes.Unavailable {
+		t.Errorf("sheddable call at half the limit: %v, want Unavailable", err)
+	}
+	if err := call(context.Background(), greet); err != nil {
+		t.Errorf("default call at half the limit: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := l.acquire(Default); err != nil {
+			t.Fatalf("call %d: %v", i+2, err)
+		}
+	}
+	if err := call(context.Background(), greet); status.Code(err) != codes.Unavailable {
+		t.Errorf("default call at the limit: %v, want Unavailable", err)
+	}
+	if err := open(context.Background(), "/greet.GreetService/GreetEveryone", true); status.Code(err) != codes.Unavailable {
+		t.Errorf("stream at the limit: %v, want Unavailable", err)
+	}
+
+	// critical calls of administrators, health checks and reflection are
+	// never shed
+	if err := call(withPriority("Critical"), greet); status.Code(err) != codes.Unavailable {
+		t.Errorf("critical call of an anonymous caller at the limit: %v, want Unavailable", err)
+	}
+	admin := metadata.NewIncomingContext(context.Background(), metadata.Pairs(PriorityKey, "Critical", "authorization", "Bearer admin"))
+	if err := call(admin, greet); err != nil {
+		t.Errorf("critical call of an administrator at the limit: %v", err)
+	}
+	if err := call(context.Background(), "/grpc.health.v1.Health/Check"); err != nil {
+		t.Errorf("health check at the limit: %v", err)
+	}
+	if err := open(withPriority("sheddable"), "/grpc.health.v1.Health/Watch", false); err != nil {
+		t.Errorf("health watch at the limit: %v", err)
+	}
+	if err := open(context.Background(), "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", true); err != nil {
+		t.Errorf("reflection at the limit: %v", err)
+	}
+}
+
+func TestStreamShedding(t *testing.T) {
+	l := New(Options{InitialLimit: 2, MinLimit: 2, MaxLimit: 2})
+	stream := l.StreamServerInterceptor()
+	list := &grpc.StreamServerInfo{FullMethod: "/blog.BlogService/ListBlog", IsServerStream: true}
+	room := &grpc.StreamServerInfo{FullMethod: "/greet.GreetService/GreetEveryone", IsClientStream: true, IsServerStream: true}
+	ss := &fakeStream{ctx: context.Background()}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan error, 3)
+	hold := func(info *grpc.StreamServerInfo) {
+		go func() {
+			done <- stream(nil, ss, info, func(interface{}, grpc.ServerStream) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+		}()
+		<-started
+	}
+	refused := func(info *grpc.StreamServerInfo) bool {
+		err := stream(nil, ss, info, func(interface{}, grpc.ServerStream) error { return nil })
+		return status.Code(err) == codes.Unavailable
+	}
+
+	// streams the client sends on hold no place
+	hold(room)
+	if _, err := l.acquire(Default); err != nil {
+		t.Fatalf("call next to an open room: %v", err)
+	}
+	l.leave()
+
+	// server-streaming calls hold one until they end
+	hold(list)
+	hold(list)
+	if !refused(list) {
+		t.Error("server stream over the limit was admitted")
+	}
+	if !refused(room) {
+		t.Error("room over the limit was admitted")
+	}
+	if _, err := l.acquire(Default); status.Code(err) != codes.Unavailable {
+		t.Errorf("call over the limit: %v, want Unavailable", err)
+	}
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("held stream: %v", err)
+		}
+	}
+	if refused(list) {
+		t.Error("server stream refused once the others ended")
+	}
+}
+
+// fakeCalls runs calls through the release of a limiter on a fake clock.
+type fakeCalls struct {
+	t   *testing.T
+	l   *Limiter
+	now time.Time
+}
+
+func newFakeCalls(t *testing.T, o Options) *fakeCalls {
+	c := &fakeCalls{t: t, l: New(o), now: time.Unix(1000, 0)}
+	c.l.now = func() time.Time { return c.now }
+	return c
+}
+
+// run runs n calls to method at once, which take latency to succeed, or to
+// fail when ok is false.
+func (c *fakeCalls) run(method string, n int, latency time.Duration, ok bool) {
+	c.t.Helper()
+	var starts []time.Time
+	for i := 0; i < n; i++ {
+		start, err := c.l.acquire(Critical)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		starts = append(starts, start)
+	}
+	c.now = c.now.Add(latency)
+	for _, start := range starts {
+		c.l.release(method, start, ok)
+	}
+}
+
+func TestAdaptiveLimit(t *testing.T) {
+	c := newFakeCalls(t, Options{InitialLimit: 10, MinLimit: 2, MaxLimit: 12})
+	const greet = "/greet.GreetService/Greet"
+
+	// fast calls grow the limit while the server is busy
+	c.run(greet, 10, 10*time.Millisecond, true)
+	if got := c.l.Limit(); got != 12 {
+		t.Errorf("limit after busy fast calls = %d, want 12", got)
+	}
+	// but not while it is idle
+	c.run(greet, 1, 10*time.Millisecond, true)
+	if got := c.l.Limit(); got != 12 {
+		t.Errorf("limit after an idle fast call = %d, want 12", got)
+	}
+
+	// slow calls near the limit shrink it once for all the calls slowed
+	// down together
+	c.run(greet, 10, 100*time.Millisecond, true)
+	if got := c.l.Limit(); got != 10 {
+		t.Errorf("limit after slow calls = %d, want 10", got)
+	}
+	// slow calls far from the limit do not
+	c.run(greet, 1, 100*time.Millisecond, true)
+	if got := c.l.Limit(); got != 10 {
+		t.Errorf("limit after an idle slow call = %d, want 10", got)
+	}
+	c.run(greet, 9, 100*time.Millisecond, true)
+	if got := c.l.Limit(); got != 9 {
+		t.Errorf("limit after more slow calls = %d, want 9", got)
+	}
+	for i := 0; i < 50; i++ {
+		c.run(greet, 9, 100*time.Millisecond, true)
+	}
+	if got := c.l.Limit(); got != 2 {
+		t.Errorf("limit after many slow calls = %d, want the minimum 2", got)
+	}
+
+	// a slower baseline is learned once the fast calls fall out of the window
+	c.now = c.now.Add(time.Minute)
+	c.run(greet, 1, 100*time.Millisecond, true)
+	c.now = c.now.Add(time.Minute)
+	c.run(greet, 2, 100*time.Millisecond, true)
+	if got := c.l.Limit(); got != 3 {
+		t.Errorf("limit after busy calls as fast as the new baseline = %d, want 3", got)
+	}
+}
+
+func TestAdaptiveLimitBaselines(t *testing.T) {
+	const greet, check = "/greet.GreetService/Greet", "/greet.GreetService/Check"
+
+	// a fast call does not make the usual calls look slow on an idle server
+	c := newFakeCalls(t, Options{})
+	c.run(greet, 1, 20*time.Microsecond, true)
+	for i := 0; i < 20; i++ {
+		c.run(greet, 1, 2*time.Millisecond, true)
+	}
+	if got, want := c.l.Limit(), DefaultOptions().InitialLimit; got != want {
+		t.Errorf("limit after sequential calls = %d, want %d", got, want)
+	}
+
+	// nor the calls to other methods, or after a call failed fast
+	c = newFakeCalls(t, Options{InitialLimit: 10, MaxLimit: 20})
+	c.run(check, 1, 20*time.Microsecond, true)
+	c.run(greet, 1, 20*time.Microsecond, false)
+	c.run(greet, 1, 2*time.Millisecond, true)
+	c.run(greet, 10, 2*time.Millisecond, true)
+	if got := c.l.Limit(); got <= 10 {
+		t.Errorf("limit after busy calls as fast as their baseline = %d, want more than 10", got)
+	}
+
+	// failed calls neither grow nor shrink the limit
+	c = newFakeCalls(t, Options{InitialLimit: 10})
+	c.run(greet, 10, 2*time.Millisecond, false)
+	c.run(greet, 10, time.Second, false)
+	if got := c.l.Limit(); got != 10 {
+		t.Errorf("limit after failed calls = %d, want 10", got)
+	}
+}
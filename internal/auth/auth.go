@@ -0,0 +1,124 @@
+// Package auth identifies the callers of a server by the bearer token they
+// send in the authorization metadata, as blogctl does with -token.
+//
+// Callers are listed in the "callers" section of the server config file
+// (see internal/serverconfig) by name, with the SHA-256 digest of their
+// token in hex, as printed by "printf %s $TOKEN | sha256sum":
+//
+//	{
+//	  "callers": {
+//	    "ops": {"tokenSHA256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "admin": true},
+//	    "blogctl": {"tokenSHA256": "ef54b0b7aaa1efbf154d433c56469ceea8d4efab36078c01e0f43f6e830e5883"}
+//	  }
+//	}
+//
+// Calls without a token, or with one that is not listed, are anonymous:
+// they are served, but rate limited by address and never critical.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// Callers maps the names of the known callers to their credentials.
+type Callers map[string]Caller
+
+// Caller holds the credentials of a caller.
+type Caller struct {
+	// TokenSHA256 is the hex SHA-256 digest of the bearer token of the
+	// caller.
+	TokenSHA256 string `json:"tokenSHA256"`
+	// Admin callers may send critical calls, see internal/admission.
+	Admin bool `json:"admin,omitempty"`
+}
+
+// Validate reports the first caller of c without a valid token digest, or
+// sharing it with another.
+func (c Callers) Validate() error {
+	names := map[string]string{}
+	for name, caller := range c {
+		if name == "" {
+			return fmt.Errorf("caller without a name")
+		}
+		digest := strings.ToLower(caller.TokenSHA256)
+		if b, err := hex.DecodeString(digest); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("caller %q: tokenSHA256 must be %d hex digits", name, 2*sha256.Size)
+		}
+		if other, ok := names[digest]; ok {
+			return fmt.Errorf("callers %q and %q have the same token", other, name)
+		}
+		names[digest] = name
+	}
+	return nil
+}
+
+// identity is a caller known by the digest of its token.
+type identity struct {
+	name  string
+	admin bool
+}
+
+// Authenticator recognizes the callers of a Callers config, which can be
+// replaced while it is in use.
+type Authenticator struct {
+	byDigest atomic.Pointer[map[string]identity]
+}
+
+// New returns an authenticator recognizing c.
+func New(c Callers) *Authenticator {
+	a := &Authenticator{}
+	a.SetCallers(c)
+	return a
+}
+
+// SetCallers replaces the callers a recognizes.
+func (a *Authenticator) SetCallers(c Callers) {
+	byDigest := make(map[string]identity, len(c))
+	for name, caller := range c {
+		byDigest[strings.ToLower(caller.TokenSHA256)] = identity{name: name, admin: caller.Admin}
+	}
+	a.byDigest.Store(&byDigest)
+}
+
+// Identity returns the name of the caller of ctx, and false for anonymous
+// callers. It fits ratelimit.Options.Authenticator.
+func (a *Authenticator) Identity(ctx context.Context) (string, bool) {
+	id, ok := a.lookup(ctx)
+	return id.name, ok
+}
+
+// Admin reports whether the caller of ctx is an admin. It fits
+// admission.Options.Admin.
+func (a *Authenticator) Admin(ctx context.Context) bool {
+	id, ok := a.lookup(ctx)
+	return ok && id.admin
+}
+
+func (a *Authenticator) lookup(ctx context.Context) (identity, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token := strings.TrimPrefix(v, "Bearer ")
+		if token == v || token == "" {
+			continue
+		}
+		// the map is keyed on digests, so looking a token up does not
+		// leak its bytes through timing
+		if id, ok := (*a.byDigest.Load())[Digest(token)]; ok {
+			return id, true
+		}
+	}
+	return identity{}, false
+}
+
+// Digest returns the hex SHA-256 digest of token, as listed in Callers.
+func Digest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
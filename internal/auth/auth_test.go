@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"example.com/greet/greetpb"
+	"example.com/greet/greetservice"
+	"example.com/internal/admission"
+	"example.com/internal/harness"
+	"example.com/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testCallers = Callers{
+	"ops":     {TokenSHA256: Digest("ops-token"), Admin: true},
+	"blogctl": {TokenSHA256: Digest("blogctl-token")},
+}
+
+func withAuthorization(ctx context.Context, v string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", v))
+}
+
+func TestAuthenticator(t *testing.T) {
+	a := New(testCallers)
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		id    string
+		ok    bool
+		admin bool
+	}{
+		{"admin", withAuthorization(ctx, "Bearer ops-token"), "ops", true, true},
+		{"caller", withAuthorization(ctx, "Bearer blogctl-token"), "blogctl", true, false},
+		{"unknown token", withAuthorization(ctx, "Bearer random"), "", false, false},
+		{"not a bearer token", withAuthorization(ctx, "Basic ops-token"), "", false, false},
+		{"bare token", withAuthorization(ctx, "ops-token"), "", false, false},
+		{"no token", ctx, "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id, ok := a.Identity(tt.ctx); id != tt.id || ok != tt.ok {
+				t.Errorf("Identity = %q, %v, want %q, %v", id, ok, tt.id, tt.ok)
+			}
+			if admin := a.Admin(tt.ctx); admin != tt.admin {
+				t.Errorf("Admin = %v, want %v", admin, tt.admin)
+			}
+		})
+	}
+
+	a.SetCallers(Callers{"ops": {TokenSHA256: Digest("new-token")}})
+	if _, ok := a.Identity(withAuthorization(ctx, "Bearer ops-token")); ok {
+		t.Error("Identity of a replaced token succeeded")
+	}
+	if id, _ := a.Identity(withAuthorization(ctx, "Bearer new-token")); id != "ops" {
+		t.Errorf("Identity of the new token = %q, want ops", id)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := testCallers.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	for name, c := range map[string]Callers{
+		"no name":      {"": {TokenSHA256: Digest("a")}},
+		"short digest": {"ops": {TokenSHA256: Digest("a")[:32]}},
+		"not hex":      {"ops": {TokenSHA256: "z" + Digest("a")[1:]}},
+		"shared token": {"ops": {TokenSHA256: Digest("a")}, "dev": {TokenSHA256: Digest("a")}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate of %s succeeded, want error", name)
+		}
+	}
+}
+
+// TestRateLimitPerToken checks that callers sharing an address get rate
+// limits of their own once they send a known token.
+func TestRateLimitPerToken(t *testing.T) {
+	limiter := ratelimit.New(&ratelimit.Config{Caller: &ratelimit.Rate{PerSecond: 0.001, Burst: 1}},
+		ratelimit.Options{Authenticator: New(testCallers).Identity})
+	conn := harness.Start(t, func(s *grpc.Server) {
+		greetpb.RegisterGreetServiceServer(s, &greetservice.Server{})
+	}, grpc.UnaryInterceptor(limiter.UnaryServerInterceptor()))
+	c := greetpb.NewGreetServiceClient(conn)
+	greet := func(token string) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+		}
+		_, err := c.Greet(ctx, &greetpb.GreetRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}})
+		return err
+	}
+
+	for _, token := range []string{"ops-token", "blogctl-token", ""} {
+		if err := greet(token); err != nil {
+			t.Fatalf("first call with token %q: %v", token, err)
+		}
+	}
+	for _, token := range []string{"ops-token", "blogctl-token", "", "random"} {
+		if err := greet(token); status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("second call with token %q error = %v, want ResourceExhausted", token, err)
+		}
+	}
+}
+
+// TestAdminNotShed checks that only admins can make critical calls that
+// are served over the concurrency limit.
+func TestAdminNotShed(t *testing.T) {
+	shedder := admission.New(admission.Options{InitialLimit: 1, MaxLimit: 1, Admin: New(testCallers).Admin})
+	started, release := make(chan struct{}), make(chan struct{})
+	// calls with the block metadata hold their place under the limit
+	block := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if md, _ := metadata.FromIncomingContext(ctx); len(md.Get("block")) > 0 {
+			started <- struct{}{}
+			<-release
+		}
+		return handler(ctx, req)
+	}
+	conn := harness.Start(t, func(s *grpc.Server) {
+		greetpb.RegisterGreetServiceServer(s, &greetservice.Server{})
+	}, grpc.ChainUnaryInterceptor(shedder.UnaryServerInterceptor(), block))
+	c := greetpb.NewGreetServiceClient(conn)
+	greet := func(kv ...string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), kv...)
+		_, err := c.Greet(ctx, &greetpb.GreetRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}})
+		return err
+	}
+
+	done := make(chan error)
+	go func() { done <- greet("block", "1") }()
+	<-started
+	if err := greet(); status.Code(err) != codes.Unavailable {
+		t.Errorf("call over the limit error = %v, want Unavailable", err)
+	}
+	if err := greet(admission.PriorityKey, "critical", "authorization", "Bearer blogctl-token"); status.Code(err) != codes.Unavailable {
+		t.Errorf("critical call of a caller over the limit error = %v, want Unavailable", err)
+	}
+	if err := greet(admission.PriorityKey, "critical", "authorization", "Bearer ops-token"); err != nil {
+		t.Errorf("critical call of an admin over the limit: %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("blocked call: %v", err)
+	}
+}
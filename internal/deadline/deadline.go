@@ -0,0 +1,123 @@
+// Package deadline rejects calls that cannot finish before their deadline.
+//
+// A method is given a minimum budget, the time it needs at the very least
+// to do useful work. Calls to it that arrive with less time left are failed
+// with codes.DeadlineExceeded before their handler runs, instead of starting
+// work whose result the client will never see. Calls without a deadline are
+// always let through.
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Budgets maps full method names, such as
+// "/greet.GreetService/GreetWithDeadline", to their minimum budget. The
+// service name followed by "/*" applies to every method of a service that
+// has no budget of its own.
+type Budgets map[string]time.Duration
+
+// ParseBudgets parses a comma separated list of method=duration pairs, as
+// in "/greet.GreetService/*=10ms,/greet.GreetService/Greet=1ms".
+func ParseBudgets(s string) (Budgets, error) {
+	b := Budgets{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(pair, "=")
+		if !ok || !strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2 {
+			return nil, fmt.Errorf("invalid budget %q, want /package.Service/Method=duration", pair)
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid budget duration %q for %s", value, method)
+		}
+		b[method] = d
+	}
+	return b, nil
+}
+
+// String formats b as accepted by ParseBudgets.
+func (b Budgets) String() string {
+	pairs := make([]string, 0, len(b))
+	for method, d := range b {
+		pairs = append(pairs, method+"="+d.String())
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set adds the budgets parsed from s to b, so that Budgets can be used with
+// flag.Var.
+func (b *Budgets) Set(s string) error {
+	parsed, err := ParseBudgets(s)
+	if err != nil {
+		return err
+	}
+	if *b == nil {
+		*b = Budgets{}
+	}
+	for method, d := range parsed {
+		(*b)[method] = d
+	}
+	return nil
+}
+
+func (b Budgets) budget(method string) time.Duration {
+	if d, ok := b[method]; ok {
+		return d
+	}
+	if i := strings.LastIndexByte(method, '/'); i > 0 {
+		return b[method[:i]+"/*"]
+	}
+	return 0
+}
+
+// check returns an error when ctx has less time left than the budget of
+// method.
+func (b Budgets) check(ctx context.Context, method string) error {
+	budget := b.budget(method)
+	if budget <= 0 {
+		return nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	if left := time.Until(deadline); left < budget {
+		return status.Errorf(codes.DeadlineExceeded, "%s needs at least %v, the deadline leaves %v", method, budget, left.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// UnaryServerInterceptor rejects unary calls whose deadline is shorter than
+// the budget of their method.
+func UnaryServerInterceptor(b Budgets) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := b.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects streams whose deadline is shorter than the
+// budget of their method.
+func StreamServerInterceptor(b Budgets) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := b.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
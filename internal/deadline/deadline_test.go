@@ -0,0 +1,77 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParseBudgets(t *testing.T) {
+	b, err := ParseBudgets(" /greet.GreetService/*=10ms, /greet.GreetService/Greet=1ms,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.String(), "/greet.GreetService/*=10ms,/greet.GreetService/Greet=1ms"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	for method, want := range map[string]time.Duration{
+		"/greet.GreetService/Greet":             time.Millisecond,
+		"/greet.GreetService/GreetWithDeadline": 10 * time.Millisecond,
+		"/calculator.CalculatorService/Sum":     0,
+	} {
+		if got := b.budget(method); got != want {
+			t.Errorf("budget(%q) = %v, want %v", method, got, want)
+		}
+	}
+
+	for _, s := range []string{"Greet=1s", "/greet.GreetService/Greet", "/greet.GreetService/Greet=soon", "/a/b=-1s", "/a/b/c=1s"} {
+		if _, err := ParseBudgets(s); err == nil {
+			t.Errorf("ParseBudgets(%q) succeeded, want error", s)
+		}
+	}
+
+	var flagged Budgets
+	if err := flagged.Set("/a.S/M=1s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := flagged.Set("/a.S/N=2s"); err != nil {
+		t.Fatal(err)
+	}
+	if len(flagged) != 2 {
+		t.Errorf("Set twice kept %v, want both budgets", flagged)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	intercept := UnaryServerInterceptor(Budgets{"/greet.GreetService/GreetWithDeadline": 100 * time.Millisecond})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	tests := []struct {
+		name    string
+		method  string
+		timeout time.Duration
+		code    codes.Code
+	}{
+		{"no deadline", "/greet.GreetService/GreetWithDeadline", 0, codes.OK},
+		{"enough time", "/greet.GreetService/GreetWithDeadline", time.Minute, codes.OK},
+		{"too little time", "/greet.GreetService/GreetWithDeadline", 10 * time.Millisecond, codes.DeadlineExceeded},
+		{"no budget", "/greet.GreetService/Greet", time.Millisecond, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			_, err := intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if code := status.Code(err); code != tt.code {
+				t.Errorf("code = %v, want %v (err %v)", code, tt.code, err)
+			}
+		})
+	}
+}
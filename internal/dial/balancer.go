@@ -0,0 +1,162 @@
+package dial
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+// WeightedLeastRequest is the name of a load balancing policy that spreads
+// calls over the ready backends in proportion to their weight divided by
+// the number of calls in flight to them plus one. Idle backends thus share
+// the load by weight, and busy ones get less than their share. Like
+// round_robin, it only uses backends reported healthy when the service
+// config has a healthCheckConfig.
+const WeightedLeastRequest = "weighted_least_request"
+
+func init() {
+	balancer.Register(wlrBuilder{})
+}
+
+type weightKey struct{}
+
+// WithWeight returns addr with the weight used by WeightedLeastRequest,
+// which is 1 for addresses without one.
+func WithWeight(addr resolver.Address, w uint32) resolver.Address {
+	addr.BalancerAttributes = addr.BalancerAttributes.WithValue(weightKey{}, w)
+	return addr
+}
+
+func weight(addr resolver.Address) uint32 {
+	if w, ok := addr.BalancerAttributes.Value(weightKey{}).(uint32); ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+// wlrBuilder builds base balancers whose pickers weigh backends by the
+// latest weights from the resolver. The weights are kept out of the
+// SubConns, which are keyed by address and would otherwise keep the weight
+// they were created with.
+type wlrBuilder struct{}
+
+func (wlrBuilder) Name() string { return WeightedLeastRequest }
+
+func (wlrBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pickers := &wlrPickerBuilder{}
+	return &wlrBalancer{
+		Balancer: base.NewBalancerBuilder(WeightedLeastRequest, pickers, base.Config{HealthCheck: true}).Build(cc, opts),
+		pickers:  pickers,
+	}
+}
+
+type wlrBalancer struct {
+	balancer.Balancer
+	pickers *wlrPickerBuilder
+}
+
+// UpdateClientConnState records the weights before the base balancer
+// rebuilds the picker with them.
+func (b *wlrBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	b.pickers.setWeights(s.ResolverState.Addresses)
+	return b.Balancer.UpdateClientConnState(s)
+}
+
+func (b *wlrBalancer) ExitIdle() {
+	if ei, ok := b.Balancer.(balancer.ExitIdler); ok {
+		ei.ExitIdle()
+	}
+}
+
+type wlrPickerBuilder struct {
+	// inFlight counts the calls in flight per SubConn. It outlives pickers,
+	// which are rebuilt whenever a backend changes state.
+	inFlight sync.Map // balancer.SubConn -> *int64
+
+	mu      sync.Mutex
+	weights map[string]uint32 // by address
+}
+
+func (b *wlrPickerBuilder) setWeights(addrs []resolver.Address) {
+	weights := make(map[string]uint32, len(addrs))
+	for _, addr := range addrs {
+		weights[addr.Addr] = weight(addr)
+	}
+	b.mu.Lock()
+	b.weights = weights
+	b.mu.Unlock()
+}
+
+func (b *wlrPickerBuilder) weight(addr resolver.Address) uint32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w, ok := b.weights[addr.Addr]; ok {
+		return w
+	}
+	return weight(addr)
+}
+
+func (b *wlrPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	// forget idle counters of SubConns that are gone or not ready
+	b.inFlight.Range(func(sc, n interface{}) bool {
+		if _, ok := info.ReadySCs[sc.(balancer.SubConn)]; !ok && atomic.LoadInt64(n.(*int64)) == 0 {
+			b.inFlight.Delete(sc)
+		}
+		return true
+	})
+	p := &wlrPicker{}
+	for sc, sci := range info.ReadySCs {
+		n, _ := b.inFlight.LoadOrStore(sc, new(int64))
+		p.backends = append(p.backends, &wlrBackend{
+			sc:       sc,
+			weight:   float64(b.weight(sci.Address)),
+			inFlight: n.(*int64),
+		})
+	}
+	return p
+}
+
+type wlrBackend struct {
+	sc       balancer.SubConn
+	weight   float64
+	inFlight *int64
+	// current is the smooth weighted round robin credit of the backend.
+	current float64
+}
+
+// wlrPicker runs smooth weighted round robin on effective weights lowered
+// by the calls in flight: each pick credits every backend its effective
+// weight and takes the sum of those weights from the backend picked, the
+// one with the most credit.
+type wlrPicker struct {
+	mu       sync.Mutex
+	backends []*wlrBackend
+}
+
+func (p *wlrPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	p.mu.Lock()
+	var best *wlrBackend
+	total := 0.0
+	for _, b := range p.backends {
+		effective := b.weight / float64(atomic.LoadInt64(b.inFlight)+1)
+		b.current += effective
+		total += effective
+		if best == nil || b.current > best.current {
+			best = b
+		}
+	}
+	best.current -= total
+	p.mu.Unlock()
+
+	atomic.AddInt64(best.inFlight, 1)
+	return balancer.PickResult{
+		SubConn: best.sc,
+		Done:    func(balancer.DoneInfo) { atomic.AddInt64(best.inFlight, -1) },
+	}, nil
+}
@@ -0,0 +1,82 @@
+package dial
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+type fakeSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+func TestWeightedLeastRequestPicker(t *testing.T) {
+	a, b := &fakeSubConn{name: "a"}, &fakeSubConn{name: "b"}
+	builder := &wlrPickerBuilder{}
+	builder.setWeights([]resolver.Address{WithWeight(resolver.Address{Addr: "a:1"}, 3), {Addr: "b:1"}})
+	// SubConns keep the addresses they were created with, so the weights
+	// come from the resolver instead
+	picker := builder.Build(base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+		a: {Address: WithWeight(resolver.Address{Addr: "a:1"}, 7)},
+		b: {Address: resolver.Address{Addr: "b:1"}},
+	}})
+	pick := func() (string, func(balancer.DoneInfo)) {
+		res, err := picker.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.SubConn.(*fakeSubConn).name, res.Done
+	}
+
+	// idle backends share the calls by weight
+	count := map[string]int{}
+	for i := 0; i < 400; i++ {
+		name, done := pick()
+		count[name]++
+		done(balancer.DoneInfo{})
+	}
+	if count["a"] != 300 || count["b"] != 100 {
+		t.Errorf("idle picks = %v, want a:300 b:100", count)
+	}
+
+	// with five calls in flight, a's effective weight drops to 3/6
+	n, _ := builder.inFlight.Load(a)
+	*n.(*int64) = 5
+	count = map[string]int{}
+	for i := 0; i < 300; i++ {
+		name, done := pick()
+		count[name]++
+		done(balancer.DoneInfo{})
+	}
+	if count["a"] != 100 || count["b"] != 200 {
+		t.Errorf("picks with a busy = %v, want a:100 b:200", count)
+	}
+
+	// counters survive the picker being rebuilt
+	builder.Build(base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{b: {}}})
+	if n, ok := builder.inFlight.Load(a); !ok || *n.(*int64) != 5 {
+		t.Error("calls in flight to a were forgotten while a was not ready")
+	}
+
+	if _, err := builder.Build(base.PickerBuildInfo{}).Pick(balancer.PickInfo{}); err != balancer.ErrNoSubConnAvailable {
+		t.Errorf("Pick without backends error = %v", err)
+	}
+}
+
+func TestParseBackends(t *testing.T) {
+	addrs, err := parseBackends([]byte("# replicas\nb:1 2\n\n  a:1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 2 || addrs[0].Addr != "a:1" || weight(addrs[0]) != 1 || addrs[1].Addr != "b:1" || weight(addrs[1]) != 2 {
+		t.Errorf("parseBackends = %v", addrs)
+	}
+	for _, src := range []string{"a", "a:1 0", "a:1 x", "a:1 1 2", "a:1\na:1"} {
+		if _, err := parseBackends([]byte(src)); err == nil {
+			t.Errorf("parseBackends(%q) succeeded, want error", src)
+		}
+	}
+}
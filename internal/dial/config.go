@@ -0,0 +1,277 @@
+// Package dial connects the example clients to their servers with a gRPC
+// service config: idempotent RPCs are retried with backoff, latency
+// sensitive reads are hedged, and both are throttled when the server keeps
+// failing.
+//
+// The config is the JSON service config of gRPC
+// (https://github.com/grpc/grpc/blob/master/doc/service_config.md), so it can
+// be kept in a file and loaded with Load. The gRPC runtime applies timeouts,
+// retry policies and retry throttling itself. It does not implement hedging
+// policies, which are carried out by a client interceptor installed by Dial.
+package dial
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/codes"
+)
+
+// maxHedgingAttempts clamps HedgingPolicy.MaxAttempts, as gRPC clamps the
+// attempts of retry policies.
+const maxHedgingAttempts = 5
+
+// Config is a gRPC service config.
+type Config struct {
+	// LoadBalancingPolicy is "pick_first", "round_robin" or
+	// WeightedLeastRequest.
+	LoadBalancingPolicy string             `json:"loadBalancingPolicy,omitempty"`
+	HealthCheckConfig   *HealthCheckConfig `json:"healthCheckConfig,omitempty"`
+	MethodConfig        []MethodConfig     `json:"methodConfig,omitempty"`
+	RetryThrottling     *RetryThrottling   `json:"retryThrottling,omitempty"`
+}
+
+// HealthCheckConfig makes the load balancer watch each backend with the
+// gRPC health service and stop using those not serving ServiceName, the
+// whole server when empty. Backends without a health service count as
+// healthy. pick_first ignores it.
+type HealthCheckConfig struct {
+	ServiceName string `json:"serviceName"`
+}
+
+// Name selects the methods a MethodConfig applies to. An empty Method
+// selects every method of Service, and an empty Name every method that is
+// not otherwise configured.
+type Name struct {
+	Service string `json:"service"`
+	Method  string `json:"method,omitempty"`
+}
+
+// MethodConfig configures the calls of the methods it names. At most one of
+// RetryPolicy and HedgingPolicy may be set.
+type MethodConfig struct {
+	Name          []Name         `json:"name"`
+	Timeout       *Duration      `json:"timeout,omitempty"`
+	RetryPolicy   *RetryPolicy   `json:"retryPolicy,omitempty"`
+	HedgingPolicy *HedgingPolicy `json:"hedgingPolicy,omitempty"`
+}
+
+// RetryPolicy retries a call that failed with one of RetryableStatusCodes,
+// waiting a random backoff of up to InitialBackoff*BackoffMultiplier^(n-1),
+// capped at MaxBackoff, before the nth retry.
+type RetryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       Duration `json:"initialBackoff"`
+	MaxBackoff           Duration `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// HedgingPolicy sends a new attempt of a call every HedgingDelay, and
+// immediately after an attempt failed with one of NonFatalStatusCodes, until
+// one attempt succeeds or fails with another code, or MaxAttempts were sent.
+type HedgingPolicy struct {
+	MaxAttempts         int      `json:"maxAttempts"`
+	HedgingDelay        Duration `json:"hedgingDelay"`
+	NonFatalStatusCodes []string `json:"nonFatalStatusCodes,omitempty"`
+}
+
+// RetryThrottling stops retries and hedged attempts while the failures of a
+// connection outweigh its successes. Each failure takes a token, each success
+// gives back TokenRatio; retries are sent while more than half of MaxTokens
+// are left.
+type RetryThrottling struct {
+	MaxTokens  float64 `json:"maxTokens"`
+	TokenRatio float64 `json:"tokenRatio"`
+}
+
+// Duration is a time.Duration written in seconds with an "s" suffix, as in
+// "0.25s", like protobuf durations in JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatFloat(time.Duration(d).Seconds(), 'f', -1, 64) + "s")
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %v", err)
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64)
+	if err != nil || !strings.HasSuffix(s, "s") {
+		return fmt.Errorf("invalid duration %q, want seconds such as \"1.5s\"", s)
+	}
+	*d = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// DefaultConfig returns the config used when none is given. Calls are
+// spread round robin over the healthy backends. Greet, Sum and
+// SquareRoot are retried while the server is unavailable. ReadBlog is
+// hedged instead, which retries it as well: a second attempt is sent when
+// the first one has not answered within 50ms.
+func DefaultConfig() *Config {
+	retry := &RetryPolicy{
+		MaxAttempts:          4,
+		InitialBackoff:       Duration(100 * time.Millisecond),
+		MaxBackoff:           Duration(time.Second),
+		BackoffMultiplier:    2,
+		RetryableStatusCodes: []string{"UNAVAILABLE"},
+	}
+	return &Config{
+		LoadBalancingPolicy: "round_robin",
+		HealthCheckConfig:   &HealthCheckConfig{},
+		MethodConfig: []MethodConfig{
+			{
+				Name: []Name{
+					{Service: "greet.GreetService", Method: "Greet"},
+					{Service: "calculator.CalculatorService", Method: "Sum"},
+					{Service: "calculator.CalculatorService", Method: "SquareRoot"},
+				},
+				RetryPolicy: retry,
+			},
+			{
+				Name: []Name{{Service: "blog.BlogService", Method: "ReadBlog"}},
+				HedgingPolicy: &HedgingPolicy{
+					MaxAttempts:         3,
+					HedgingDelay:        Duration(50 * time.Millisecond),
+					NonFatalStatusCodes: []string{"UNAVAILABLE"},
+				},
+			},
+		},
+		RetryThrottling: &RetryThrottling{MaxTokens: 10, TokenRatio: 0.1},
+	}
+}
+
+// Load reads a config from a JSON file, or returns DefaultConfig when path
+// is empty.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		return DefaultConfig(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+// Parse parses and validates a JSON config.
+func Parse(data []byte) (*Config, error) {
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// JSON returns the config in the form expected by
+// grpc.WithDefaultServiceConfig.
+func (c *Config) JSON() string {
+	b, err := json.Marshal(c)
+	if err != nil {
+		// every field has a JSON encoding
+		panic(err)
+	}
+	return string(b)
+}
+
+// Validate reports the first invalid setting of c.
+func (c *Config) Validate() error {
+	if c.LoadBalancingPolicy != "" && balancer.Get(c.LoadBalancingPolicy) == nil {
+		return fmt.Errorf("unknown loadBalancingPolicy %q", c.LoadBalancingPolicy)
+	}
+	seen := map[Name]bool{}
+	for i, mc := range c.MethodConfig {
+		if len(mc.Name) == 0 {
+			return fmt.Errorf("methodConfig[%d]: no name", i)
+		}
+		for _, n := range mc.Name {
+			if n.Service == "" && n.Method != "" {
+				return fmt.Errorf("methodConfig[%d]: method %s without a service", i, n.Method)
+			}
+			if seen[n] {
+				return fmt.Errorf("methodConfig[%d]: %s/%s configured twice", i, n.Service, n.Method)
+			}
+			seen[n] = true
+		}
+		if mc.Timeout != nil && *mc.Timeout <= 0 {
+			return fmt.Errorf("methodConfig[%d]: timeout must be positive", i)
+		}
+		if mc.RetryPolicy != nil && mc.HedgingPolicy != nil {
+			return fmt.Errorf("methodConfig[%d]: retryPolicy and hedgingPolicy are exclusive", i)
+		}
+		if p := mc.RetryPolicy; p != nil {
+			if err := p.validate(); err != nil {
+				return fmt.Errorf("methodConfig[%d].retryPolicy: %v", i, err)
+			}
+		}
+		if p := mc.HedgingPolicy; p != nil {
+			if err := p.validate(); err != nil {
+				return fmt.Errorf("methodConfig[%d].hedgingPolicy: %v", i, err)
+			}
+		}
+	}
+	if t := c.RetryThrottling; t != nil {
+		if t.MaxTokens <= 0 || t.MaxTokens > 1000 {
+			return errors.New("retryThrottling: maxTokens must be in (0, 1000]")
+		}
+		if t.TokenRatio <= 0 {
+			return errors.New("retryThrottling: tokenRatio must be positive")
+		}
+	}
+	return nil
+}
+
+func (p *RetryPolicy) validate() error {
+	switch {
+	case p.MaxAttempts < 2:
+		return errors.New("maxAttempts must be at least 2")
+	case p.InitialBackoff <= 0 || p.MaxBackoff <= 0:
+		return errors.New("backoffs must be positive")
+	case p.BackoffMultiplier <= 0:
+		return errors.New("backoffMultiplier must be positive")
+	case len(p.RetryableStatusCodes) == 0:
+		return errors.New("retryableStatusCodes must not be empty")
+	}
+	_, err := parseCodes(p.RetryableStatusCodes)
+	return err
+}
+
+func (p *HedgingPolicy) validate() error {
+	switch {
+	case p.MaxAttempts < 2:
+		return errors.New("maxAttempts must be at least 2")
+	case p.HedgingDelay < 0:
+		return errors.New("hedgingDelay must not be negative")
+	}
+	_, err := parseCodes(p.NonFatalStatusCodes)
+	return err
+}
+
+// parseCodes converts status code names such as "UNAVAILABLE".
+func parseCodes(names []string) (map[codes.Code]bool, error) {
+	set := make(map[codes.Code]bool, len(names))
+	for _, name := range names {
+		var c codes.Code
+		if err := c.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			return nil, fmt.Errorf("unknown status code %q", name)
+		}
+		set[c] = true
+	}
+	return set, nil
+}
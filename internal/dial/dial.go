@@ -0,0 +1,204 @@
+package dial
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/health" // client side health checking
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Dial connects to target with config c, DefaultConfig when nil. The config
+// is the default service config of the connection: one sent by the name
+// resolver takes precedence. Besides the targets known to grpc.Dial, target
+// may use FileScheme or DNSSRVScheme to reach several backends.
+func Dial(target string, c *Config, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if c == nil {
+		c = DefaultConfig()
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	h := newHedger(c)
+	opts = append([]grpc.DialOption{
+		grpc.WithDefaultServiceConfig(c.JSON()),
+		grpc.WithChainUnaryInterceptor(h.intercept),
+		grpc.WithResolvers(fileResolver, srvResolver),
+	}, opts...)
+	return grpc.Dial(target, opts...)
+}
+
+// hedgingPolicy is a validated HedgingPolicy.
+type hedgingPolicy struct {
+	maxAttempts int
+	delay       time.Duration
+	nonFatal    map[codes.Code]bool
+}
+
+// hedger carries out the hedging policies of a config for unary calls.
+type hedger struct {
+	// policies holds the policy of every name of the config, nil for
+	// names configured without hedging.
+	policies map[Name]*hedgingPolicy
+	throttle *throttle
+}
+
+func newHedger(c *Config) *hedger {
+	h := &hedger{policies: map[Name]*hedgingPolicy{}}
+	for _, mc := range c.MethodConfig {
+		var p *hedgingPolicy
+		if hp := mc.HedgingPolicy; hp != nil {
+			nonFatal, _ := parseCodes(hp.NonFatalStatusCodes)
+			p = &hedgingPolicy{
+				maxAttempts: hp.MaxAttempts,
+				delay:       time.Duration(hp.HedgingDelay),
+				nonFatal:    nonFatal,
+			}
+			if p.maxAttempts > maxHedgingAttempts {
+				p.maxAttempts = maxHedgingAttempts
+			}
+		}
+		for _, n := range mc.Name {
+			h.policies[n] = p
+		}
+	}
+	if t := c.RetryThrottling; t != nil {
+		h.throttle = &throttle{max: t.MaxTokens, ratio: t.TokenRatio, tokens: t.MaxTokens}
+	}
+	return h
+}
+
+// policy returns the hedging policy of the most specific name matching the
+// full method name, as in "/blog.BlogService/ReadBlog".
+func (h *hedger) policy(fullMethod string) *hedgingPolicy {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	for _, n := range []Name{{service, method}, {service, ""}, {}} {
+		if p, ok := h.policies[n]; ok {
+			return p
+		}
+	}
+	return nil
+}
+
+type attempt struct {
+	reply proto.Message
+	err   error
+}
+
+func (h *hedger) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	p := h.policy(method)
+	out, ok := reply.(proto.Message)
+	if p == nil || !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	// The losing attempts are canceled once the call returns.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan attempt, p.maxAttempts)
+	sent, pending := 0, 0
+	send := func() {
+		actx := ctx
+		if sent > 0 {
+			actx = metadata.AppendToOutgoingContext(ctx, "grpc-previous-rpc-attempts", strconv.Itoa(sent))
+		}
+		sent++
+		pending++
+		go func() {
+			r := out.ProtoReflect().New().Interface()
+			err := invoker(actx, method, req, r, cc, opts...)
+			results <- attempt{r, err}
+		}()
+	}
+	// canHedge reports whether another attempt may be sent, and stops
+	// hedging for good once the throttle refused one.
+	canHedge := func() bool {
+		if sent < p.maxAttempts && !h.throttle.allow() {
+			sent = p.maxAttempts
+		}
+		return sent < p.maxAttempts
+	}
+
+	send()
+	timer := time.NewTimer(p.delay)
+	defer timer.Stop()
+	var lastErr error
+	for {
+		var next <-chan time.Time
+		if sent < p.maxAttempts {
+			next = timer.C
+		}
+		select {
+		case <-next:
+			if canHedge() {
+				send()
+				timer.Reset(p.delay)
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				h.throttle.record(true)
+				proto.Reset(out)
+				proto.Merge(out, r.reply)
+				return nil
+			}
+			if !p.nonFatal[status.Code(r.err)] {
+				return r.err
+			}
+			h.throttle.record(false)
+			lastErr = r.err
+			if canHedge() {
+				send()
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(p.delay)
+			} else if pending == 0 {
+				return lastErr
+			}
+		}
+	}
+}
+
+// throttle is the token bucket of RetryThrottling. A nil throttle allows
+// everything.
+type throttle struct {
+	mu         sync.Mutex
+	max, ratio float64
+	tokens     float64
+}
+
+func (t *throttle) allow() bool {
+	if t == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.tokens > t.max/2
+}
+
+func (t *throttle) record(success bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if success {
+		t.tokens += t.ratio
+		if t.tokens > t.max {
+			t.tokens = t.max
+		}
+	} else if t.tokens -= 1; t.tokens < 0 {
+		t.tokens = 0
+	}
+}
@@ -0,0 +1,221 @@
+package dial_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/greet/greetpb"
+	"example.com/greet/greetservice"
+	"example.com/internal/dial"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// greeter answers Greet with the result of its greet function, which is
+// given the 0-based number of the call.
+type greeter struct {
+	*greetservice.Server
+	calls int32
+	greet func(ctx context.Context, call int) error
+}
+
+func (g *greeter) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
+	if err := g.greet(ctx, int(atomic.AddInt32(&g.calls, 1)-1)); err != nil {
+		return nil, err
+	}
+	return g.Server.Greet(ctx, req)
+}
+
+// start serves g and returns a client dialed with c.
+func start(t *testing.T, g *greeter, c *dial.Config) greetpb.GreetServiceClient {
+	t.Helper()
+	g.Server = &greetservice.Server{}
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	greetpb.RegisterGreetServiceServer(s, g)
+	go s.Serve(lis)
+	conn, err := dial.Dial("passthrough:///bufnet", c,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return greetpb.NewGreetServiceClient(conn)
+}
+
+func greetConfig(t *testing.T, policy string) *dial.Config {
+	t.Helper()
+	c, err := dial.Parse([]byte(`{
+		"methodConfig": [{
+			"name": [{"service": "greet.GreetService", "method": "Greet"}],
+			` + policy + `
+		}],
+		"retryThrottling": {"maxTokens": 4, "tokenRatio": 0.5}
+	}`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return c
+}
+
+func TestRetry(t *testing.T) {
+	g := &greeter{greet: func(ctx context.Context, call int) error {
+		if call < 2 {
+			return status.Error(codes.Unavailable, "warming up")
+		}
+		return nil
+	}}
+	c := start(t, g, nil)
+	res, err := c.Greet(context.Background(), &greetpb.GreetRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}})
+	if err != nil || res.GetResult() != "hello rahul" {
+		t.Fatalf("Greet = %v, %v after two failures, want a retried success", res, err)
+	}
+	if g.calls != 3 {
+		t.Errorf("server saw %d calls, want 3", g.calls)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	g := &greeter{greet: func(ctx context.Context, call int) error {
+		return status.Error(codes.InvalidArgument, "bad")
+	}}
+	c := start(t, g, nil)
+	if _, err := c.Greet(context.Background(), &greetpb.GreetRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Greet error = %v, want InvalidArgument", err)
+	}
+	if g.calls != 1 {
+		t.Errorf("server saw %d calls, want 1", g.calls)
+	}
+}
+
+func TestHedging(t *testing.T) {
+	previous := make(chan []string, 3)
+	g := &greeter{greet: func(ctx context.Context, call int) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		previous <- md.Get("grpc-previous-rpc-attempts")
+		if call == 0 {
+			// the first attempt is stuck until the hedged one wins
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}}
+	c := start(t, g, greetConfig(t, `"hedgingPolicy": {"maxAttempts": 3, "hedgingDelay": "0.02s"}`))
+	res, err := c.Greet(context.Background(), &greetpb.GreetRequest{Greeting: &greetpb.Greeting{FirstName: "rahul"}})
+	if err != nil || res.GetResult() != "hello rahul" {
+		t.Fatalf("Greet = %v, %v, want the hedged answer", res, err)
+	}
+	if got := atomic.LoadInt32(&g.calls); got != 2 {
+		t.Errorf("server saw %d calls, want 2", got)
+	}
+	got := []string{strings.Join(<-previous, ","), strings.Join(<-previous, ",")}
+	if !(got[0] == "" && got[1] == "1" || got[0] == "1" && got[1] == "") {
+		t.Errorf("grpc-previous-rpc-attempts = %q, want \"\" and \"1\"", got)
+	}
+}
+
+func TestHedgingFailures(t *testing.T) {
+	g := &greeter{greet: func(ctx context.Context, call int) error {
+		if call == 0 {
+			return status.Error(codes.Unavailable, "down")
+		}
+		return status.Error(codes.NotFound, "gone")
+	}}
+	c := start(t, g, greetConfig(t, `"hedgingPolicy": {"maxAttempts": 5, "hedgingDelay": "10s", "nonFatalStatusCodes": ["UNAVAILABLE"]}`))
+	// a non-fatal failure sends the next attempt right away, and a fatal
+	// one ends the call
+	begin := time.Now()
+	if _, err := c.Greet(context.Background(), &greetpb.GreetRequest{}); status.Code(err) != codes.NotFound {
+		t.Errorf("Greet error = %v, want NotFound", err)
+	}
+	if g.calls != 2 || time.Since(begin) > 5*time.Second {
+		t.Errorf("server saw %d calls in %v, want 2 without waiting for the delay", g.calls, time.Since(begin))
+	}
+}
+
+func TestHedgingThrottled(t *testing.T) {
+	g := &greeter{greet: func(ctx context.Context, call int) error {
+		return status.Error(codes.Unavailable, "down")
+	}}
+	c := start(t, g, greetConfig(t, `"hedgingPolicy": {"maxAttempts": 5, "hedgingDelay": "10s", "nonFatalStatusCodes": ["UNAVAILABLE"]}`))
+	// Four tokens, each failure taking one: attempts are hedged while more
+	// than two are left, so the first call sends two attempts and later
+	// ones a single attempt.
+	for i := 0; i < 3; i++ {
+		if _, err := c.Greet(context.Background(), &greetpb.GreetRequest{}); status.Code(err) != codes.Unavailable {
+			t.Errorf("Greet error = %v, want Unavailable", err)
+		}
+	}
+	if g.calls != 4 {
+		t.Errorf("server saw %d calls, want 4", g.calls)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	c := dial.DefaultConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("DefaultConfig: %v", err)
+	}
+	round, err := dial.Parse([]byte(c.JSON()))
+	if err != nil {
+		t.Fatalf("Parse(DefaultConfig().JSON()): %v", err)
+	}
+	if round.JSON() != c.JSON() {
+		t.Errorf("config changed through JSON:\n%s\n%s", c.JSON(), round.JSON())
+	}
+	if !strings.Contains(c.JSON(), `"initialBackoff":"0.1s"`) {
+		t.Errorf("durations are not written in seconds: %s", c.JSON())
+	}
+
+	loaded, err := dial.Load("testdata/service_config.json")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p := loaded.MethodConfig[1].HedgingPolicy; p == nil || time.Duration(p.HedgingDelay) != 100*time.Millisecond {
+		t.Errorf("loaded ReadBlog hedging policy %+v, want a 100ms delay", p)
+	}
+	if loaded.LoadBalancingPolicy != dial.WeightedLeastRequest {
+		t.Errorf("loaded policy %q", loaded.LoadBalancingPolicy)
+	}
+	if _, err := dial.Load("testdata/missing.json"); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+
+	retry := `"retryPolicy": {"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}`
+	invalid := []string{
+		`{"methodConfig": [{"name": []}]}`,
+		`{"methodConfig": [{"name": [{"method": "Greet"}]}]}`,
+		`{"methodConfig": [{"name": [{"service": "s"}]}, {"name": [{"service": "s"}]}]}`,
+		`{"methodConfig": [{"name": [{"service": "s"}], "timeout": "soon"}]}`,
+		`{"methodConfig": [{"name": [{"service": "s"}], "timeout": "0s"}]}`,
+		`{"methodConfig": [{"name": [{"service": "s"}], "retryPolicy": {"maxAttempts": 1}}]}`,
+		`{"methodConfig": [{"name": [{"service": "s"}], "retryPolicy": {"maxAttempts": 3, "initialBackoff": "0.1s", "maxBackoff": "1s", "backoffMultiplier": 2, "retryableStatusCodes": ["NOPE"]}}]}`,
+		`{"methodConfig": [{"name": [{"service": "s"}], ` + retry + `, "hedgingPolicy": {"maxAttempts": 2}}]}`,
+		`{"methodConfig": [{"name": [{"service": "s"}], "hedgingPolicy": {"maxAttempts": 2, "hedgingDelay": "-1s"}}]}`,
+		`{"retryThrottling": {"maxTokens": 0, "tokenRatio": 0.1}}`,
+		`{"loadBalancingPolicy": "random"}`,
+	}
+	for _, src := range invalid {
+		if _, err := dial.Parse([]byte(src)); err == nil {
+			t.Errorf("Parse(%s) succeeded, want error", src)
+		}
+	}
+	if _, err := dial.Parse([]byte(`{"methodConfig": [{"name": [{}], ` + retry + `}]}`)); err != nil {
+		t.Errorf("Parse of a default method config: %v", err)
+	}
+}
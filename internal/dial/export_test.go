@@ -0,0 +1,18 @@
+package dial
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// SetResolvers makes the resolvers poll every interval and look SRV records
+// up with lookup for the duration of the test.
+func SetResolvers(t *testing.T, interval time.Duration, lookup func(ctx context.Context, name string) ([]*net.SRV, error)) {
+	oldFile, oldSRV, oldLookup := filePollInterval, srvRefreshInterval, lookupSRV
+	filePollInterval, srvRefreshInterval, lookupSRV = interval, interval, lookup
+	t.Cleanup(func() {
+		filePollInterval, srvRefreshInterval, lookupSRV = oldFile, oldSRV, oldLookup
+	})
+}
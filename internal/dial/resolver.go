@@ -0,0 +1,226 @@
+package dial
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// Schemes of the targets resolved by this package, besides those of gRPC.
+//
+// A "file" target such as "file:///etc/blog/backends" names a file listing
+// one backend per line as "host:port [weight]". Blank lines and lines
+// starting with "#" are ignored. The file is read again every few seconds,
+// so backends can be added and removed without restarting the client.
+//
+// A "dnssrv" target such as "dnssrv:///_grpc._tcp.blog.example.com" names
+// DNS SRV records. The records of the lowest priority are used, weighted
+// by their weight, and looked up again every half minute.
+//
+// The authority of these targets is not a host name, so connections using
+// TLS need grpc.WithAuthority to check the certificate of the backends.
+const (
+	FileScheme   = "file"
+	DNSSRVScheme = "dnssrv"
+)
+
+// Refresh periods of the resolvers; variables for tests.
+var (
+	filePollInterval   = 2 * time.Second
+	srvRefreshInterval = 30 * time.Second
+	lookupSRV          = func(ctx context.Context, name string) ([]*net.SRV, error) {
+		_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+		return srvs, err
+	}
+)
+
+// pollingBuilder builds resolvers that call resolve every interval and
+// whenever gRPC asks to resolve again.
+type pollingBuilder struct {
+	scheme   string
+	interval func() time.Duration
+	resolve  func(ctx context.Context, target resolver.Target) ([]resolver.Address, error)
+}
+
+func (b *pollingBuilder) Scheme() string { return b.scheme }
+
+func (b *pollingBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	if target.Endpoint() == "" {
+		return nil, fmt.Errorf("%s target %q names no %s", b.scheme, target.URL.String(), b.scheme)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &pollingResolver{
+		builder: b,
+		target:  target,
+		cc:      cc,
+		cancel:  cancel,
+		now:     make(chan struct{}, 1),
+	}
+	r.wg.Add(1)
+	go r.run(ctx)
+	return r, nil
+}
+
+type pollingResolver struct {
+	builder *pollingBuilder
+	target  resolver.Target
+	cc      resolver.ClientConn
+	cancel  context.CancelFunc
+	now     chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (r *pollingResolver) run(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.builder.interval())
+	defer ticker.Stop()
+	var last []resolver.Address
+	for {
+		addrs, err := r.builder.resolve(ctx, r.target)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			r.cc.ReportError(err)
+		case len(addrs) == 0:
+			r.cc.ReportError(fmt.Errorf("%s: no backends", r.target.URL.String()))
+		case !sameAddresses(addrs, last):
+			if r.cc.UpdateState(resolver.State{Addresses: addrs}) == nil {
+				last = addrs
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.now:
+		}
+	}
+}
+
+func (r *pollingResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.now <- struct{}{}:
+	default:
+	}
+}
+
+func (r *pollingResolver) Close() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func sameAddresses(a, b []resolver.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Addr != b[i].Addr || weight(a[i]) != weight(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+var fileResolver = &pollingBuilder{
+	scheme:   FileScheme,
+	interval: func() time.Duration { return filePollInterval },
+	resolve: func(_ context.Context, target resolver.Target) ([]resolver.Address, error) {
+		path := target.URL.Path
+		if path == "" {
+			path = target.URL.Opaque
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		addrs, err := parseBackends(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return addrs, nil
+	},
+}
+
+// parseBackends parses the lines of a backend file, sorted by address.
+func parseBackends(data []byte) ([]resolver.Address, error) {
+	var addrs []resolver.Address
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: want \"host:port [weight]\"", n)
+		}
+		if _, _, err := net.SplitHostPort(fields[0]); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		w := uint64(1)
+		if len(fields) == 2 {
+			var err error
+			w, err = strconv.ParseUint(fields[1], 10, 32)
+			if err != nil || w == 0 {
+				return nil, fmt.Errorf("line %d: weight must be a positive integer", n)
+			}
+		}
+		if seen[fields[0]] {
+			return nil, fmt.Errorf("line %d: duplicate backend %s", n, fields[0])
+		}
+		seen[fields[0]] = true
+		addrs = append(addrs, WithWeight(resolver.Address{Addr: fields[0]}, uint32(w)))
+	}
+	sort.Slice(addrs, func(i, j int) bool { return addrs[i].Addr < addrs[j].Addr })
+	return addrs, sc.Err()
+}
+
+var srvResolver = &pollingBuilder{
+	scheme:   DNSSRVScheme,
+	interval: func() time.Duration { return srvRefreshInterval },
+	resolve: func(ctx context.Context, target resolver.Target) ([]resolver.Address, error) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		srvs, err := lookupSRV(ctx, target.Endpoint())
+		if err != nil {
+			return nil, err
+		}
+		if len(srvs) == 0 {
+			return nil, errors.New("no SRV records")
+		}
+		priority := srvs[0].Priority
+		for _, srv := range srvs {
+			if srv.Priority < priority {
+				priority = srv.Priority
+			}
+		}
+		var addrs []resolver.Address
+		for _, srv := range srvs {
+			if srv.Priority != priority {
+				continue
+			}
+			w := uint32(srv.Weight)
+			if w == 0 {
+				// weight 0 records are picked rarely, not never
+				w = 1
+			}
+			addr := net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port)))
+			addrs = append(addrs, WithWeight(resolver.Address{Addr: addr}, w))
+		}
+		sort.Slice(addrs, func(i, j int) bool { return addrs[i].Addr < addrs[j].Addr })
+		return addrs, nil
+	},
+}
@@ -0,0 +1,181 @@
+package dial_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/greet/greetpb"
+	"example.com/greet/greetservice"
+	"example.com/internal/dial"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// replicas are in-memory greet servers reachable as "replica-<i>:1".
+type replicas struct {
+	calls     []int32
+	health    []*health.Server
+	listeners map[string]*bufconn.Listener
+}
+
+func startReplicas(t *testing.T, n int) *replicas {
+	t.Helper()
+	r := &replicas{calls: make([]int32, n), listeners: map[string]*bufconn.Listener{}}
+	for i := 0; i < n; i++ {
+		i := i
+		lis := bufconn.Listen(1 << 20)
+		s := grpc.NewServer()
+		greetpb.RegisterGreetServiceServer(s, &greeter{Server: &greetservice.Server{}, greet: func(context.Context, int) error {
+			atomic.AddInt32(&r.calls[i], 1)
+			return nil
+		}})
+		hs := health.NewServer()
+		healthpb.RegisterHealthServer(s, hs)
+		go s.Serve(lis)
+		t.Cleanup(s.Stop)
+		r.health = append(r.health, hs)
+		r.listeners[fmt.Sprintf("replica-%d:1", i)] = lis
+	}
+	return r
+}
+
+func (r *replicas) dial(t *testing.T, target string, c *dial.Config) greetpb.GreetServiceClient {
+	t.Helper()
+	conn, err := dial.Dial(target, c,
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			lis, ok := r.listeners[addr]
+			if !ok {
+				return nil, fmt.Errorf("no replica at %s", addr)
+			}
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return greetpb.NewGreetServiceClient(conn)
+}
+
+// spread makes n calls and returns how many each replica received.
+func (r *replicas) spread(t *testing.T, c greetpb.GreetServiceClient, n int) []int32 {
+	t.Helper()
+	before := make([]int32, len(r.calls))
+	for i := range r.calls {
+		before[i] = atomic.LoadInt32(&r.calls[i])
+	}
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := c.Greet(ctx, &greetpb.GreetRequest{}, grpc.WaitForReady(true))
+		cancel()
+		if err != nil {
+			t.Fatalf("Greet: %v", err)
+		}
+	}
+	for i := range r.calls {
+		before[i] = atomic.LoadInt32(&r.calls[i]) - before[i]
+	}
+	return before
+}
+
+// waitSpread makes batches of n calls until one is spread as want.
+func (r *replicas) waitSpread(t *testing.T, c greetpb.GreetServiceClient, n int, want func([]int32) bool) []int32 {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		got := r.spread(t, c, n)
+		if want(got) {
+			return got
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("calls still spread as %v", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func writeBackends(t *testing.T, path, content string) {
+	t.Helper()
+	// replace the file at once so the resolver never reads half of it
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	dial.SetResolvers(t, 10*time.Millisecond, nil)
+	r := startReplicas(t, 3)
+	path := filepath.Join(t.TempDir(), "backends")
+	writeBackends(t, path, "# greet replicas\nreplica-0:1\nreplica-1:1\n")
+	c := r.dial(t, "file://"+path, nil)
+
+	// round robin over both once they are connected
+	r.waitSpread(t, c, 10, func(got []int32) bool { return got[0] == 5 && got[1] == 5 })
+
+	// an unhealthy replica is ejected, and comes back once serving again
+	r.health[1].SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	r.waitSpread(t, c, 10, func(got []int32) bool { return got[0] == 10 })
+	r.health[1].SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	r.waitSpread(t, c, 10, func(got []int32) bool { return got[0] == 5 && got[1] == 5 })
+
+	// the file is watched for changes
+	writeBackends(t, path, "replica-2:1\n")
+	r.waitSpread(t, c, 10, func(got []int32) bool { return got[2] == 10 })
+}
+
+func TestFileResolverWeights(t *testing.T) {
+	dial.SetResolvers(t, 10*time.Millisecond, nil)
+	r := startReplicas(t, 2)
+	path := filepath.Join(t.TempDir(), "backends")
+	writeBackends(t, path, "replica-0:1 1\nreplica-1:1 1\n")
+	config := dial.DefaultConfig()
+	config.LoadBalancingPolicy = dial.WeightedLeastRequest
+	c := r.dial(t, "file://"+path, config)
+	r.waitSpread(t, c, 100, func(got []int32) bool { return got[0] == 50 && got[1] == 50 })
+
+	// a change of weight alone is picked up by the connected backends
+	writeBackends(t, path, "replica-0:1 1\nreplica-1:1 3\n")
+	r.waitSpread(t, c, 100, func(got []int32) bool { return got[0] == 25 && got[1] == 75 })
+}
+
+func TestDNSSRVResolver(t *testing.T) {
+	lookups := make(chan string, 100)
+	dial.SetResolvers(t, time.Hour, func(ctx context.Context, name string) ([]*net.SRV, error) {
+		select {
+		case lookups <- name:
+		default:
+		}
+		return []*net.SRV{
+			{Target: "replica-0.", Port: 1, Priority: 10, Weight: 3},
+			{Target: "replica-1.", Port: 1, Priority: 10, Weight: 1},
+			// a backup that is only used when the others are gone
+			{Target: "replica-2.", Port: 1, Priority: 20, Weight: 100},
+		}, nil
+	})
+	r := startReplicas(t, 3)
+	config := dial.DefaultConfig()
+	config.LoadBalancingPolicy = dial.WeightedLeastRequest
+	c := r.dial(t, "dnssrv:///_grpc._tcp.greet.test", config)
+
+	got := r.waitSpread(t, c, 400, func(got []int32) bool { return got[0] == 300 && got[1] == 100 })
+	if got[2] != 0 {
+		t.Errorf("backup replica got %d calls", got[2])
+	}
+	if name := <-lookups; name != "_grpc._tcp.greet.test" {
+		t.Errorf("looked up %q", name)
+	}
+}
@@ -0,0 +1,81 @@
+// Package harness runs the services in-process on a bufconn listener so tests
+// can exercise them through real generated clients without opening sockets.
+package harness
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"example.com/blog/blogpb"
+	"example.com/blog/blogservice"
+	"example.com/blog/blogstore"
+	"example.com/calculator/calculatorpb"
+	"example.com/calculator/calculatorservice"
+	"example.com/greet/greetpb"
+	"example.com/greet/greetservice"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024
+
+// Start serves the services added by register on an in-memory listener and
+// returns a connection to it. Server and connection are shut down when the
+// test finishes.
+func Start(t testing.TB, register func(*grpc.Server), opts ...grpc.ServerOption) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer(opts...)
+	register(s)
+	go s.Serve(lis)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial bufnet: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return conn
+}
+
+// Greet returns a client of a GreetService whose streaming and deadline RPCs
+// pause for interval instead of one second.
+func Greet(t testing.TB, interval time.Duration) greetpb.GreetServiceClient {
+	t.Helper()
+	conn := Start(t, func(s *grpc.Server) {
+		greetpb.RegisterGreetServiceServer(s, &greetservice.Server{Interval: interval})
+	})
+	return greetpb.NewGreetServiceClient(conn)
+}
+
+// Calculator returns a client of a CalculatorService.
+func Calculator(t testing.TB) calculatorpb.CalculatorServiceClient {
+	t.Helper()
+	conn := Start(t, func(s *grpc.Server) {
+		calculatorpb.RegisterCalculatorServiceServer(s, &calculatorservice.Server{})
+	})
+	return calculatorpb.NewCalculatorServiceClient(conn)
+}
+
+// Blog returns a client of a BlogService backed by store. A nil store is
+// replaced by an empty in-memory one.
+func Blog(t testing.TB, store blogstore.Store) blogpb.BlogServiceClient {
+	t.Helper()
+	if store == nil {
+		store = blogstore.NewMemory()
+	}
+	conn := Start(t, func(s *grpc.Server) {
+		blogpb.RegisterBlogServiceServer(s, blogservice.New(store))
+	})
+	return blogpb.NewBlogServiceClient(conn)
+}
@@ -0,0 +1,65 @@
+// Package httpserve exposes a grpc.Server over plain HTTP so that browsers
+// can call it with gRPC-Web or the Connect protocol, while native gRPC
+// clients keep working on the same listener.
+package httpserve
+
+import (
+	"net/http"
+	"strings"
+
+	connectcors "connectrpc.com/cors"
+	"connectrpc.com/vanguard/vanguardgrpc"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// Handler returns a handler serving every service registered on s over
+// native gRPC, gRPC-Web and Connect, including server-streaming RPCs.
+// HTTP/1.1 and cleartext HTTP/2 (h2c) are both accepted, so the handler can
+// be passed straight to http.Serve. Cross-origin requests are allowed from
+// allowedOrigins only; a single "*" allows every origin, which lets any web
+// page a user visits call the server with their credentials.
+func Handler(s *grpc.Server, allowedOrigins ...string) (http.Handler, error) {
+	transcoder, err := vanguardgrpc.NewTranscoder(s)
+	if err != nil {
+		return nil, err
+	}
+	return h2c.NewHandler(withCORS(transcoder, allowedOrigins), &http2.Server{}), nil
+}
+
+// withCORS answers preflight requests and adds the headers browsers need to
+// read gRPC-Web and Connect responses from another origin.
+func withCORS(next http.Handler, allowedOrigins []string) http.Handler {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			allowed[origin] = true
+		}
+	}
+	if len(allowed) == 0 {
+		return next
+	}
+	methods := strings.Join(connectcors.AllowedMethods(), ", ")
+	headers := strings.Join(append(connectcors.AllowedHeaders(), "Authorization"), ", ")
+	exposed := strings.Join(connectcors.ExposedHeaders(), ", ")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !(allowed["*"] || allowed[origin]) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+		h.Set("Access-Control-Expose-Headers", exposed)
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", methods)
+			h.Set("Access-Control-Allow-Headers", headers)
+			h.Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,99 @@
+// Package ratelimit limits how much each caller may ask of a server: calls
+// per second overall and per method, concurrent streams per method, and
+// messages per second on each stream.
+//
+// Callers are told apart by the identity an Authenticator vouches for, and
+// by their IP address otherwise. Calls relayed by a trusted proxy, such as
+// the REST gateway of the blog server, are told apart by the client address
+// the proxy adds to the x-forwarded-for metadata, which is ignored from
+// other callers since they could make it up.
+//
+// Calls over a limit fail with codes.ResourceExhausted and a
+// google.rpc.RetryInfo detail saying when to try again.
+//
+// A Config is the "rateLimits" section of the server config file (see
+// internal/serverconfig), as in
+//
+//	{
+//	  "caller": {"perSecond": 50, "burst": 100},
+//	  "methods": {
+//	    "/blog.BlogService/CreateBlog": {"calls": {"perSecond": 1, "burst": 5}},
+//	    "/calculator.CalculatorService/FindMaximum": {
+//	      "maxStreams": 10,
+//	      "messages": {"perSecond": 100, "burst": 200}
+//	    },
+//	    "*": {"maxStreams": 100}
+//	  }
+//	}
+//
+// where "caller" applies to all calls of a caller together, and the limits
+// of a method, or of "*" for methods not listed, to the calls of a caller
+// to that method. Limits that are left out do not apply.
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Config holds the limits of a server.
+type Config struct {
+	Caller  *Rate                   `json:"caller,omitempty"`
+	Methods map[string]MethodLimits `json:"methods,omitempty"`
+}
+
+// Rate is a token bucket refilled with PerSecond tokens a second and
+// holding at most Burst tokens; each call or message takes one.
+type Rate struct {
+	PerSecond float64 `json:"perSecond"`
+	Burst     int     `json:"burst"`
+}
+
+// MethodLimits are the limits of each caller of a method.
+type MethodLimits struct {
+	Calls *Rate `json:"calls,omitempty"`
+	// MaxStreams caps the concurrent streams of a streaming method.
+	MaxStreams int `json:"maxStreams,omitempty"`
+	// Messages limits the messages received on each stream.
+	Messages *Rate `json:"messages,omitempty"`
+}
+
+// Validate reports the first invalid limit of c.
+func (c *Config) Validate() error {
+	if err := c.Caller.validate(); err != nil {
+		return fmt.Errorf("caller: %v", err)
+	}
+	for method, m := range c.Methods {
+		if method != "*" && (!strings.HasPrefix(method, "/") || strings.Count(method, "/") != 2) {
+			return fmt.Errorf("invalid method %q, want /package.Service/Method or *", method)
+		}
+		if err := m.Calls.validate(); err != nil {
+			return fmt.Errorf("%s: calls: %v", method, err)
+		}
+		if err := m.Messages.validate(); err != nil {
+			return fmt.Errorf("%s: messages: %v", method, err)
+		}
+		if m.MaxStreams < 0 {
+			return fmt.Errorf("%s: maxStreams must not be negative", method)
+		}
+	}
+	return nil
+}
+
+func (r *Rate) validate() error {
+	if r == nil {
+		return nil
+	}
+	if r.PerSecond <= 0 || r.Burst < 1 {
+		return fmt.Errorf("perSecond must be positive and burst at least 1")
+	}
+	return nil
+}
+
+// method returns the limits of the full method name.
+func (c *Config) method(name string) MethodLimits {
+	if m, ok := c.Methods[name]; ok {
+		return m
+	}
+	return c.Methods["*"]
+}
@@ -0,0 +1,273 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// streamRetryDelay is the retry delay suggested to callers over their
+// stream cap, which frees up at an unknown time.
+const streamRetryDelay = time.Second
+
+// sweepInterval is how often the buckets of idle callers are dropped.
+const sweepInterval = time.Minute
+
+// An Authenticator returns the identity of the caller of ctx once it has
+// checked its credentials, and false for anonymous callers.
+type Authenticator func(ctx context.Context) (identity string, ok bool)
+
+// Options tell a Limiter who its callers are.
+type Options struct {
+	// Authenticator identifies callers. With a nil Authenticator, every
+	// caller is known by its address.
+	Authenticator Authenticator
+	// Proxy reports whether ctx is a call relayed by a trusted proxy, such
+	// as the REST gateway of the blog server, which names the client in
+	// the x-forwarded-for metadata. With a nil Proxy, that metadata is
+	// ignored.
+	Proxy func(ctx context.Context) bool
+}
+
+// Limiter enforces a Config. Its config can be replaced while it is in use.
+type Limiter struct {
+	opts   Options
+	config atomic.Pointer[Config]
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	streams   map[bucketKey]int
+	lastSweep time.Time
+}
+
+// bucketKey identifies the calls of a caller to a method, or all calls of
+// the caller when method is empty.
+type bucketKey struct {
+	caller, method string
+}
+
+// New returns a limiter enforcing c on the callers o tells apart.
+func New(c *Config, o Options) *Limiter {
+	l := &Limiter{
+		opts:    o,
+		now:     time.Now,
+		buckets: map[bucketKey]*bucket{},
+		streams: map[bucketKey]int{},
+	}
+	l.config.Store(c)
+	return l
+}
+
+// SetConfig replaces the config of l. Callers start over with full buckets;
+// streams in flight keep counting against the new caps.
+func (l *Limiter) SetConfig(c *Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config.Store(c)
+	l.buckets = map[bucketKey]*bucket{}
+}
+
+// UnaryServerInterceptor limits the rate of unary calls.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := l.allowCall(l.callerID(ctx), info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor limits the rate and number of concurrent streams,
+// and the rate of the messages received on each.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		caller := l.callerID(ss.Context())
+		if err := l.allowCall(caller, info.FullMethod); err != nil {
+			return err
+		}
+		release, err := l.acquireStream(caller, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if r := l.config.Load().method(info.FullMethod).Messages; r != nil {
+			ss = &limitedStream{ServerStream: ss, bucket: newBucket(r, l.now()), now: l.now}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// allowCall takes a token from the caller bucket and the method bucket of
+// the caller, or from neither when one is empty.
+func (l *Limiter) allowCall(caller, method string) error {
+	c := l.config.Load()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	var buckets []*bucket
+	if c.Caller != nil {
+		buckets = append(buckets, l.bucket(bucketKey{caller, ""}, c.Caller, now))
+	}
+	if r := c.method(method).Calls; r != nil {
+		buckets = append(buckets, l.bucket(bucketKey{caller, method}, r, now))
+	}
+	var wait time.Duration
+	for _, b := range buckets {
+		if w := b.wait(now); w > wait {
+			wait = w
+		}
+	}
+	if wait > 0 {
+		return exhausted(wait, "rate limit of %s exceeded", method)
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return nil
+}
+
+func (l *Limiter) bucket(key bucketKey, r *Rate, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(r, now)
+		l.buckets[key] = b
+	}
+	return b
+}
+
+// sweep drops the buckets that refilled, which are no different from new
+// ones.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		b.wait(now)
+		if b.tokens >= float64(b.rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *Limiter) acquireStream(caller, method string) (release func(), err error) {
+	max := l.config.Load().method(method).MaxStreams
+	key := bucketKey{caller, method}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if max > 0 && l.streams[key] >= max {
+		return nil, exhausted(streamRetryDelay, "at most %d concurrent %s streams", max, method)
+	}
+	l.streams[key]++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.streams[key]--; l.streams[key] <= 0 {
+			delete(l.streams, key)
+		}
+	}, nil
+}
+
+// bucket is a token bucket.
+type bucket struct {
+	rate   *Rate
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(r *Rate, now time.Time) *bucket {
+	return &bucket{rate: r, tokens: float64(r.Burst), last: now}
+}
+
+// wait refills b and returns how long until it holds a token.
+func (b *bucket) wait(now time.Time) time.Duration {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(b.rate.Burst), b.tokens+elapsed.Seconds()*b.rate.PerSecond)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate.PerSecond * float64(time.Second))
+}
+
+// limitedStream fails RecvMsg once the client sends messages faster than
+// its bucket allows.
+type limitedStream struct {
+	grpc.ServerStream
+	bucket *bucket
+	now    func() time.Time
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if wait := s.bucket.wait(s.now()); wait > 0 {
+		return exhausted(wait, "message rate limit exceeded")
+	}
+	s.bucket.tokens--
+	return nil
+}
+
+// callerID identifies the caller of ctx by its authenticated identity, or
+// by its IP address.
+func (l *Limiter) callerID(ctx context.Context) string {
+	if l.opts.Authenticator != nil {
+		if id, ok := l.opts.Authenticator(ctx); ok {
+			return "id:" + id
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if l.opts.Proxy != nil && l.opts.Proxy(ctx) {
+		if client := forwardedFor(ctx); client != "" {
+			return "ip:" + client
+		}
+	}
+	return "ip:" + addr
+}
+
+// forwardedFor returns the client address a proxy added last to the
+// x-forwarded-for metadata.
+func forwardedFor(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	v := md.Get("x-forwarded-for")
+	if len(v) == 0 {
+		return ""
+	}
+	hops := strings.Split(v[len(v)-1], ",")
+	return strings.TrimSpace(hops[len(hops)-1])
+}
+
+// exhausted returns a ResourceExhausted error telling the caller to retry
+// after wait.
+func exhausted(wait time.Duration, format string, args ...interface{}) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf(format, args...))
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
@@ -0,0 +1,255 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// clock is a fake time source.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newLimiter(t *testing.T, config string, auth Authenticator) (*Limiter, *clock) {
+	t.Helper()
+	var c Config
+	if err := json.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	l := New(&c, Options{Authenticator: auth})
+	clk := &clock{t: time.Unix(1000, 0)}
+	l.now = clk.now
+	return l, clk
+}
+
+func fromIP(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 4242}})
+}
+
+func withMetadata(ctx context.Context, kv ...string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(kv...))
+}
+
+// tokenAuth authenticates the callers sending one of the tokens it knows.
+func tokenAuth(tokens map[string]string) Authenticator {
+	return func(ctx context.Context) (string, bool) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, auth := range md.Get("authorization") {
+			if id, ok := tokens[strings.TrimPrefix(auth, "Bearer ")]; ok {
+				return id, true
+			}
+		}
+		return "", false
+	}
+}
+
+// retryDelay returns the RetryInfo delay of a ResourceExhausted error.
+func retryDelay(t *testing.T, err error) time.Duration {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("error = %v, want ResourceExhausted", err)
+	}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.RetryInfo); ok {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	t.Fatalf("error %v has no RetryInfo", err)
+	return 0
+}
+
+func TestUnaryLimits(t *testing.T) {
+	l, clock := newLimiter(t, `{
+		"caller": {"perSecond": 10, "burst": 3},
+		"methods": {"/blog.BlogService/CreateBlog": {"calls": {"perSecond": 0.5, "burst": 1}}}
+	}`, nil)
+	intercept := l.UnaryServerInterceptor()
+	call := func(ctx context.Context, method string) error {
+		_, err := intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+	const create, read = "/blog.BlogService/CreateBlog", "/blog.BlogService/ReadBlog"
+	alice := fromIP("10.0.0.1")
+
+	if err := call(alice, create); err != nil {
+		t.Fatalf("first CreateBlog: %v", err)
+	}
+	if d := retryDelay(t, call(alice, create)); d != 2*time.Second {
+		t.Errorf("second CreateBlog retry delay = %v, want 2s", d)
+	}
+	// the refused call took no token from the caller bucket
+	for i := 0; i < 2; i++ {
+		if err := call(alice, read); err != nil {
+			t.Fatalf("ReadBlog %d: %v", i, err)
+		}
+	}
+	if d := retryDelay(t, call(alice, read)); d != 100*time.Millisecond {
+		t.Errorf("ReadBlog over the caller burst retry delay = %v, want 100ms", d)
+	}
+
+	// other callers have their own buckets, told apart by IP
+	if err := call(fromIP("10.0.0.2"), create); err != nil {
+		t.Errorf("CreateBlog of another caller: %v", err)
+	}
+
+	clock.advance(2 * time.Second)
+	if err := call(alice, create); err != nil {
+		t.Errorf("CreateBlog after the bucket refilled: %v", err)
+	}
+}
+
+// fakeStream is a stream whose client sends messages without end.
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context    { return s.ctx }
+func (s *fakeStream) RecvMsg(m interface{}) error { return nil }
+
+func TestStreamLimits(t *testing.T) {
+	l, clock := newLimiter(t, `{"methods": {
+		"/calculator.CalculatorService/FindMaximum": {"maxStreams": 2, "messages": {"perSecond": 1, "burst": 2}}
+	}}`, nil)
+	intercept := l.StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/calculator.CalculatorService/FindMaximum"}
+	stream := &fakeStream{ctx: fromIP("10.0.0.1")}
+
+	// two streams are held open while a third is refused
+	release := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- intercept(nil, stream, info, func(interface{}, grpc.ServerStream) error {
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+		}()
+		<-started
+	}
+	if d := retryDelay(t, intercept(nil, stream, info, func(interface{}, grpc.ServerStream) error { return nil })); d != time.Second {
+		t.Errorf("third stream retry delay = %v, want 1s", d)
+	}
+	if err := intercept(nil, &fakeStream{ctx: fromIP("10.0.0.2")}, info, func(interface{}, grpc.ServerStream) error { return nil }); err != nil {
+		t.Errorf("stream of another caller: %v", err)
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("held stream: %v", err)
+		}
+	}
+
+	// messages beyond the burst are refused until the bucket refills
+	err := intercept(nil, stream, info, func(_ interface{}, ss grpc.ServerStream) error {
+		for i := 0; i < 2; i++ {
+			if err := ss.RecvMsg(nil); err != nil {
+				t.Errorf("message %d: %v", i, err)
+			}
+		}
+		if d := retryDelay(t, ss.RecvMsg(nil)); d != time.Second {
+			t.Errorf("third message retry delay = %v, want 1s", d)
+		}
+		clock.advance(time.Second)
+		return ss.RecvMsg(nil)
+	})
+	if err != nil {
+		t.Errorf("message after the bucket refilled: %v", err)
+	}
+}
+
+func TestCallerID(t *testing.T) {
+	alice := fromIP("10.0.0.1")
+	local := fromIP("127.0.0.1")
+	tokens := Options{Authenticator: tokenAuth(map[string]string{"secret": "bob"})}
+	// the proxy proves itself with metadata other callers cannot know
+	proxy := Options{Proxy: func(ctx context.Context) bool {
+		md, _ := metadata.FromIncomingContext(ctx)
+		v := md.Get("x-proxy-key")
+		return len(v) > 0 && v[0] == "key"
+	}}
+	relayed := func(forwardedFor string) context.Context {
+		return withMetadata(local, "x-proxy-key", "key", "x-forwarded-for", forwardedFor)
+	}
+	tests := []struct {
+		name string
+		opts Options
+		ctx  context.Context
+		want string
+	}{
+		{"peer", Options{}, alice, "ip:10.0.0.1"},
+		{"unverified token", Options{}, withMetadata(alice, "authorization", "Bearer random"), "ip:10.0.0.1"},
+		{"spoofed forwarded address", proxy, withMetadata(alice, "x-forwarded-for", "10.9.9.9"), "ip:10.0.0.1"},
+		{"spoofed forwarded address from the same host", proxy, withMetadata(local, "x-forwarded-for", "10.9.9.9"), "ip:127.0.0.1"},
+		{"wrong proxy key", proxy, withMetadata(local, "x-proxy-key", "guess", "x-forwarded-for", "10.9.9.9"), "ip:127.0.0.1"},
+		{"without a trusted proxy", Options{}, relayed("10.0.0.2"), "ip:127.0.0.1"},
+		{"proxy", proxy, relayed("10.0.0.2"), "ip:10.0.0.2"},
+		{"proxy behind another proxy", proxy, relayed("10.9.9.9, 10.0.0.2"), "ip:10.0.0.2"},
+		{"no peer", Options{}, context.Background(), "unknown"},
+		{"authenticated", tokens, withMetadata(alice, "authorization", "Bearer secret"), "id:bob"},
+		{"unknown token", tokens, withMetadata(alice, "authorization", "Bearer random"), "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(&Config{}, tt.opts).callerID(tt.ctx); got != tt.want {
+				t.Errorf("callerID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	l, _ := newLimiter(t, `{"caller": {"perSecond": 1, "burst": 1}}`, nil)
+	ctx := fromIP("10.0.0.1")
+	if err := l.allowCall(l.callerID(ctx), "/greet.GreetService/Greet"); err != nil {
+		t.Fatal(err)
+	}
+	// a fresh bearer token does not make a fresh caller
+	if err := l.allowCall(l.callerID(withMetadata(ctx, "authorization", "Bearer random")), "/greet.GreetService/Greet"); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second call error = %v, want ResourceExhausted", err)
+	}
+
+	l.SetConfig(&Config{})
+	for i := 0; i < 10; i++ {
+		if err := l.allowCall(l.callerID(ctx), "/greet.GreetService/Greet"); err != nil {
+			t.Fatalf("call without limits: %v", err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, src := range []string{
+		`{"caller": {"perSecond": 0, "burst": 1}}`,
+		`{"methods": {"CreateBlog": {}}}`,
+		`{"methods": {"*": {"maxStreams": -1}}}`,
+		`{"methods": {"*": {"messages": {"perSecond": 1}}}}`,
+	} {
+		var c Config
+		if err := json.Unmarshal([]byte(src), &c); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%s) succeeded, want error", src)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Package recovery turns panics in gRPC handlers into codes.Internal errors
+// so that a bug in one call does not take the whole server down.
+package recovery
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor recovers from panics in unary handlers.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer recoverTo(info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor recovers from panics in streaming handlers.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverTo(info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+// recoverTo logs a panic of the handler of method with its stack and
+// replaces the result of the call by an internal error, which does not
+// reveal the panic to the client.
+func recoverTo(method string, err *error) {
+	if p := recover(); p != nil {
+		log.Printf("panic in %s: %v\n%s", method, p, debug.Stack())
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
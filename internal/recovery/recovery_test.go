@@ -0,0 +1,68 @@
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logs := captureLog(t)
+	intercept := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/greet.GreetService/Greet"}
+
+	resp, err := intercept(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", nil
+	})
+	if resp != "resp" || err != nil {
+		t.Errorf("handler without panic returned %v, %v", resp, err)
+	}
+	wantErr := status.Error(codes.NotFound, "missing")
+	if _, err := intercept(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, wantErr
+	}); err != wantErr {
+		t.Errorf("handler error became %v", err)
+	}
+
+	_, err = intercept(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		var m map[string]int
+		m["boom"]++
+		return nil, nil
+	})
+	if status.Code(err) != codes.Internal || strings.Contains(err.Error(), "nil map") {
+		t.Errorf("panicking handler returned %v, want a bare Internal error", err)
+	}
+	if out := logs.String(); !strings.Contains(out, "panic in /greet.GreetService/Greet") || !strings.Contains(out, "recovery_test.go") {
+		t.Errorf("log does not contain the method and stack:\n%s", out)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	captureLog(t)
+	intercept := StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/greet.GreetService/LongGreet"}
+
+	wantErr := errors.New("recv failed")
+	if err := intercept(nil, nil, info, func(srv interface{}, ss grpc.ServerStream) error { return wantErr }); err != wantErr {
+		t.Errorf("handler error became %v", err)
+	}
+	err := intercept(nil, nil, info, func(srv interface{}, ss grpc.ServerStream) error { panic("boom") })
+	if status.Code(err) != codes.Internal {
+		t.Errorf("panicking handler returned %v, want Internal", err)
+	}
+}
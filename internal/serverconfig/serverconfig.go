@@ -0,0 +1,77 @@
+// Package serverconfig reads the config file shared by the servers, a JSON
+// object such as
+//
+//	{
+//	  "callers": {"blogctl": {"tokenSHA256": "ef54b0b7..."}},
+//	  "rateLimits": {"caller": {"perSecond": 50, "burst": 100}}
+//	}
+//
+// whose sections are described by the packages they configure: callers by
+// internal/auth and rateLimits by internal/ratelimit. Sections that are
+// left out take their zero value.
+package serverconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"example.com/internal/auth"
+	"example.com/internal/ratelimit"
+)
+
+// Config is the content of a server config file.
+type Config struct {
+	Callers    auth.Callers     `json:"callers"`
+	RateLimits ratelimit.Config `json:"rateLimits"`
+}
+
+// Load reads a config file. An empty path means the zero config.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		return &Config{}, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := c.Callers.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: callers: %v", path, err)
+	}
+	if err := c.RateLimits.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: rateLimits: %v", path, err)
+	}
+	return &c, nil
+}
+
+// ReloadOnHangup reads the config file at path again whenever the process
+// receives SIGHUP, and passes it to apply. An invalid file is logged and
+// the config in use is kept.
+func ReloadOnHangup(path string, apply func(*Config)) {
+	if path == "" {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			c, err := Load(path)
+			if err != nil {
+				log.Printf("keeping the previous config: %v", err)
+				continue
+			}
+			apply(c)
+			log.Printf("reloaded config from %s", path)
+		}
+	}()
+}
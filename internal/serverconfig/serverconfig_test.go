@@ -0,0 +1,57 @@
+package serverconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"example.com/internal/auth"
+)
+
+func TestLoad(t *testing.T) {
+	if c, err := Load(""); err != nil || c.RateLimits.Caller != nil || len(c.RateLimits.Methods) != 0 {
+		t.Errorf("Load(\"\") = %+v, %v, want no limits", c, err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.json")
+	write := func(src string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{
+		"callers": {"ops": {"tokenSHA256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "admin": true}},
+		"rateLimits": {"caller": {"perSecond": 2, "burst": 4}, "methods": {"*": {"maxStreams": 3}}}
+	}`)
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if r := c.RateLimits.Caller; r == nil || r.PerSecond != 2 || r.Burst != 4 || c.RateLimits.Methods["*"].MaxStreams != 3 {
+		t.Errorf("Load = %+v, want the rate limits of the file", c)
+	}
+	if ops := c.Callers["ops"]; !ops.Admin || ops.TokenSHA256 != auth.Digest("test") {
+		t.Errorf("Load callers = %+v, want ops as an admin", c.Callers)
+	}
+
+	for _, src := range []string{
+		`{"rateLimits": {"caller": {"perSecond": 0, "burst": 1}}}`,
+		`{"rateLimits": {"methods": {"CreateBlog": {}}}}`,
+		`{"rateLimits": {"methods": {"*": {"maxStreams": -1}}}}`,
+		`{"rateLimits": {"methods": {"*": {"messages": {"perSecond": 1}}}}}`,
+		`{"rateLimits": {"callers": {"perSecond": 1, "burst": 1}}}`,
+		`{"caller": {"perSecond": 1, "burst": 1}}`,
+		`{"callers": {"ops": {"tokenSHA256": "not hex"}}}`,
+		`{"callers": {"ops": {"tokenSHA256": "9f86d081"}}}`,
+	} {
+		write(src)
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%s) succeeded, want error", src)
+		}
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}